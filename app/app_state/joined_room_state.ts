@@ -23,7 +23,7 @@ export class JoinedRoomState extends ApplicationState {
         )
 
         let ret = await this.app.msg_state.register_message(msg_idx);
-        if (ret.message_type !== MessageType.GenericResponse) {
+        if (ret.message_type !== MessageType.ArenaActionResponse) {
             throw new Error("Connection error: Wrong Type")
         }
 
@@ -29,11 +29,15 @@ export class LoginState extends ApplicationState {
 
         let msg_idx = this.app.conn.send({
             message_type: MessageType.InitialMessageAction,
-            data: {name: this.app.username}
+            data: {name: this.app.username, session_token: this.app.session_token ?? undefined}
         })
 
         let ret = await this.app.msg_state.register_message(msg_idx)
-        if (ret.message_type === MessageType.GenericResponse) {
+        if (ret.message_type === MessageType.InitialMessageResponse) {
+            this.app.session_token = ret.data.session_token
+            this.app.state = new ConnectedState(this.app)
+            await this.app.router.push({name: 'connected_page'})
+        } else if (ret.message_type === MessageType.GenericResponse) {
             if (!ret.data.success) {
                 console.log("Failed to connect: ", ret.data.fail_reason)
             }
@@ -8,6 +8,8 @@ import {EventHandler} from "./messaging/event_handler";
 export class Application {
     connection: Connection | null;
     username: string
+    // Identifies the client to the server when it connects again
+    session_token: string | null
     public state: ApplicationState
     router: Router
     msg_state: MessageState
@@ -17,6 +19,7 @@ export class Application {
         this.state = new LoginState(this)
         this.connection = null
         this.username = "No username set"
+        this.session_token = null
         this.router = useRouter()
         this.handler = new EventHandler()
         this.msg_state = new MessageState(this.handler)
@@ -9,6 +9,15 @@ export enum ArenaMessageType {
     StartGameAction,
     PlayerAction,
     PlayerQuitAction,
+
+    // Sent from game (server) to player (client) when the player has to act
+    TimerEvent,
+
+    // Sent from game (server) to player (client) with everything the player
+    // can currently see of the game, in response to SnapshotAction
+    SnapshotEvent,
+    // Sent from player (client) to game (server) to ask for a snapshot
+    SnapshotAction,
 }
 
 type MessageEntry<T extends ArenaMessageType = ArenaMessageType, D = any> = {
@@ -33,7 +42,43 @@ type MessageMap = {
         // TODO
     }
     [ArenaMessageType.PlayerQuitAction]: {}
+    [ArenaMessageType.TimerEvent]: {
+        action_time: number,
+        time_bank: number,
+    }
+    [ArenaMessageType.SnapshotEvent]: {
+        view: PlayerView
+    }
+    [ArenaMessageType.SnapshotAction]: {}
+
+}
+
+type SeatView = {
+    discards: number[],
+    kans: number[],
+    ankans: number[],
+    pons: number[],
+    chiis: number[],
+    closed_tiles: number,
+    hand_in_riichi: boolean,
+    points: number,
+    seat_wind: number,
+}
 
+export type PlayerView = {
+    player: number,
+    closed_hand: number[],
+    drawn_tile: number,
+    seats: SeatView[],
+    round_wind: number,
+    kyoku: number,
+    honba: number,
+    riichi_sticks: number,
+    dora_indicators: number[],
+    wall_remaining: number,
+    current_player: number,
+    discarded_tile: number,
+    pending_actions: any[], // TODO
 }
 
 type ConstrainedMap<M extends Record<ArenaMessageType, any>> = {
@@ -16,6 +16,76 @@ export enum MessageType {
     ListArenasAction,
     CreateArenaAction,
     ArenaInfoAction,
+
+	// Sent in response to InitialMessageAction
+    InitialMessageResponse,
+
+	// Steps through the replay of a finished match
+    ReplayAction,
+    ReplayResponse,
+
+	// Sent in response to ServerArenaAction, once the arena took or turned
+	// down the action
+    ArenaActionResponse,
+}
+
+// What kind of error a request ran into, matching ErrorCode on the
+// server. The numbers never change, and are grouped by layer.
+export enum ErrorCode {
+    NoError = 0,
+    Unknown = 1,
+    BadMessage = 2,         // The data does not match the type of the message
+
+    // Server
+    SessionNotFound = 100,
+    AlreadyInArena = 101,
+    NotInArena = 102,
+
+    // Arena
+    ArenaNotFound = 200,
+    ArenaNameTaken = 201,
+    ArenaFull = 202,
+    GameNotStarted = 203,
+    GameAlreadyStarted = 204,
+    NotEnoughPlayers = 205,
+    PlayersAway = 206,      // Seats are held for players to reconnect to
+    PlayerAway = 207,       // The player left, and a bot plays the seat
+    NoSeatHeld = 208,       // No seat is held to reconnect to
+    SpectatorCannotAct = 209,
+    AlreadySpectating = 210,
+    NotSpectating = 211,
+    UnknownView = 212,
+    CannotSpectate = 213,   // Only clients can spectate
+
+    // Game
+    WrongState = 300,       // The game is not at a point where the action can be taken
+    NotYourTurn = 301,
+    InvalidTile = 302,      // The tile cannot be used for the action
+    ActionNotAllowed = 303, // The action is not one the player can take now
+
+    // Hand
+    TileNotInHand = 400,
+    TooManyTiles = 401,
+    TooFewTiles = 402,
+    NotASequence = 403,
+    NotEnoughTiles = 404,   // Too few copies of the tile to call it
+    NoPon = 405,            // No pon to add the tile to
+    NotWaiting = 406,       // The hand does not win on the tile
+    Furiten = 407,
+    NoYaku = 408,
+    NotTenpai = 409,        // Discarding the tile leaves the hand not ready for riichi
+    TooFewTerminals = 410,  // Fewer than nine terminals and honours for kyuushu kyuuhai
+
+    // Replay
+    ReplayUnavailable = 500, // The logs cannot be read, or the match is still going
+    OutOfRange = 501,
+}
+
+// What a spectator gets to see of a game
+export enum SpectatorView {
+    Public,     // Only what every player can see
+    Player,     // Everything one player can see
+    Omniscient, // Everything, after a delay
 }
 
 export type IncomingMessage = Message & {
@@ -28,19 +98,27 @@ type MessageEntry<T extends MessageType = MessageType, D = any> = {
 }
 
 type MessageMap = {
-    [MessageType.InitialMessageAction]: { name: string }
+    [MessageType.InitialMessageAction]: { name: string, session_token?: string }
+    [MessageType.InitialMessageResponse]: {
+        success: boolean,
+        session_token: string,
+        resumed: boolean
+    }
     [MessageType.ServerArenaEvent]: { arena_message: ArenaMessage }
     [MessageType.ServerArenaAction]: {
         arena_message: ArenaMessage
     }
     [MessageType.JoinArenaAction]: {
-        arena_name: string
+        arena_name: string,
+        spectate?: boolean,
+        view?: SpectatorView,
+        player?: number
     }
     [MessageType.CreateArenaAction]: {
         arena_name: string
     }
     [MessageType.ListArenasAction]: {}
-	[MessageType.GenericResponse]: {success: boolean, fail_reason: string}
+	[MessageType.GenericResponse]: {success: boolean, code: ErrorCode, fail_reason: string}
 	[MessageType.ListArenasResponse]: {
 		success: boolean,
 		arena_list: string[]
@@ -49,12 +127,31 @@ type MessageMap = {
         success: boolean,
         name: string,
         agents: Array<{
-            name: string
+            name: string,
+            id: string
         }>,
         game_started: boolean,
-        date_created: string
+        date_created: string,
+        game_id: string
     }
     [MessageType.ArenaInfoAction]: {}
+    [MessageType.ReplayAction]: {
+        game_id: string,
+        position: number,
+        view: SpectatorView,
+        player?: number
+    }
+    [MessageType.ReplayResponse]: {
+        success: boolean,
+        position: number,
+        length: number,
+        messages: ArenaMessage[]
+    }
+    [MessageType.ArenaActionResponse]: {
+        success: boolean,
+        code: ErrorCode,
+        fail_reason: string
+    }
 }
 
 type ConstrainedMap<M extends Record<MessageType, any>> = {
@@ -16,6 +16,7 @@ func main() {
 	}
 
 	core.InitializeMap()
+	core.GlobalLogSink = core.FileLogSink{Dir: "logs"}
 	web.SetupHTTP(server.AcceptConnection)
 
 	signals := make(chan os.Signal, 1)
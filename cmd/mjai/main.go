@@ -0,0 +1,62 @@
+// Plays a match between mjai bot processes and built-in bots, and
+// writes its game log, which the tenhou command can convert for review.
+//
+//	mjai [-logs dir] "mortal --config a.toml" "./other-bot"
+//
+// Every argument is the command of an mjai bot, and the seats left over
+// are filled with built-in bots.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	core "codeberg.org/ijnakashiar/LibreRiichi/core"
+	messages "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/google/uuid"
+)
+
+func main() {
+	logs := flag.String("logs", "logs", "directory to write the game log to")
+	flag.Parse()
+	if flag.NArg() == 0 || flag.NArg() > 4 {
+		fmt.Fprintln(os.Stderr, "Usage: mjai [-logs dir] <bot command>...")
+		os.Exit(2)
+	}
+
+	core.GlobalLogSink = core.FileLogSink{Dir: *logs}
+	arena := core.CreateArena("mjai", uuid.New())
+	for idx, command := range flag.Args() {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			fmt.Fprintln(os.Stderr, "Empty bot command")
+			os.Exit(2)
+		}
+		agent, err := core.SpawnMjaiAgent(&arena, fmt.Sprintf("mjai %d", idx), fields[0], fields[1:]...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start %q: %v\n", command, err)
+			os.Exit(1)
+		}
+		defer agent.Close()
+	}
+	for idx := flag.NArg(); idx < 4; idx++ {
+		if err := arena.JoinArena(core.NewBot(fmt.Sprintf("bot %d", idx)), true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := arena.HandleStartGameAction(messages.StartGameActionData{}, 0); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to start the match:", err)
+		os.Exit(1)
+	}
+	info := arena.GetArenaInfo()
+	for info.GameStarted {
+		time.Sleep(100 * time.Millisecond)
+		info = arena.GetArenaInfo()
+	}
+	fmt.Println("Game log:", core.FileLogSink{Dir: *logs}.Path(info.GameID))
+}
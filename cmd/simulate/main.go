@@ -0,0 +1,85 @@
+// Plays matches between built-in strategy players without a server, and
+// reports how each player did. Any error from the game engine stops the
+// run, so it also serves as a regression test for the engine.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	core "codeberg.org/ijnakashiar/LibreRiichi/core"
+	game_data "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+)
+
+// Takes wins, and otherwise discards whatever it draws without calling
+type tsumogiri struct{ core.Bot }
+
+func (player *tsumogiri) Act(view game_data.PlayerView) []game_data.ActionData {
+	for _, action := range view.PendingActions {
+		if action.ActionType == game_data.TSUMO || action.ActionType == game_data.RON {
+			return []game_data.ActionData{action}
+		}
+	}
+	// Nothing to discard before drawing, so the default actions will do
+	return nil
+}
+
+var strategies = map[string]func(name string) core.Actor{
+	"bot": func(name string) core.Actor { return core.NewBot(name) },
+	"tsumogiri": func(name string) core.Actor {
+		return &tsumogiri{Bot: *core.NewBot(name)}
+	},
+}
+
+func main() {
+	matches := flag.Int("matches", 100, "number of matches to play")
+	players := flag.String("players", "bot,bot,bot,bot", "comma separated strategy of each player: bot or tsumogiri")
+	tonpuusen := flag.Bool("tonpuusen", false, "play east round only matches")
+	redFives := flag.Bool("red-fives", false, "play with one red five of each suit")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the first match, incremented for every match after it")
+	flag.Parse()
+
+	names := strings.Split(*players, ",")
+	if len(names) != 4 {
+		fmt.Fprintln(os.Stderr, "Exactly 4 players are needed")
+		os.Exit(2)
+	}
+	actors := make([]core.Actor, 0, len(names))
+	for idx, name := range names {
+		strategy, ok := strategies[name]
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Unknown strategy:", name)
+			os.Exit(2)
+		}
+		actors = append(actors, strategy(fmt.Sprintf("%s %d", name, idx)))
+	}
+
+	rules := core.DefaultMatchRules()
+	if *tonpuusen {
+		rules.Length = core.TONPUUSEN
+	}
+	rules.RedFives = *redFives
+
+	stats := core.NewSimulationStats(len(actors))
+	for match := range int64(*matches) {
+		if err := core.SimulateMatch(rules, *seed+match, actors, &stats); err != nil {
+			fmt.Fprintf(os.Stderr, "Match with seed %d failed: %v\n", *seed+match, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("%d matches from seed %d, %d hands, %.1f%% draws\n",
+		stats.Matches, *seed, stats.Hands, 100*stats.DrawRate())
+	fmt.Printf("%-14s %8s %8s %10s\n", "player", "win", "deal-in", "placement")
+	for idx, actor := range actors {
+		player := uint8(idx)
+		fmt.Printf("%-14s %7.1f%% %7.1f%% %10.2f\n",
+			actor.Info().Name,
+			100*stats.WinRate(player),
+			100*stats.DealInRate(player),
+			stats.AveragePlacement(player))
+	}
+}
@@ -0,0 +1,50 @@
+// Converts a game log written by the server to the tenhou.net/6 JSON
+// format, for analysis tools such as mortal and NAGA.
+//
+//	tenhou [-o out.json] logs/<game id>.jsonl
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	core "codeberg.org/ijnakashiar/LibreRiichi/core"
+)
+
+func main() {
+	output := flag.String("o", "", "file to write the tenhou log to, stdout if empty")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: tenhou [-o out.json] <game log>")
+		os.Exit(2)
+	}
+
+	entries, err := core.ReadLogFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read the game log:", err)
+		os.Exit(1)
+	}
+	log, err := core.ExportTenhou(entries)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to export the game log:", err)
+		os.Exit(1)
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write the tenhou log:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+// Anything that can take a seat in an arena, be it a websocket client
+// or a bot playing in-process
+type Agent interface {
+	Info() AgentInfo
+	// Receives a message the arena sends to the seat. It is called with
+	// the arena locked, so it must neither block nor call back into the
+	// arena.
+	Send(ArenaMessage)
+}
+
+// An agent that plays in-process. Whenever the game waits on its seat,
+// the arena asks it for its actions right away, without going through
+// messages. Its actions are checked like those of any other player. Agents that are not actors submit their actions with
+// Arena.SubmitAction instead.
+type Actor interface {
+	Agent
+	// The actions to take with what the seat can see. Like Send, it is
+	// called with the arena locked.
+	Act(PlayerView) []ActionData
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The actor playing the seat, if any
+func (arena *Arena) actor(playerIdx uint8) (Actor, bool) {
+	if seat, ok := arena.away[playerIdx]; ok {
+		return seat.bot, true
+	}
+	actor, ok := arena.agents[playerIdx].(Actor)
+	return actor, ok
+}
+
+// Lets the first actor the game waits on act, and returns whether one
+// did. An actor whose actions are not allowed gets the default actions
+// instead, so that it cannot stall the game.
+func (arena *Arena) runActor() bool {
+	if !arena.gameStarted {
+		return false
+	}
+
+	game := &arena.match.Game
+	for _, player := range game.AwaitedPlayers() {
+		actor, ok := arena.actor(player)
+		if !ok {
+			continue
+		}
+
+		actions := actor.Act(game.PlayerView(player))
+		if len(actions) == 0 {
+			actions = game.DefaultActions(player)
+		}
+		for _, action := range actions {
+			if err := arena.seatAction(actor, action, player); err != nil {
+				fmt.Println("Agent action failed:", err)
+				arena.defaultActions(player)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (arena *Arena) defaultActions(playerIdx uint8) {
+	for _, action := range arena.match.Game.DefaultActions(playerIdx) {
+		if err := arena.playerAction(action, playerIdx); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Takes an action for the seat of an agent that is not an actor
+func (arena *Arena) SubmitAction(agent Agent, action ActionData) error {
+	arena.Lock()
+	idx, err := arena.playerIdx(agent)
+	arena.Unlock()
+	if err != nil {
+		return err
+	}
+	return arena.HandlePlayerAction(PlayerActionData{ActionData: action}, idx)
+}
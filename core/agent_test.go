@@ -0,0 +1,145 @@
+package core
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+func TestActorsPlayMatch(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	for _, name := range []string{"east", "south", "west", "north"} {
+		if err := arena.JoinArena(NewBot(name), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.JoinArena(NewBot("spectator"), false); err == nil {
+		t.Error("Bot joined as a spectator")
+	}
+
+	// Actors are asked for their actions as soon as the game waits on
+	// them, so the whole match is played before the arena returns
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !arena.match.Finished || arena.gameStarted {
+		t.Error("Match did not finish")
+	}
+}
+
+func TestMixedAgents(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	client := newTestClient()
+	agents := []Agent{NewBot("bot 1"), client, NewBot("bot 2"), NewBot("bot 3")}
+	for _, agent := range agents {
+		if err := arena.JoinArena(agent, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bots play until it is the client's turn
+	for turns := 0; turns < 10 && arena.gameStarted; turns++ {
+		game := &arena.match.Game
+		awaited := game.AwaitedPlayers()
+		if len(awaited) != 1 || awaited[0] != 1 {
+			t.Fatalf("Game waits on %v, want only the client", awaited)
+		}
+
+		action := ActionData{ActionType: SKIP, Data: SkipData{ActionToSkip: game.actionsOf(1)[0]}}
+		if game.GameState == CURRENT_TURN {
+			action = ActionData{ActionType: TOSS, Data: TossData{TileToToss: game.DrawnTile}}
+		}
+		if game.DrawnTile == Invalid && game.GameState == CURRENT_TURN {
+			action.Data = TossData{TileToToss: game.Players[1].ClosedHand[0]}
+		}
+		if err := arena.SubmitAction(client, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(received(client)) == 0 {
+		t.Error("Client was not sent the game")
+	}
+	if err := arena.SubmitAction(NewBot("stranger"), ActionData{}); err == nil {
+		t.Error("An agent without a seat took an action")
+	}
+}
+
+func TestSeatActionChecks(t *testing.T) {
+	arena, clients := newSessionArena(t, time.Minute)
+	if err := arena.Disconnect(clients[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	arena.Lock()
+	defer arena.Unlock()
+	bot := arena.away[1].bot
+	tests := []struct {
+		name   string // description of this test case
+		agent  Agent
+		player uint8
+		want   ErrorCode
+	}{
+		{"player who is away", clients[1], 1, PLAYER_AWAY},
+		{"bot of another seat", bot, 2, PLAYER_AWAY},
+		{"seat that does not exist", bot, 4, NOT_IN_ARENA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := arena.seatAction(tt.agent, ActionData{ActionType: SKIP, Data: SkipData{}}, tt.player)
+			if CodeOf(err) != tt.want {
+				t.Errorf("seatAction() = %v, want code %v", err, tt.want)
+			}
+		})
+	}
+
+	// The bot acts like any other player, so its actions are checked by
+	// the game
+	err := arena.seatAction(bot, ActionData{ActionType: TOSS, Data: TossData{TileToToss: Invalid}}, 1)
+	if CodeOf(err) == PLAYER_AWAY || CodeOf(err) == NOT_IN_ARENA {
+		t.Errorf("Bot was not let act for its seat: %v", err)
+	}
+}
+
+// A bot that notes how deep the stack is whenever it acts
+type depthBot struct {
+	*Bot
+	depths []int
+}
+
+func (bot *depthBot) Act(view PlayerView) []ActionData {
+	bot.depths = append(bot.depths, runtime.Callers(0, make([]uintptr, 4096)))
+	return bot.Bot.Act(view)
+}
+
+func TestHandsDoNotNest(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	bots := make([]*depthBot, 4)
+	for idx := range bots {
+		bots[idx] = &depthBot{Bot: NewBot("bot")}
+		if err := arena.JoinArena(bots[idx], true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if arena.match.Round.Hand < 2 {
+		t.Fatalf("Match only lasted %d hands", arena.match.Round.Hand+1)
+	}
+
+	for _, bot := range bots {
+		if slices.Min(bot.depths) != slices.Max(bot.depths) {
+			t.Fatalf("Stack grew from %d to %d frames over the match", slices.Min(bot.depths), slices.Max(bot.depths))
+		}
+	}
+}
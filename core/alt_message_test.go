@@ -0,0 +1,201 @@
+package core
+
+import (
+	"slices"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+func boardMessage(eventType BoardEventType, data any) ArenaMessage {
+	return ArenaMessage{
+		MessageType: ArenaBoardEventType,
+		Data:        ArenaBoardEventData{BoardEvent: BoardEvent{EventType: eventType, Data: data}},
+	}
+}
+
+func TestGetAltMessage(t *testing.T) {
+	draw := ActionData{ActionType: DRAW, Data: DrawData{DrawnTile: Pinzu + 4}}
+	toss := ActionData{ActionType: TOSS, Data: TossData{TileToToss: Pinzu + 4}}
+	setup := []Setup{{Type: INITIAL_TILES, Data: tiles(Manzu, 1, 2, 3)}, {Type: HONBA, Data: uint8(1)}}
+
+	tests := []struct {
+		name     string // description of this test case
+		message  ArenaMessage
+		wantSend bool
+		want     ArenaMessage
+	}{
+		{
+			"drawn tile is hidden",
+			boardMessage(PlayerActionEventType, PlayerActionEventData{ActionData: draw, FromPlayer: 2}),
+			true,
+			boardMessage(PlayerActionEventType, PlayerActionEventData{
+				ActionData: ActionData{ActionType: DRAW, Data: DrawData{DrawnTile: Hidden}},
+				FromPlayer: 2,
+			}),
+		},
+		{
+			"public action is unchanged",
+			boardMessage(PlayerActionEventType, PlayerActionEventData{ActionData: toss, FromPlayer: 1}),
+			true,
+			boardMessage(PlayerActionEventType, PlayerActionEventData{ActionData: toss, FromPlayer: 1}),
+		},
+		{
+			"potential action is not sent",
+			boardMessage(PotentialActionEventType, PotentialActionEventData{ActionData: toss}),
+			false,
+			ArenaMessage{},
+		},
+		{
+			"dealt tiles are hidden",
+			boardMessage(GameSetupEventType, GameSetupEventData{Setup: setup}),
+			true,
+			boardMessage(GameSetupEventType, GameSetupEventData{Setup: []Setup{
+				{Type: INITIAL_TILES, Data: []Tile{Hidden, Hidden, Hidden}},
+				{Type: HONBA, Data: uint8(1)},
+			}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, send, err := GetAltMessage(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if send != tt.wantSend {
+				t.Fatalf("send = %v, want %v", send, tt.wantSend)
+			}
+			if send && !equalMessages(got, tt.want) {
+				t.Errorf("GetAltMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The original message must not be changed by redacting it
+	if setup[0].Data.([]Tile)[0] != Manzu {
+		t.Error("Redacting the setup changed the dealt tiles")
+	}
+}
+
+func equalMessages(a, b ArenaMessage) bool {
+	eventA := a.Data.(ArenaBoardEventData).BoardEvent
+	eventB := b.Data.(ArenaBoardEventData).BoardEvent
+	if eventA.EventType != eventB.EventType {
+		return false
+	}
+	setupA, okA := eventA.Data.(GameSetupEventData)
+	setupB, okB := eventB.Data.(GameSetupEventData)
+	if okA && okB {
+		return slices.EqualFunc(setupA.Setup, setupB.Setup, func(a, b Setup) bool {
+			tilesA, okA := a.Data.([]Tile)
+			tilesB, okB := b.Data.([]Tile)
+			if okA && okB {
+				return a.Type == b.Type && slices.Equal(tilesA, tilesB)
+			}
+			return a == b
+		})
+	}
+	return eventA == eventB
+}
+
+// Checks that a seat was only sent what it may know, and returns the
+// actions it was offered since the last hand ended
+func checkNoLeaks(t *testing.T, seat int, offered []ActionData, messages []ArenaMessage) []ActionData {
+	t.Helper()
+	for _, message := range messages {
+		if message.MessageType != ArenaBoardEventType {
+			continue
+		}
+		switch data := message.Data.(ArenaBoardEventData).Data.(type) {
+		case PlayerActionEventData:
+			if data.ActionType == DRAW && int(data.FromPlayer) != seat &&
+				data.Data.(DrawData).DrawnTile != Hidden {
+				t.Fatalf("Seat %d saw the tile drawn by seat %d", seat, data.FromPlayer)
+			}
+		case GameSetupEventData:
+			for _, setup := range data.Setup {
+				if setup.Type == PLAYER_NUMBER && int(setup.Data.(uint8)) != seat {
+					t.Fatalf("Seat %d was sent the setup of seat %d", seat, setup.Data)
+				}
+			}
+		case GameEndEventData:
+			offered = nil
+		case PotentialActionEventData:
+			if seat < 0 {
+				t.Fatalf("A spectator was sent a potential action")
+			}
+			offered = append(offered, data.ActionData)
+		}
+	}
+	return offered
+}
+
+// Picks what a seat does with the actions it was offered: wins are
+// taken, tiles are tossed and calls are skipped
+func respond(game *MahjongGame, seat uint8, offered []ActionData) []ActionData {
+	for _, action := range offered {
+		if action.ActionType == RON || action.ActionType == TSUMO {
+			return []ActionData{action}
+		}
+	}
+	for _, action := range offered {
+		if action.ActionType == TOSS {
+			player := game.Players[seat]
+			tile := isolatedTile(player.ClosedHand)
+			if player.HandInRiichi {
+				tile = game.DrawnTile
+			}
+			return []ActionData{{ActionType: TOSS, Data: TossData{TileToToss: tile}}}
+		}
+	}
+
+	skips := make([]ActionData, 0, len(offered))
+	for _, action := range offered {
+		skips = append(skips, ActionData{ActionType: SKIP, Data: SkipData{ActionToSkip: action}})
+	}
+	return skips
+}
+
+func TestNoHiddenTilesLeak(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	seats := make([]*Client, 4)
+	for idx := range seats {
+		seats[idx] = newTestClient()
+		if err := arena.JoinArena(seats[idx], true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spectator := newTestClient()
+	if err := arena.JoinArena(spectator, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	offered := make([][]ActionData, len(seats))
+	for steps := 0; arena.gameStarted; steps++ {
+		if steps > 20000 {
+			t.Fatal("Match did not end")
+		}
+
+		checkNoLeaks(t, -1, nil, received(spectator))
+		for idx, client := range seats {
+			offered[idx] = checkNoLeaks(t, idx, offered[idx], received(client))
+		}
+
+		seat := slices.IndexFunc(offered, func(actions []ActionData) bool { return len(actions) != 0 })
+		if seat == -1 {
+			t.Fatal("Nobody was offered an action")
+		}
+		for _, action := range respond(&arena.match.Game, uint8(seat), offered[seat]) {
+			if err := arena.HandlePlayerAction(PlayerActionData{ActionData: action}, uint8(seat)); err != nil {
+				t.Fatalf("%v for %+v from %d", err, action, seat)
+			}
+		}
+		offered[seat] = nil
+	}
+}
@@ -1,29 +1,38 @@
 package core
 
 import (
-	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
-	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
 	"github.com/google/uuid"
 )
 
 // A location where players gather. Controls the flow of the game,
 // directing messages to players, requesting input/ouput
 type Arena struct {
-	agents      []*Client
-	spectators  []*Client
+	agents      []Agent
+	spectators  []spectator
 	gameStarted bool
-	game        MahjongGame
+	match       Match
+	timeLimits  TimeLimits
+	timer       actionTimer
+	// How long omniscient spectators are kept behind the players
+	spectatorDelay time.Duration
+	// Seats of players who are not connected, by player index
+	away        map[uint8]*awaySeat
+	gracePeriod time.Duration
+	// Everything that happened in the current or last match
+	log     GameLog
+	logSink LogSink
 	// AwaitingInputs []??? that stores the list of agents that it is waiting on
 
 	DateCreated time.Time
 	Name        string
-	uuid uuid.UUID
+	uuid        uuid.UUID
 
 	sync.Mutex
 }
@@ -40,7 +49,7 @@ func (arena *Arena) GetArenaInfo() ArenaInfoResponseData {
 
 	agents := make([]AgentInfo, 0)
 	for _, agent := range arena.agents {
-		agents = append(agents, AgentInfo{Name: agent.Name})
+		agents = append(agents, agent.Info())
 	}
 
 	return ArenaInfoResponseData{
@@ -49,93 +58,97 @@ func (arena *Arena) GetArenaInfo() ArenaInfoResponseData {
 		Agents:      agents,
 		GameStarted: arena.gameStarted,
 		DateCreated: arena.DateCreated,
+		GameID:      arena.log.ID,
 	}
 }
 
 func (arena *Arena) Send(data ArenaMessage, visibility Visibility, sendTo uint8) error {
+	if event, ok := data.Data.(ArenaBoardEventData); ok && arena.gameStarted {
+		arena.log.recordEvent(event, visibility, sendTo)
+	}
+
 	switch visibility {
 	case GLOBAL:
-		for i, player := range arena.agents {
+		for i := range arena.agents {
 			fmt.Println("Sending index: ", i)
-			player.Recv <- Message{
-				MessageType: ServerArenaEventType,
-				Data:        ServerArenaMessageEventData{ArenaMessage: data},
-			}
+			arena.deliver(uint8(i), data)
 		}
 
 	case PARTIAL:
-		arena.agents[sendTo].Recv <- Message{
-			MessageType: ServerArenaEventType,
-			Data:        ServerArenaMessageEventData{ArenaMessage: data},
-		}
+		arena.deliver(sendTo, data)
 
-		altMessage, err := GetAltMessage(data)
+		altMessage, send, err := GetAltMessage(data)
 		if err != nil {
 			return err
 		}
 
-		for idx, player := range arena.agents {
+		for idx := range arena.agents {
 			fmt.Println("Sending index: ", idx)
-			if idx == int(sendTo) {
+			if idx == int(sendTo) || !send {
 				continue
 			}
-			player.Recv <- Message{
-				MessageType: ServerArenaEventType,
-				Data:        ServerArenaMessageEventData{ArenaMessage: altMessage},
-			}
+			arena.deliver(uint8(idx), altMessage)
 		}
 
 	case PLAYER:
-		arena.agents[sendTo].Recv <- Message{
-			MessageType: ServerArenaEventType,
-			Data:        ServerArenaMessageEventData{ArenaMessage: data},
-		}
+		arena.deliver(sendTo, data)
 	case EXCLUDE:
-		for i, player := range arena.agents {
+		for i := range arena.agents {
 			fmt.Println("Exclude: sending index: ", i)
 			if i == int(sendTo) {
 				fmt.Println("Exclude: Skipping: ", i)
 				continue
 			}
 			fmt.Println("Exclude: Continuing with: ", i)
-			player.Recv <- Message{
-				MessageType: ServerArenaEventType,
-				Data:        ServerArenaMessageEventData{ArenaMessage: data},
-			}
+			arena.deliver(uint8(i), data)
 		}
 	default:
 		panic(fmt.Sprintf("unexpected core.Visibility: %#v", sendTo))
 	}
 
-	return nil
+	return arena.sendToSpectators(data, visibility, sendTo)
 }
 
 func CreateArena(name string, uuid uuid.UUID) Arena {
 	return Arena{
-		agents:      make([]*Client, 0),
-		spectators:  make([]*Client, 0),
+		agents:      make([]Agent, 0),
+		spectators:  make([]spectator, 0),
 		gameStarted: false,
-		game:        MahjongGame{},
+		match:       Match{},
+		timeLimits:  DefaultTimeLimits(),
 		DateCreated: time.Now(),
 		Mutex:       sync.Mutex{},
 		Name:        name,
 		uuid:        uuid,
+
+		spectatorDelay: DefaultSpectatorDelay,
+		away:           make(map[uint8]*awaySeat),
+		gracePeriod:    DefaultGracePeriod,
+		logSink:        GlobalLogSink,
 	}
 }
 
-func (arena *Arena) JoinArena(agent *Client, joinAsPlayer bool) error {
+func (arena *Arena) JoinArena(agent Agent, joinAsPlayer bool) error {
+	client, isClient := agent.(*Client)
 	if !joinAsPlayer {
-		panic("NYI")
+		if !isClient {
+			return CodedError{Code: CANNOT_SPECTATE}
+		}
+		return arena.Spectate(client, Spectator{View: PUBLIC_VIEW})
 	}
 
 	arena.Lock()
 	defer arena.Unlock()
 
+	if len(arena.agents) >= arena.match.Game.GetMaxPlayers() {
+		return CodedError{Code: ARENA_FULL}
+	}
 	arena.agents = append(arena.agents, agent)
 
+	info := agent.Info()
 	data := PlayerJoinedEventData{
-		Name: agent.Name,
-		ID:   agent.ID,
+		Name: info.Name,
+		ID:   info.ID,
 	}
 
 	err := arena.Send(
@@ -148,7 +161,9 @@ func (arena *Arena) JoinArena(agent *Client, joinAsPlayer bool) error {
 		panic(err)
 	}
 
-	agent.Arena = arena
+	if isClient {
+		client.Arena = arena
+	}
 
 	return nil
 }
@@ -159,39 +174,51 @@ func (arena *Arena) DriveGame() error {
 	return arena.driveGame()
 }
 
-// Drives the game forward
+// Drives the game forward, until it waits on a player who is not an
+// actor. The hands of the match are played one after the other in this
+// loop, so a match of actors does not nest a call for every hand.
 func (arena *Arena) driveGame() error {
+	for {
+		sendInfos, shouldEnd := arena.match.Game.GetNextEvent()
+
+		// Send the event to the players
+		for _, sendInfo := range sendInfos {
+			for _, event := range sendInfo.Events {
+				arena.Send(ArenaMessage{
+					MessageType: ArenaBoardEventType,
+					Data:        event,
+				}, sendInfo.Visibility, sendInfo.SendTo)
+			}
+		}
 
-	sendInfos, gameContinue := arena.game.GetNextEvent()
-
-	if !gameContinue {
-		arena.FinishRoundArena()
-		return nil
-	}
-
-	// Send the event to the players
-	for _, sendInfo := range sendInfos {
-		for event := range sendInfo.Events {
-			arena.Send(ArenaMessage{
-				MessageType: ArenaBoardEventType,
-				Data:        event,
-			}, sendInfo.Visibility, sendInfo.SendTo)
+		if shouldEnd {
+			arena.FinishRoundArena()
+			if !arena.gameStarted {
+				break
+			}
+			continue
+		}
+		if !arena.runActor() {
+			break
 		}
 	}
-
+	arena.updateTimers()
 	return nil
 }
 
-func (arena *Arena) getPlayerIdx(client *Client) (uint8, error) {
+func (arena *Arena) getPlayerIdx(agent Agent) (uint8, error) {
 	arena.Lock()
 	defer arena.Unlock()
+	return arena.playerIdx(agent)
+}
 
-	for i, ptr := range arena.agents {
-		if ptr == client {
+func (arena *Arena) playerIdx(agent Agent) (uint8, error) {
+	for i, seated := range arena.agents {
+		if seated == agent {
 			return uint8(i), nil
 		}
 	}
-	return 0, errors.New("not found")
+	return 0, CodedError{Code: NOT_IN_ARENA}
 }
 
 // TODO: Implement ServerArenaHandler
@@ -201,22 +228,45 @@ func (arena *Arena) HandleStartGameAction(data StartGameActionData, fromPlayer u
 	defer arena.Unlock()
 
 	if arena.gameStarted {
-		return errors.New("Game already started")
+		return CodedError{Code: GAME_ALREADY_STARTED}
 	}
 
 	if len(arena.agents) != 4 {
-		return errors.New("Not enough agents")
+		return CodedError{Code: NOT_ENOUGH_PLAYERS}
 	}
 
-	setups, err := arena.game.StartNewGame()
+	if len(arena.away) != 0 {
+		return CodedError{Code: PLAYERS_AWAY}
+	}
+
+	arena.match = NewMatch(DefaultMatchRules())
+	arena.timer = newActionTimer(arena.timeLimits, len(arena.agents))
+	arena.gameStarted = true
+	arena.startLog()
+	if err := arena.startHand(); err != nil {
+		return err
+	}
+	return arena.driveGame()
+}
+
+// Sends the setup of the next hand of the match to each player. The
+// hand is played once the game is driven.
+func (arena *Arena) startHand() error {
+	setups, err := arena.match.StartNextHand()
 	if err != nil {
 		return err
 	}
+	round := arena.match.Round
+	// Only the hash of the wall is printed, since the seeds give away
+	// the tiles of hands still to be played
+	fmt.Printf("Arena %s: starting hand %d-%d with wall hash %s\n",
+		arena.Name, round.RoundWind-East+1, round.Kyoku+1, arena.match.Game.WallHash)
+	arena.log.RecordHand(arena.match.Game, round)
 
 	// Send over the setups for each player
+	messages := make([]ArenaMessage, len(setups))
 	for idx, setup := range setups {
-
-		err = arena.Send(ArenaMessage{
+		messages[idx] = ArenaMessage{
 			MessageType: ArenaBoardEventType,
 			Data: ArenaBoardEventData{
 				BoardEvent: BoardEvent{
@@ -226,25 +276,28 @@ func (arena *Arena) HandleStartGameAction(data StartGameActionData, fromPlayer u
 					},
 				},
 			},
-		}, PLAYER, uint8(idx))
+		}
+
+		err = arena.Send(messages[idx], PLAYER, uint8(idx))
 
 		if err != nil {
 			panic(err)
 		}
 	}
-
-	arena.gameStarted = true
-	return arena.driveGame()
+	if err := arena.sendPublicSetup(messages[0]); err != nil {
+		panic(err)
+	}
+	return nil
 }
 
-func (arena *Arena) HandlePlayerAction(data PlayerActionData, fromPlayer uint8) error {
-	arena.Lock()
-	defer arena.Unlock()
-
-	sendInfos, err := ActionDecode(&arena.game, data.ActionData, fromPlayer)
+// Applies an action to the game and sends out what it changed, without
+// moving the game on
+func (arena *Arena) playerAction(action ActionData, fromPlayer uint8) error {
+	sendInfos, err := ActionDecode(&arena.match.Game, action, fromPlayer)
 	if err != nil {
 		return err
 	}
+	arena.log.RecordAction(action, fromPlayer)
 
 	for _, sendInfo := range sendInfos {
 		for _, event := range sendInfo.Events {
@@ -254,6 +307,43 @@ func (arena *Arena) HandlePlayerAction(data PlayerActionData, fromPlayer uint8)
 			}, sendInfo.Visibility, sendInfo.SendTo)
 		}
 	}
+	return nil
+}
+
+// Takes an action for the seat once the agent is found to be the one
+// playing it, which is the bot while its player is away. Players and
+// the actors playing in-process go through the same checks.
+func (arena *Arena) seatAction(agent Agent, action ActionData, fromPlayer uint8) error {
+	if !arena.gameStarted {
+		return CodedError{Code: GAME_NOT_STARTED}
+	}
+	if int(fromPlayer) >= len(arena.agents) {
+		return CodedError{Code: NOT_IN_ARENA}
+	}
+
+	playing := arena.agents[fromPlayer]
+	if seat, away := arena.away[fromPlayer]; away {
+		playing = seat.bot
+	}
+	if agent != playing {
+		return CodedError{Code: PLAYER_AWAY}
+	}
+
+	return arena.playerAction(action, fromPlayer)
+}
+
+func (arena *Arena) HandlePlayerAction(data PlayerActionData, fromPlayer uint8) error {
+	arena.Lock()
+	defer arena.Unlock()
+
+	var agent Agent
+	if int(fromPlayer) < len(arena.agents) {
+		agent = arena.agents[fromPlayer]
+	}
+	err := arena.seatAction(agent, data.ActionData, fromPlayer)
+	if err != nil {
+		return err
+	}
 
 	err = arena.driveGame()
 	if err != nil {
@@ -266,28 +356,118 @@ func (arena *Arena) HandlePlayerAction(data PlayerActionData, fromPlayer uint8)
 func (arena *Arena) HandlePlayerQuitAction(data PlayerQuitActionData, fromPlayer uint8) error {
 	arena.Lock()
 	defer arena.Unlock()
-	if arena.gameStarted {
-		// Replace with AI
+	arena.handlePlayerQuit(fromPlayer)
+	return nil
+}
 
+func (arena *Arena) handlePlayerQuit(fromPlayer uint8) {
+	agent := arena.agents[fromPlayer]
+	if arena.gameStarted {
+		// A bot plays the seat, which the player can still reclaim
+		seat, ok := arena.away[fromPlayer]
+		if !ok {
+			token := ""
+			if client, ok := agent.(*Client); ok {
+				token = client.SessionToken
+			}
+			seat = arena.leaveSeat(fromPlayer, token)
+		}
+		if !seat.quitAnnounced {
+			seat.quitAnnounced = true
+			arena.Send(ArenaMessage{
+				MessageType: PlayerQuitEventType,
+				Data: PlayerQuitEventData{
+					Name: agent.Info().Name,
+				},
+			}, EXCLUDE, fromPlayer)
+		}
+		if !ok {
+			arena.driveGame()
+		}
 	} else if len(arena.agents) == 1 {
 		fmt.Println("Removing arena")
 		RemoveArena(arena.Name)
 	} else {
-		agent := arena.agents[fromPlayer]
-		Remove(&arena.agents, uint(fromPlayer))
+		arena.removeAgent(fromPlayer)
 		arena.Send(ArenaMessage{
 			MessageType: PlayerQuitEventType,
 			Data: PlayerQuitEventData{
-				Name: agent.Name,
+				Name: agent.Info().Name,
 			},
 		}, GLOBAL, 0)
 	}
-	return nil
+}
+
+// Sends the player everything they can currently see of the hand
+func (arena *Arena) SendSnapshot(playerIdx uint8) error {
+	arena.Lock()
+	defer arena.Unlock()
+	return arena.sendSnapshot(playerIdx)
+}
+
+func (arena *Arena) sendSnapshot(playerIdx uint8) error {
+	if !arena.gameStarted {
+		return CodedError{Code: GAME_NOT_STARTED}
+	}
+
+	return arena.Send(ArenaMessage{
+		MessageType: SnapshotEventType,
+		Data:        SnapshotEventData{View: arena.match.Game.PlayerView(playerIdx)},
+	}, PLAYER, playerIdx)
+}
+
+func (arena *Arena) HandleSnapshotAction(data SnapshotActionData, fromPlayer uint8) error {
+	return arena.SendSnapshot(fromPlayer)
 }
 
 // FinishRoundArena is called when the arena round should be finished. It broadcasts an end round message to the connected players
+// and sets up the next hand, unless the match is over. The caller drives the game on.
 func (arena *Arena) FinishRoundArena() {
-	arena.game.GetGameResults()
+	result, err := arena.match.Game.GetGameResults()
+	if err != nil {
+		panic(err)
+	}
+
+	arena.Send(ArenaMessage{
+		MessageType: ArenaBoardEventType,
+		Data: ArenaBoardEventData{
+			BoardEvent: BoardEvent{
+				EventType: GameEndEventType,
+				Data: GameEndEventData{
+					GameResult: result,
+					Wall:       arena.match.Game.RevealWall(),
+				},
+			},
+		},
+	}, GLOBAL, 0)
+	arena.flushLog()
+
+	if err := arena.match.FinishHand(); err != nil {
+		panic(err)
+	}
+
+	if !arena.match.Finished {
+		if err := arena.startHand(); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	arena.Send(ArenaMessage{
+		MessageType: ArenaBoardEventType,
+		Data: ArenaBoardEventData{
+			BoardEvent: BoardEvent{
+				EventType: MatchEndEventType,
+				Data: MatchEndEventData{
+					Points:     arena.match.Round.Points,
+					Placements: arena.match.Placements(),
+				},
+			},
+		},
+	}, GLOBAL, 0)
+	arena.flushLog()
+	arena.gameStarted = false
+	arena.removeAbandoned()
 }
 
 // EndArena is called when the arena is finished and all players should be disconnected
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
 	"github.com/google/uuid"
 )
 
@@ -18,25 +19,20 @@ type ArenaList struct {
 
 var GlobalArenaList ArenaList = ArenaList{}
 
-type ArenaNotFoundError struct {
-	arena_name string
-}
-
-type SameNameError struct {
-	arena_name string
-}
-
 func InitializeMap() {
 	GlobalArenaList.arena = make(map[uuid.UUID]*Arena)
 	GlobalArenaList.name = make(map[string]uuid.UUID)
 }
 
-func (e ArenaNotFoundError) Error() string {
-	return fmt.Sprintf("Arena %v is not found", e.arena_name)
+func arenaNotFoundError(name string) error {
+	return CodedError{Code: ARENA_NOT_FOUND, Reason: fmt.Sprintf("Arena %v is not found", name)}
 }
 
-func (e SameNameError) Error() string {
-	return fmt.Sprintf("There is already an arena with the same name: %v", e.arena_name)
+func sameNameError(name string) error {
+	return CodedError{
+		Code:   ARENA_NAME_TAKEN,
+		Reason: fmt.Sprintf("There is already an arena with the same name: %v", name),
+	}
 }
 
 func ListArenas() []string {
@@ -56,12 +52,12 @@ func GetArenaFromName(name string) (*Arena, error) {
 
 	uuid, ok := GlobalArenaList.name[name]
 	if !ok {
-		return nil, ArenaNotFoundError{name}
+		return nil, arenaNotFoundError(name)
 	}
 
 	arena, ok := GlobalArenaList.arena[uuid]
 	if !ok {
-		return nil, ArenaNotFoundError{name}
+		return nil, arenaNotFoundError(name)
 	}
 
 	return arena, nil
@@ -73,7 +69,7 @@ func GetArena(uuid uuid.UUID) (*Arena, error) {
 
 	arena, ok := GlobalArenaList.arena[uuid]
 	if !ok {
-		return nil, ArenaNotFoundError{uuid.String()}
+		return nil, arenaNotFoundError(uuid.String())
 	}
 
 	return arena, nil
@@ -86,7 +82,7 @@ func RemoveArena(name string) error {
 	uuid, ok := GlobalArenaList.name[name]
 	if !ok {
 		fmt.Println("Did not find arena: ", name)
-		return ArenaNotFoundError{name}
+		return arenaNotFoundError(name)
 	}
 
 	delete(GlobalArenaList.name, name)
@@ -106,7 +102,7 @@ func GetArenaUUID(name string) (uuid.UUID, error) {
 
 	uuid, ok := GlobalArenaList.name[name]
 	if !ok {
-		return uuid, ArenaNotFoundError{name}
+		return uuid, arenaNotFoundError(name)
 	}
 
 	return uuid, nil
@@ -118,7 +114,7 @@ func CreateAndAddArena(name string) error {
 
 	_, exists := GlobalArenaList.name[name]
 	if exists {
-		return SameNameError{name}
+		return sameNameError(name)
 	}
 
 	newUUID := uuid.New()
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"slices"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/google/uuid"
+)
+
+// A simple in-process player. Wins are always taken, calls are skipped,
+// and on its turn it discards its most isolated tile, or the drawn tile
+// once in riichi. Bots also take over the seats of players who left.
+type Bot struct {
+	Name string
+	ID   uuid.UUID
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The tile with the fewest copies and neighbours in the hand, which is
+// good enough to reach tenpai every now and then
+func isolatedTile(hand []Tile) Tile {
+	best, bestScore := hand[0], 100
+	for _, tile := range hand {
+		score := 0
+		for _, other := range hand {
+			diff := int(other.ClearRedOrDora()) - int(tile.ClearRedOrDora())
+			switch {
+			case diff == 0:
+				score += 3
+			case tile.IsSuited() && other.SameSuit(tile) && (diff == 1 || diff == -1):
+				score += 2
+			case tile.IsSuited() && other.SameSuit(tile) && (diff == 2 || diff == -2):
+				score += 1
+			}
+		}
+		if score < bestScore {
+			best, bestScore = tile, score
+		}
+	}
+	return best
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+func NewBot(name string) *Bot {
+	return &Bot{Name: name, ID: uuid.New()}
+}
+
+// Info implements Agent.
+func (bot *Bot) Info() AgentInfo {
+	return AgentInfo{Name: bot.Name, ID: bot.ID}
+}
+
+// Send implements Agent. Bots decide from the view of their seat when
+// they have to act, which holds everything the messages told the seat,
+// so they do not need the messages.
+func (bot *Bot) Send(ArenaMessage) {}
+
+// Act implements Actor.
+func (bot *Bot) Act(view PlayerView) []ActionData {
+	return ChooseActions(view)
+}
+
+// What the bot does with the actions it has: wins are always taken,
+// calls are skipped, and on its turn it discards its most isolated
+// tile, or the drawn tile once in riichi
+func ChooseActions(view PlayerView) []ActionData {
+	actions := view.PendingActions
+	for _, action := range actions {
+		if action.ActionType == TSUMO || action.ActionType == RON {
+			return []ActionData{action}
+		}
+	}
+
+	if slices.ContainsFunc(actions, func(action ActionData) bool { return action.ActionType == TOSS }) {
+		tile := isolatedTile(view.ClosedHand)
+		if view.Seats[view.Player].HandInRiichi && view.DrawnTile != Invalid {
+			tile = view.DrawnTile
+		}
+		return []ActionData{{ActionType: TOSS, Data: TossData{TileToToss: tile}}}
+	}
+
+	skips := make([]ActionData, 0, len(actions))
+	for _, action := range actions {
+		skips = append(skips, ActionData{ActionType: SKIP, Data: SkipData{ActionToSkip: action}})
+	}
+	return skips
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+func TestBotsFinishMatch(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	clients := make([]*Client, 4)
+	for idx := range clients {
+		clients[idx] = newTestClient()
+		if err := arena.JoinArena(clients[idx], true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everyone quits, and bots play the match to the end
+	for idx := range clients {
+		if err := arena.HandlePlayerQuitAction(PlayerQuitActionData{}, uint8(idx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandlePlayerAction(PlayerActionData{}, 0); err == nil {
+		t.Error("Player acted after quitting")
+	}
+
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		arena.Lock()
+		finished := arena.match.Finished
+		arena.Unlock()
+		if finished {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Bots did not finish the match")
+		}
+	}
+
+	arena.Lock()
+	defer arena.Unlock()
+	total := int32(0)
+	for _, points := range arena.match.Round.Points {
+		total += points
+	}
+	if total != 4*arena.match.Rules.StartingPoints {
+		t.Errorf("Points are not conserved: %v", arena.match.Round.Points)
+	}
+	if len(arena.agents) != 0 || len(arena.away) != 0 {
+		t.Errorf("%d players and %d seats are left after the match", len(arena.agents), len(arena.away))
+	}
+}
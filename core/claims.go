@@ -0,0 +1,270 @@
+package core
+
+import (
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+
+	"slices"
+)
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// Rons take priority over pons and kans, which take priority over chiis
+func claimPriority(actionType ActionType) int {
+	switch actionType {
+	case RON:
+		return 3
+	case PON, KAN:
+		return 2
+	case CHII:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// The current player discards a tile, which other players can now
+// claim
+func (game *MahjongGame) discard(tile Tile) {
+	game.DiscardedTile = tile
+	game.GameState = CURRENT_TURN_PLAYED
+	game.PendingActions = nil
+	game.ClaimsOffered = false
+	game.Claims = nil
+	game.RobbingKan = false
+}
+
+// A player claims the last discard with a call, taking over the turn
+func (game *MahjongGame) claimDiscard(fromPlayer uint8) {
+	game.discardPassed()
+	game.currentPlayer().DiscardCalled = true
+	game.CurrentTurnOrder = game.PlayerToOrder[fromPlayer]
+	game.interruptTurnOrder()
+
+	game.GameState = CURRENT_TURN
+	game.DrawnTile = Invalid
+	game.PendingActions = nil
+	game.Claims = nil
+}
+
+// Records a call or ron on the last discard. The player gives up every
+// other action they could have taken on it.
+func (game *MahjongGame) declareClaim(action ActionData, fromPlayer uint8) ([]MessageSendInfo, error) {
+	if game.GameState != CURRENT_TURN_PLAYED {
+		return nil, CodedError{Code: WRONG_STATE}
+	}
+	if _, err := game.findAction(action, fromPlayer); err != nil {
+		return nil, err
+	}
+
+	game.PendingActions = slices.DeleteFunc(game.PendingActions, func(pending PendingAction) bool {
+		return pending.fromPlayer == fromPlayer
+	})
+	game.Claims = append(game.Claims, PendingAction{ActionData: action, fromPlayer: fromPlayer})
+
+	return []MessageSendInfo{
+		privatePlayerAction(action, fromPlayer),
+	}, nil
+}
+
+// The claim with the highest priority. Of equal claims, the one
+// closest to the discarder in turn order wins.
+func (game MahjongGame) bestClaim() PendingAction {
+	best := game.Claims[0]
+	for _, claim := range game.Claims[1:] {
+		priority, bestPriority := claimPriority(claim.ActionType), claimPriority(best.ActionType)
+		if priority > bestPriority ||
+			(priority == bestPriority && game.turnDistance(claim.fromPlayer) < game.turnDistance(best.fromPlayer)) {
+			best = claim
+		}
+	}
+	return best
+}
+
+// How many turns after the current player the player is
+func (game MahjongGame) turnDistance(playerIdx uint8) uint8 {
+	return (game.PlayerToOrder[playerIdx] + 4 - game.CurrentTurnOrder) % 4
+}
+
+// Whether the claims can be settled, which is once no pending action
+// could still take priority over them. Another ron still has to be
+// waited on, since it could win alongside the declared ones.
+func (game MahjongGame) claimsDecided() bool {
+	if len(game.Claims) == 0 {
+		return false
+	}
+
+	priority := claimPriority(game.bestClaim().ActionType)
+	return !slices.ContainsFunc(game.PendingActions, func(pending PendingAction) bool {
+		return claimPriority(pending.ActionType) >= priority
+	})
+}
+
+// Makes the winning claim on the last discard
+func (game *MahjongGame) resolveClaims() ([]MessageSendInfo, error) {
+	claim := game.bestClaim()
+	switch claim.ActionType {
+	case RON:
+		return game.resolveRons()
+	case CHII:
+		return game.makeChii(claim.Data.(ChiiData), claim.fromPlayer)
+	case PON:
+		return game.makePon(claim.Data.(PonData), claim.fromPlayer)
+	case KAN:
+		return game.makeDaiminkan(claim.Data.(KanData), claim.fromPlayer)
+	default:
+		return nil, CodedError{Code: ACTION_NOT_ALLOWED}
+	}
+}
+
+// Settles the rons declared on the last discard
+func (game *MahjongGame) resolveRons() ([]MessageSendInfo, error) {
+	winners := make([]uint8, 0, 3)
+	for _, claim := range game.Claims {
+		if claim.ActionType == RON {
+			winners = append(winners, claim.fromPlayer)
+		}
+	}
+	slices.SortFunc(winners, func(a, b uint8) int {
+		return int(game.turnDistance(a)) - int(game.turnDistance(b))
+	})
+
+	if game.Rules.Sanchahou && len(winners) == 3 {
+		game.finishWithAbort(SANCHAHOU_RESULT, winners...)
+		return nil, nil
+	}
+	if !game.Rules.DoubleRon {
+		winners = winners[:1]
+	}
+
+	// A riichi declared with the ronned tile does not stand
+	game.RiichiPending = false
+
+	info := game.settlementInfo()
+	var gameResult GameResult
+	for idx, winner := range winners {
+		result, err := game.Players[winner].Ron(
+			game.DiscardedTile,
+			game.winContext(winner, false),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if idx == 0 {
+			gameResult = GenerateGameResult(result, winner, info)
+			info.Honba = 0
+			info.RiichiSticks = 0
+		} else {
+			gameResult.OtherWins = append(gameResult.OtherWins, GenerateGameResult(result, winner, info))
+		}
+	}
+
+	if err := game.finishWithWin(gameResult); err != nil {
+		return nil, err
+	}
+
+	messages := make([]MessageSendInfo, 0, len(winners))
+	for _, winner := range winners {
+		messages = append(messages, globalPlayerAction(ActionData{
+			ActionType: RON,
+			Data:       RonData{TileToRon: game.DiscardedTile},
+		}, winner))
+	}
+	return messages, nil
+}
+
+func (game *MahjongGame) makeChii(chiiData ChiiData, fromPlayer uint8) ([]MessageSendInfo, error) {
+	err := game.Players[fromPlayer].Chii(chiiData.TileToChii, chiiData.TilesInHand)
+	if err != nil {
+		return nil, err
+	}
+	game.claimDiscard(fromPlayer)
+
+	return append([]MessageSendInfo{
+		globalPlayerAction(ActionData{ActionType: CHII, Data: chiiData}, fromPlayer),
+	}, game.turnActionMessages()...), nil
+}
+
+func (game *MahjongGame) makePon(ponData PonData, fromPlayer uint8) ([]MessageSendInfo, error) {
+	player := &game.Players[fromPlayer]
+	tiles := player.CallTiles(ponData.TileToPon, 2)
+	if err := player.Pon(ponData.TileToPon); err != nil {
+		return nil, err
+	}
+	ponData.TilesInHand = [2]Tile(tiles)
+	game.claimDiscard(fromPlayer)
+
+	return append([]MessageSendInfo{
+		globalPlayerAction(ActionData{ActionType: PON, Data: ponData}, fromPlayer),
+	}, game.turnActionMessages()...), nil
+}
+
+func (game *MahjongGame) makeDaiminkan(kanData KanData, fromPlayer uint8) ([]MessageSendInfo, error) {
+	if int(game.KansDrawn) >= len(game.KanDraw) {
+		return nil, CodedError{Code: ACTION_NOT_ALLOWED, Reason: "No more kan draws"}
+	}
+	player := &game.Players[fromPlayer]
+	tiles := player.CallTiles(kanData.TileToKan, 3)
+	if err := player.Daiminkan(kanData.TileToKan); err != nil {
+		return nil, err
+	}
+	kanData.TilesInHand = [3]Tile(tiles)
+	game.claimDiscard(fromPlayer)
+
+	tile, err := game.drawKanTile()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]MessageSendInfo{
+		globalPlayerAction(ActionData{ActionType: KAN, Data: kanData}, fromPlayer),
+		drawMessage(tile, fromPlayer),
+	}, game.turnActionMessages()...), nil
+}
+
+// The tiles of a pon, which is kept as a single tile that is red if
+// one of its tiles is
+func ponTiles(pon Tile) [3]Tile {
+	kind := pon.ClearRedOrDora()
+	return [3]Tile{pon, kind, kind}
+}
+
+// Adds the tile to a pon of the current player. The other players can
+// ron the added tile like a discard, and the replacement tile is drawn
+// once none of them do.
+func (game *MahjongGame) makeShouminkan(kanData KanData, fromPlayer uint8) ([]MessageSendInfo, error) {
+	player := &game.Players[fromPlayer]
+	if err := player.TestShouminkan(kanData.TileToKan); err != nil {
+		return nil, err
+	}
+	pon := player.Pons[slices.IndexFunc(player.Pons, kanData.TileToKan.SameKind)]
+	if err := player.Shouminkan(kanData.TileToKan); err != nil {
+		return nil, err
+	}
+	kanData.TilesInHand = ponTiles(pon)
+
+	game.discard(kanData.TileToKan)
+	game.RobbingKan = true
+
+	return []MessageSendInfo{
+		globalPlayerAction(ActionData{ActionType: KAN, Data: kanData}, fromPlayer),
+	}, nil
+}
+
+// Nobody robbed the kan, which is made with a replacement tile
+func (game *MahjongGame) finishShouminkan() []MessageSendInfo {
+	game.RobbingKan = false
+	game.interruptTurnOrder()
+	game.GameState = CURRENT_TURN
+	game.PendingActions = nil
+
+	tile, err := game.drawKanTile()
+	if err != nil {
+		panic(err)
+	}
+	return append(
+		[]MessageSendInfo{drawMessage(tile, game.currentPlayerIdx())},
+		game.turnActionMessages()...,
+	)
+}
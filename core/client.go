@@ -2,11 +2,11 @@ package core
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
 )
@@ -16,9 +16,20 @@ type Client struct {
 	ID         uuid.UUID
 	Connection ConnChan
 	Recv       chan Message
-	Arena      *Arena
+	// Signalled when Recv is full, since the client fell too far behind
+	// to be sent anything more
+	lagging chan UnitType
+	Arena   *Arena
+	// Issued at the initial message, for reconnecting to the arena
+	SessionToken string
+	// The replay the client is stepping through, if any
+	replay   *Replay
+	replayID uuid.UUID
 }
 
+// How many messages are kept for a client before it is dropped
+const clientBuffer = 1024
+
 type DispatchResult struct {
 	Message Message
 	DoSend  bool
@@ -54,13 +65,14 @@ func SuccessMsg() DispatchResult {
 	}
 }
 
-func FailureMsg(err string) DispatchResult {
+func FailureMsg(err error) DispatchResult {
 	return DispatchResult{
 		Message: Message{
 			MessageType: GenericResponseType,
 			Data: GenericResponseData{
 				Success:    false,
-				FailReason: err,
+				Code:       CodeOf(err),
+				FailReason: err.Error(),
 			},
 		},
 		DoSend: true,
@@ -77,7 +89,8 @@ func MakeClient(connection ConnChan) (Client, error) {
 		Name:       "Unnamed User",
 		ID:         uuid,
 		Connection: connection,
-		Recv:       make(chan Message, 32),
+		Recv:       make(chan Message, clientBuffer),
+		lagging:    make(chan UnitType, 1),
 		Arena:      nil,
 	}
 	fmt.Println("Making new client", client)
@@ -95,6 +108,11 @@ func (client Client) Loop() {
 			}
 			fmt.Println("Sending", string(bytes))
 			client.Connection.Send(bytes)
+		case <-client.lagging:
+			fmt.Println("Dropping client that fell behind:", client.Name)
+			client.Connection.CloseConnChan()
+			client.HandleClientDestruction()
+			return
 		case recv := <-client.Connection.RecvChan():
 			if err, ok := recv.(error); ok {
 				fmt.Println("Error: ", err)
@@ -116,7 +134,7 @@ func (client Client) Loop() {
 
 			if dispatchResult.DoSend {
 				dispatchResult.Message.MessageIndex = msg.MessageIndex
-				client.GetSendChannel() <- dispatchResult.Message
+				client.push(dispatchResult.Message)
 			}
 		}
 	}
@@ -139,58 +157,133 @@ func (client *Client) HandleListArenas(data ListArenasActionData) (DispatchResul
 // HandleJoinArenaAction implements ServerHandler.
 func (client *Client) HandleJoinArena(data JoinArenaActionData) (DispatchResult, error) {
 	if client.Arena != nil {
-		err := errors.New("Already in an arena")
-		return FailureMsg(err.Error()), err
+		err := CodedError{Code: ALREADY_IN_ARENA}
+		return FailureMsg(err), err
 	}
 
 	arena, err := GetArenaFromName(data.ArenaName)
 	if err != nil {
-		return FailureMsg(err.Error()), err
+		return FailureMsg(err), err
 	}
 
-	err = arena.JoinArena(client, true)
+	if data.Spectate {
+		err = arena.Spectate(client, Spectator{View: data.View, Player: data.Player})
+	} else {
+		err = arena.JoinArena(client, true)
+	}
 	if err != nil {
-		return FailureMsg(err.Error()), err
+		return FailureMsg(err), err
 	}
 
 	client.Arena = arena
+	if !data.Spectate {
+		SetSessionArena(client.SessionToken, arena)
+	}
 	return SuccessMsg(), nil
 }
 
 func (client *Client) HandleInitialMessage(data InitialMessageActionData) (DispatchResult, error) {
+	// A session that expired, or whose client is still connected, is
+	// replaced by a new one
+	if session, err := GetSession(data.SessionToken); err == nil && client.resumeSession(session) {
+		return initialMessageResponse(session.Token, true), nil
+	}
+
 	if len(data.Name) != 0 {
 		fmt.Println("Renamed user to", data.Name)
 		client.Name = data.Name
 	}
-	return SuccessMsg(), nil
+
+	session, err := NewSession(client)
+	if err != nil {
+		return FailureMsg(err), err
+	}
+	client.SessionToken = session.Token
+	return initialMessageResponse(session.Token, false), nil
+}
+
+// Takes over the session of a client whose connection dropped, along
+// with the seat held for it. Returns false when no seat is held for the
+// session, which is then left to the client that still uses it.
+func (client *Client) resumeSession(session Session) bool {
+	name, id := client.Name, client.ID
+	client.Name = session.Name
+	client.ID = session.ID
+	client.SessionToken = session.Token
+
+	if session.Arena == nil || session.Arena.Reconnect(client) != nil {
+		client.Name, client.ID, client.SessionToken = name, id, ""
+		return false
+	}
+	return true
+}
+
+func initialMessageResponse(token string, resumed bool) DispatchResult {
+	return FormatMessage(InitialMessageResponseType, InitialMessageResponseData{
+		Success:      true,
+		SessionToken: token,
+		Resumed:      resumed,
+	})
+}
+
+// Tells the client whether the arena took its action, and if not, why
+func actionResponse(err error) DispatchResult {
+	if err != nil {
+		return FormatMessage(ArenaActionResponseType, ArenaActionResponseData{
+			Success:    false,
+			Code:       CodeOf(err),
+			FailReason: err.Error(),
+		})
+	}
+	return FormatMessage(ArenaActionResponseType, ArenaActionResponseData{Success: true})
 }
 
 func (client *Client) HandleServerArena(action ServerArenaActionData) (DispatchResult, error) {
-	if client.Arena != nil {
-		idx, err := client.Arena.getPlayerIdx(client)
-		if err != nil {
-			return DispatchResult{}, err
-		}
+	if client.Arena == nil {
+		err := CodedError{Code: NOT_IN_ARENA}
+		return actionResponse(err), err
+	}
+	if client.Arena.IsSpectator(client) {
+		return client.handleSpectatorArena(action)
+	}
+
+	idx, err := client.Arena.getPlayerIdx(client)
+	if err != nil {
+		// Another connection took over the seat
+		err = CodedError{Code: NOT_IN_ARENA}
+		return actionResponse(err), err
+	}
+
+	err = ArenaActionDispatch(client.Arena, action.ArenaMessage, idx)
+	return actionResponse(err), err
+}
 
-		// TODO: Do something with this result
-		ArenaActionDispatch(client.Arena, action.ArenaMessage, idx)
-		return DispatchResult{}, nil
+// Spectators can only leave the arena
+func (client *Client) handleSpectatorArena(action ServerArenaActionData) (DispatchResult, error) {
+	if action.ArenaMessage.MessageType != PlayerQuitActionType {
+		err := CodedError{Code: SPECTATOR_CANNOT_ACT}
+		return actionResponse(err), err
 	}
-	err := errors.New("No arena found")
-	return FailureMsg(err.Error()), err
+
+	err := client.Arena.StopSpectating(client)
+	if err != nil {
+		return actionResponse(err), err
+	}
+	client.Arena = nil
+	return actionResponse(nil), nil
 }
 
 func (client *Client) HandleCreateArena(data CreateArenaActionData) (DispatchResult, error) {
 	err := CreateAndAddArena(data.ArenaName)
 	if err != nil {
-		return FailureMsg(err.Error()), err
+		return FailureMsg(err), err
 	}
 	return SuccessMsg(), nil
 }
 
 func (client *Client) HandleGetArenaInfo(data ArenaInfoActionData) (DispatchResult, error) {
 	if client.Arena == nil {
-		return FailureMsg("Not in arena"), nil
+		return FailureMsg(CodedError{Code: NOT_IN_ARENA}), nil
 	}
 
 	return DispatchResult{
@@ -202,17 +295,79 @@ func (client *Client) HandleGetArenaInfo(data ArenaInfoActionData) (DispatchResu
 	}, nil
 }
 
+func (client *Client) HandleReplay(data ReplayActionData) (DispatchResult, error) {
+	if client.replay == nil || client.replayID != data.GameID {
+		replay, err := OpenReplay(data.GameID)
+		if err != nil {
+			return FailureMsg(err), nil
+		}
+		client.replay, client.replayID = replay, data.GameID
+	}
+
+	messages, err := client.replay.Seek(data.Position, Spectator{View: data.View, Player: data.Player})
+	if err != nil {
+		return FailureMsg(err), nil
+	}
+
+	return DispatchResult{
+		Message: Message{
+			MessageType: ReplayResponseType,
+			Data: ReplayResponseData{
+				Success:  true,
+				Position: client.replay.Position(),
+				Length:   client.replay.Len(),
+				Messages: messages,
+			},
+		},
+		DoSend: true,
+	}, nil
+}
+
 func (client *Client) HandleClientDestruction() {
+	if client.Arena != nil && client.Arena.IsSpectator(client) {
+		client.Arena.StopSpectating(client)
+		return
+	}
+
 	if client.Arena != nil {
-		idx, err := client.Arena.getPlayerIdx(client)
-		if err != nil {
+		// During a match, the seat is held for the client to reconnect to
+		if client.Arena.Disconnect(client) == nil {
 			return
 		}
 
-		err = client.Arena.HandlePlayerQuitAction(PlayerQuitActionData{}, idx)
+		idx, err := client.Arena.getPlayerIdx(client)
 		if err != nil {
+			// Another connection took over the seat, and the session
 			return
 		}
+		client.Arena.HandlePlayerQuitAction(PlayerQuitActionData{}, idx)
+	}
+	RemoveSession(client.SessionToken)
+}
+
+// Info implements Agent.
+func (client *Client) Info() AgentInfo {
+	return AgentInfo{Name: client.Name, ID: client.ID}
+}
+
+// Send implements Agent.
+func (client *Client) Send(message ArenaMessage) {
+	client.push(Message{
+		MessageType: ServerArenaEventType,
+		Data:        ServerArenaMessageEventData{ArenaMessage: message},
+	})
+}
+
+// Queues a message for the client without blocking. A client whose
+// queue is full is dropped, and its seat held for it to reconnect to.
+func (client *Client) push(message Message) {
+	select {
+	case client.Recv <- message:
+	default:
+		select {
+		case client.lagging <- Unit:
+		default:
+		}
 	}
 }
 
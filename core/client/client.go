@@ -0,0 +1,285 @@
+// A client for the websocket protocol of the server, for bots, load
+// tests and tools that play or watch games without the web app.
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/gorilla/websocket"
+)
+
+// How many events each channel holds before the client stops reading
+// from the server
+const eventBuffer = 64
+
+// A connection to the server. Requests wait for the response with the
+// same MessageIndex, and can be made from several goroutines at once.
+//
+// The arena events the server sends come on one channel per type. The
+// client stops reading from the server while a channel is full, so
+// every channel has to be drained. The channels are closed once the
+// connection is.
+type Client struct {
+	BoardEvents  chan BoardEvent
+	Timers       chan TimerEventData
+	Snapshots    chan SnapshotEventData
+	PlayerJoins  chan PlayerJoinedEventData
+	PlayerQuits  chan PlayerQuitEventData
+	SessionToken string
+	// Whether the session given to Dial got back its seat in an arena
+	Resumed bool
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	nextIndex uint
+	pending   map[uint]chan Message // By MessageIndex
+	err       error                 // Why the connection closed
+	done      chan struct{}
+}
+
+// A request the server turned down. Compare the code, not the reason,
+// to tell failures apart.
+type ResponseError struct {
+	Reason string
+	Code   ErrorCode
+}
+
+func (e ResponseError) Error() string {
+	return e.Reason
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// Hands an event to its channel
+func (client *Client) deliver(message ArenaMessage) {
+	switch data := message.Data.(type) {
+	case ArenaBoardEventData:
+		client.BoardEvents <- data.BoardEvent
+	case TimerEventData:
+		client.Timers <- data
+	case SnapshotEventData:
+		client.Snapshots <- data
+	case PlayerJoinedEventData:
+		client.PlayerJoins <- data
+	case PlayerQuitEventData:
+		client.PlayerQuits <- data
+	}
+}
+
+// Reads from the server until the connection closes, passing on events
+// and responses
+func (client *Client) readLoop() {
+	var err error
+	for {
+		var data []byte
+		_, data, err = client.conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		var message Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			fmt.Println("Error unmarshalling:", err)
+			continue
+		}
+		if event, ok := message.Data.(ServerArenaMessageEventData); ok {
+			client.deliver(event.ArenaMessage)
+			continue
+		}
+
+		client.mu.Lock()
+		response, ok := client.pending[message.MessageIndex]
+		delete(client.pending, message.MessageIndex)
+		client.mu.Unlock()
+		if ok {
+			response <- message
+		}
+	}
+
+	client.mu.Lock()
+	client.err = err
+	close(client.done)
+	client.mu.Unlock()
+
+	close(client.BoardEvents)
+	close(client.Timers)
+	close(client.Snapshots)
+	close(client.PlayerJoins)
+	close(client.PlayerQuits)
+}
+
+// Sends a message without waiting for a response, and returns its index
+func (client *Client) send(messageType MessageType, data any) (uint, chan Message, error) {
+	client.mu.Lock()
+	if client.err != nil {
+		client.mu.Unlock()
+		return 0, nil, client.err
+	}
+	client.nextIndex += 1
+	index := client.nextIndex
+	response := make(chan Message, 1)
+	client.pending[index] = response
+	client.mu.Unlock()
+
+	bytes, err := json.Marshal(Message{MessageType: messageType, MessageIndex: index, Data: data})
+	if err != nil {
+		return 0, nil, err
+	}
+	client.writeMu.Lock()
+	defer client.writeMu.Unlock()
+	return index, response, client.conn.WriteMessage(websocket.TextMessage, bytes)
+}
+
+// Sends a message and waits for the server to respond to it
+func (client *Client) request(messageType MessageType, data any) (Message, error) {
+	index, response, err := client.send(messageType, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case message := <-response:
+		switch data := message.Data.(type) {
+		case GenericResponseData:
+			if !data.Success {
+				return message, ResponseError{Reason: data.FailReason, Code: data.Code}
+			}
+		case ArenaActionResponseData:
+			if !data.Success {
+				return message, ResponseError{Reason: data.FailReason, Code: data.Code}
+			}
+		}
+		return message, nil
+	case <-client.done:
+		client.mu.Lock()
+		delete(client.pending, index)
+		err := client.err
+		client.mu.Unlock()
+		return Message{}, err
+	}
+}
+
+// Sends an arena action, and waits for the arena to take it. The events
+// it caused come before the response, so the channels need room for
+// them while waiting.
+func (client *Client) arenaAction(messageType ArenaMessageType, data any) error {
+	_, err := client.request(ServerArenaActionType, ServerArenaActionData{
+		ArenaMessage: ArenaMessage{MessageType: messageType, Data: data},
+	})
+	return err
+}
+
+// Checks the type of a response, and decodes its data
+func responseData[T any](message Message, err error) (T, error) {
+	var data T
+	if err != nil {
+		return data, err
+	}
+	data, ok := message.Data.(T)
+	if !ok {
+		return data, fmt.Errorf("Unexpected response type %d", message.MessageType)
+	}
+	return data, nil
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Connects to the websocket endpoint of a server, e.g.
+// ws://localhost:3000/game, and introduces the client by name. A
+// session token from an earlier connection resumes that session. The
+// header, which can be nil, is sent with the handshake, e.g. with the
+// Origin the server accepts.
+func Dial(url string, header http.Header, name string, sessionToken string) (*Client, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		BoardEvents: make(chan BoardEvent, eventBuffer),
+		Timers:      make(chan TimerEventData, eventBuffer),
+		Snapshots:   make(chan SnapshotEventData, eventBuffer),
+		PlayerJoins: make(chan PlayerJoinedEventData, eventBuffer),
+		PlayerQuits: make(chan PlayerQuitEventData, eventBuffer),
+		conn:        conn,
+		pending:     make(map[uint]chan Message),
+		done:        make(chan struct{}),
+	}
+	go client.readLoop()
+
+	response, err := responseData[InitialMessageResponseData](client.request(
+		InitialMessageActionType,
+		InitialMessageActionData{Name: name, SessionToken: sessionToken},
+	))
+	if err == nil && !response.Success {
+		err = errors.New("Server refused the connection")
+	}
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	client.SessionToken = response.SessionToken
+	client.Resumed = response.Resumed
+	return client, nil
+}
+
+// Closes the connection. Requests still waiting on a response fail.
+func (client *Client) Close() error {
+	client.writeMu.Lock()
+	client.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	client.writeMu.Unlock()
+	return client.conn.Close()
+}
+
+// The names of the arenas on the server
+func (client *Client) ListArenas() ([]string, error) {
+	response, err := responseData[ListArenasResponseData](client.request(ListArenasActionType, ListArenasActionData{}))
+	return response.ArenaList, err
+}
+
+func (client *Client) CreateArena(name string) error {
+	_, err := client.request(CreateArenaActionType, CreateArenaActionData{ArenaName: name})
+	return err
+}
+
+// Takes a seat in the arena
+func (client *Client) JoinArena(name string) error {
+	_, err := client.request(JoinArenaActionType, JoinArenaActionData{ArenaName: name})
+	return err
+}
+
+// Watches the game in the arena. The player is the one watched with
+// PLAYER_VIEW.
+func (client *Client) Spectate(name string, view SpectatorView, player uint8) error {
+	_, err := client.request(JoinArenaActionType, JoinArenaActionData{
+		ArenaName: name,
+		Spectate:  true,
+		View:      view,
+		Player:    player,
+	})
+	return err
+}
+
+func (client *Client) ArenaInfo() (ArenaInfoResponseData, error) {
+	return responseData[ArenaInfoResponseData](client.request(ArenaInfoActionType, ArenaInfoActionData{}))
+}
+
+// Starts the match in the arena the client is seated in
+func (client *Client) StartGame() error {
+	return client.arenaAction(StartGameActionType, StartGameActionData{})
+}
+
+// Takes an action in the game. A ResponseError tells why the game
+// turned it down.
+func (client *Client) SendAction(action ActionData) error {
+	return client.arenaAction(PlayerActionType, PlayerActionData{ActionData: action})
+}
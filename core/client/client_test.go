@@ -0,0 +1,139 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	core "codeberg.org/ijnakashiar/LibreRiichi/core"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	web "codeberg.org/ijnakashiar/LibreRiichi/core/web"
+	"github.com/gorilla/websocket"
+)
+
+// The only origin the test server accepts connections from
+const testOrigin = "http://localhost:3000"
+
+// Serves the game endpoint like the server does
+func startServer(t *testing.T) string {
+	t.Helper()
+	core.InitializeMap()
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
+		return r.Header.Get("Origin") == testOrigin
+	}}
+	server := web.Server{Rooms: &core.GlobalArenaList}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// The upgrader already answered the refused connection
+			return
+		}
+		server.AcceptConnection(conn)
+	}))
+	t.Cleanup(httpServer.Close)
+	return "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+// The next board event, skipping the ones the check does not accept
+func nextEvent(t *testing.T, client *Client, check func(BoardEvent) bool) BoardEvent {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-client.BoardEvents:
+			if !ok {
+				t.Fatal("Connection closed")
+			}
+			if check(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("No event came")
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	url := startServer(t)
+	if _, err := Dial(url, nil, "sdk", ""); err == nil {
+		t.Error("Connected without the origin the server accepts")
+	}
+	client, err := Dial(url, http.Header{"Origin": []string{testOrigin}}, "sdk", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if client.SessionToken == "" {
+		t.Error("No session was issued")
+	}
+
+	if err := client.CreateArena("sdk"); err != nil {
+		t.Fatal(err)
+	}
+	var responseErr ResponseError
+	if err := client.CreateArena("sdk"); !errors.As(err, &responseErr) || responseErr.Code != ARENA_NAME_TAKEN {
+		t.Errorf("Creating the arena again returned %v, want code %v", err, ARENA_NAME_TAKEN)
+	}
+	arenas, err := client.ListArenas()
+	if err != nil || !slices.Contains(arenas, "sdk") {
+		t.Fatalf("ListArenas() = %v, %v", arenas, err)
+	}
+	if err := client.JoinArena("sdk"); err != nil {
+		t.Fatal(err)
+	}
+
+	arena, err := core.GetArenaFromName("sdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bot 1", "bot 2", "bot 3"} {
+		if err := arena.JoinArena(core.NewBot(name), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err := client.ArenaInfo(); err != nil || len(info.Agents) != 4 {
+		t.Fatalf("ArenaInfo() = %+v, %v", info, err)
+	}
+
+	if err := client.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StartGame(); !errors.As(err, &responseErr) || responseErr.Code != GAME_ALREADY_STARTED {
+		t.Errorf("Starting the game again returned %v, want code %v", err, GAME_ALREADY_STARTED)
+	}
+	setup := nextEvent(t, client, func(event BoardEvent) bool { return event.EventType == GameSetupEventType })
+	var player uint8
+	for _, setup := range setup.Data.(GameSetupEventData).Setup {
+		if setup.Type == PLAYER_NUMBER {
+			player = setup.Data.(uint8)
+		}
+	}
+
+	// Discard the first tile drawn, once it is the client's turn, and
+	// skip the calls offered before it
+	isTurn := func(event BoardEvent) bool {
+		if potential, ok := event.Data.(PotentialActionEventData); ok && potential.ActionType != TOSS {
+			skip := ActionData{ActionType: SKIP, Data: SkipData{ActionToSkip: potential.ActionData}}
+			if err := client.SendAction(skip); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, ok := event.Data.(PlayerActionEventData)
+		return ok && data.ActionType == DRAW && data.FromPlayer == player
+	}
+	draw := nextEvent(t, client, isTurn).Data.(PlayerActionEventData)
+	tile := draw.Data.(DrawData).DrawnTile
+	if err := client.SendAction(ActionData{ActionType: TOSS, Data: TossData{TileToToss: tile}}); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, client, func(event BoardEvent) bool {
+		data, ok := event.Data.(PlayerActionEventData)
+		return ok && data.ActionType == TOSS && data.FromPlayer == player && data.Data == TossData{TileToToss: tile}
+	})
+}
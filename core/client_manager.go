@@ -1 +1,77 @@
 package core
+
+import (
+	"sync"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	"github.com/google/uuid"
+)
+
+// Identifies a client across connections, so that a client whose
+// connection dropped can reclaim its seat
+type Session struct {
+	Token string
+	Name  string
+	ID    uuid.UUID
+	Arena *Arena // The arena the client joined, if any
+}
+
+type SessionList struct {
+	sessions map[string]*Session
+
+	sync.RWMutex
+}
+
+var GlobalSessionList SessionList = SessionList{
+	sessions: make(map[string]*Session),
+}
+
+var SessionNotFoundError = CodedError{Code: SESSION_NOT_FOUND}
+
+// Issues a new session for the client
+func NewSession(client *Client) (*Session, error) {
+	token, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		Token: token.String(),
+		Name:  client.Name,
+		ID:    client.ID,
+		Arena: client.Arena,
+	}
+
+	GlobalSessionList.Lock()
+	defer GlobalSessionList.Unlock()
+	GlobalSessionList.sessions[session.Token] = session
+	return session, nil
+}
+
+// A copy of the session with the given token
+func GetSession(token string) (Session, error) {
+	GlobalSessionList.RLock()
+	defer GlobalSessionList.RUnlock()
+
+	session, ok := GlobalSessionList.sessions[token]
+	if !ok {
+		return Session{}, SessionNotFoundError
+	}
+	return *session, nil
+}
+
+// Records the arena the client of the session is in
+func SetSessionArena(token string, arena *Arena) {
+	GlobalSessionList.Lock()
+	defer GlobalSessionList.Unlock()
+
+	if session, ok := GlobalSessionList.sessions[token]; ok {
+		session.Arena = arena
+	}
+}
+
+func RemoveSession(token string) {
+	GlobalSessionList.Lock()
+	defer GlobalSessionList.Unlock()
+	delete(GlobalSessionList.sessions, token)
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+)
+
+// Sends an arena action as the client, and returns the response to it
+func arenaAction(t *testing.T, client *Client, messageType ArenaMessageType, data any) ArenaActionResponseData {
+	t.Helper()
+	result, _ := client.HandleServerArena(ServerArenaActionData{
+		ArenaMessage: ArenaMessage{MessageType: messageType, Data: data},
+	})
+	if !result.DoSend || result.Message.MessageType != ArenaActionResponseType {
+		t.Fatalf("Got %+v, want an arena action response", result)
+	}
+	return result.Message.Data.(ArenaActionResponseData)
+}
+
+func TestArenaActionResponse(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	client := newTestClient()
+
+	if got := arenaAction(t, client, StartGameActionType, StartGameActionData{}); got.Code != NOT_IN_ARENA {
+		t.Errorf("Starting outside an arena got %+v, want code %v", got, NOT_IN_ARENA)
+	}
+
+	if err := arena.JoinArena(client, true); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string // description of this test case
+		messageType ArenaMessageType
+		data        any
+		bots        int // Bots that join the arena before the action
+		want        ErrorCode
+	}{
+		{"action before the game", PlayerActionType, PlayerActionData{}, 0, GAME_NOT_STARTED},
+		{"start alone", StartGameActionType, StartGameActionData{}, 0, NOT_ENOUGH_PLAYERS},
+		{"start", StartGameActionType, StartGameActionData{}, 3, NO_ERROR},
+		{"start again", StartGameActionType, StartGameActionData{}, 0, GAME_ALREADY_STARTED},
+		{"data of another action", PlayerActionType, StartGameActionData{}, 0, BAD_MESSAGE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range tt.bots {
+				if err := arena.JoinArena(NewBot("bot"), true); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := arenaAction(t, client, tt.messageType, tt.data)
+			if got.Success != (tt.want == NO_ERROR) || got.Code != tt.want {
+				t.Errorf("Got %+v, want code %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureResponseCode(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	for range 4 {
+		if err := arena.JoinArena(NewBot("bot"), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := newTestClient()
+
+	result, _ := client.HandleGetArenaInfo(ArenaInfoActionData{})
+	if got := result.Message.Data.(GenericResponseData); got.Success || got.Code != NOT_IN_ARENA {
+		t.Errorf("Arena info outside an arena got %+v, want code %v", got, NOT_IN_ARENA)
+	}
+
+	err := arena.JoinArena(client, true)
+	if !errors.Is(err, CodedError{Code: ARENA_FULL}) {
+		t.Errorf("Joining a full arena returned %v, want code %v", err, ARENA_FULL)
+	}
+	result = FailureMsg(err)
+	if got := result.Message.Data.(GenericResponseData); got.Code != ARENA_FULL || got.FailReason != err.Error() {
+		t.Errorf("FailureMsg() = %+v, want code %v", got, ARENA_FULL)
+	}
+}
+
+func TestSendDoesNotBlock(t *testing.T) {
+	client := &Client{Name: "test", Recv: make(chan Message, 2), lagging: make(chan UnitType, 1)}
+	for range 3 {
+		client.Send(ArenaMessage{MessageType: SnapshotEventType})
+	}
+
+	if len(client.Recv) != 2 {
+		t.Errorf("Got %d messages queued, want 2", len(client.Recv))
+	}
+	select {
+	case <-client.lagging:
+	default:
+		t.Error("Client was not dropped once its queue was full")
+	}
+}
@@ -1,7 +1,149 @@
 package core
 
-type BadMessage struct{}
+import (
+	"errors"
+	"fmt"
+)
 
-func (BadMessage) Error() string {
-	return "Bad message"
+// Tells clients what kind of error a request ran into. The numbers are
+// part of the protocol, so a code keeps its number once it is added.
+// Codes are grouped by the layer that returns them.
+type ErrorCode uint16
+
+const (
+	NO_ERROR      ErrorCode = 0
+	UNKNOWN_ERROR ErrorCode = 1
+	BAD_MESSAGE   ErrorCode = 2 // The data does not match the type of the message
+
+	// Server
+	SESSION_NOT_FOUND ErrorCode = 100
+	ALREADY_IN_ARENA  ErrorCode = 101
+	NOT_IN_ARENA      ErrorCode = 102
+
+	// Arena
+	ARENA_NOT_FOUND      ErrorCode = 200
+	ARENA_NAME_TAKEN     ErrorCode = 201
+	ARENA_FULL           ErrorCode = 202
+	GAME_NOT_STARTED     ErrorCode = 203
+	GAME_ALREADY_STARTED ErrorCode = 204
+	NOT_ENOUGH_PLAYERS   ErrorCode = 205
+	PLAYERS_AWAY         ErrorCode = 206 // Seats are held for players to reconnect to
+	PLAYER_AWAY          ErrorCode = 207 // The player left, and a bot plays the seat
+	NO_SEAT_HELD         ErrorCode = 208 // No seat is held to reconnect to
+	SPECTATOR_CANNOT_ACT ErrorCode = 209
+	ALREADY_SPECTATING   ErrorCode = 210
+	NOT_SPECTATING       ErrorCode = 211
+	UNKNOWN_VIEW         ErrorCode = 212
+	CANNOT_SPECTATE      ErrorCode = 213 // Only clients can spectate
+
+	// Game
+	WRONG_STATE        ErrorCode = 300 // The game is not at a point where the action can be taken
+	NOT_YOUR_TURN      ErrorCode = 301
+	INVALID_TILE       ErrorCode = 302 // The tile cannot be used for the action
+	ACTION_NOT_ALLOWED ErrorCode = 303 // The action is not one the player can take now
+
+	// Hand, for the checks of Player
+	TILE_NOT_IN_HAND  ErrorCode = 400
+	TOO_MANY_TILES    ErrorCode = 401
+	TOO_FEW_TILES     ErrorCode = 402
+	NOT_A_SEQUENCE    ErrorCode = 403
+	NOT_ENOUGH_TILES  ErrorCode = 404 // Too few copies of the tile to call it
+	NO_PON            ErrorCode = 405 // No pon to add the tile to
+	NOT_WAITING       ErrorCode = 406 // The hand does not win on the tile
+	FURITEN           ErrorCode = 407
+	NO_YAKU_TO_WIN    ErrorCode = 408
+	NOT_TENPAI        ErrorCode = 409 // Discarding the tile leaves the hand not ready for riichi
+	TOO_FEW_TERMINALS ErrorCode = 410 // Fewer than nine terminals and honours for kyuushu kyuuhai
+
+	// Replay
+	REPLAY_UNAVAILABLE ErrorCode = 500 // The logs cannot be read, or the match is still going
+	OUT_OF_RANGE       ErrorCode = 501
+)
+
+var errorDescriptions = map[ErrorCode]string{
+	NO_ERROR:      "No error",
+	UNKNOWN_ERROR: "Unknown error",
+	BAD_MESSAGE:   "Bad message",
+
+	SESSION_NOT_FOUND: "Session not found",
+	ALREADY_IN_ARENA:  "Already in an arena",
+	NOT_IN_ARENA:      "Not in an arena",
+
+	ARENA_NOT_FOUND:      "Arena not found",
+	ARENA_NAME_TAKEN:     "There is already an arena with the same name",
+	ARENA_FULL:           "Arena is full",
+	GAME_NOT_STARTED:     "Game not started",
+	GAME_ALREADY_STARTED: "Game already started",
+	NOT_ENOUGH_PLAYERS:   "Not enough agents",
+	PLAYERS_AWAY:         "Waiting for players to reconnect",
+	PLAYER_AWAY:          "Player left the game",
+	NO_SEAT_HELD:         "No seat held for the session",
+	SPECTATOR_CANNOT_ACT: "Spectators cannot act",
+	ALREADY_SPECTATING:   "Already spectating",
+	NOT_SPECTATING:       "Not spectating",
+	UNKNOWN_VIEW:         "Unknown view",
+	CANNOT_SPECTATE:      "Only clients can spectate",
+
+	WRONG_STATE:        "Action cannot be taken at this point of the game",
+	NOT_YOUR_TURN:      "Not the player's turn",
+	INVALID_TILE:       "Tile cannot be used for the action",
+	ACTION_NOT_ALLOWED: "Action is not allowed",
+
+	TILE_NOT_IN_HAND:  "Tile not found",
+	TOO_MANY_TILES:    "Too many tiles in hand",
+	TOO_FEW_TILES:     "Too little tiles in hand",
+	NOT_A_SEQUENCE:    "Tiles are not in a sequence",
+	NOT_ENOUGH_TILES:  "Not enough tiles",
+	NO_PON:            "Does not have a pon",
+	NOT_WAITING:       "Tile is not part of waiting tiles",
+	FURITEN:           "Hand in furiten",
+	NO_YAKU_TO_WIN:    "No yaku",
+	NOT_TENPAI:        "Cannot riichi on tile",
+	TOO_FEW_TERMINALS: "Fewer than nine terminals and honours",
+
+	REPLAY_UNAVAILABLE: "Replay is not available",
+	OUT_OF_RANGE:       "Position out of range",
+}
+
+func (code ErrorCode) String() string {
+	if description, ok := errorDescriptions[code]; ok {
+		return description
+	}
+	return fmt.Sprintf("ErrorCode(%d)", code)
+}
+
+// An error that clients can tell apart by its code. Errors with the same
+// code match with errors.Is, whatever their reason:
+//
+//	errors.Is(err, CodedError{Code: NOT_YOUR_TURN})
+type CodedError struct {
+	Code ErrorCode
+	// What went wrong, when the description of the code is not enough
+	Reason string
+}
+
+func (err CodedError) Error() string {
+	if err.Reason != "" {
+		return err.Reason
+	}
+	return err.Code.String()
+}
+
+func (err CodedError) Is(target error) bool {
+	coded, ok := target.(CodedError)
+	return ok && coded.Code == err.Code
+}
+
+// The code of the first CodedError in the chain of the error, which is
+// UNKNOWN_ERROR if there is none
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return NO_ERROR
+	}
+
+	var coded CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return UNKNOWN_ERROR
 }
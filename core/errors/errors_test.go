@@ -0,0 +1,56 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCodedError(t *testing.T) {
+	notYourTurn := CodedError{Code: NOT_YOUR_TURN, Reason: "Player 2 cannot toss now"}
+	wrapped := fmt.Errorf("Action failed: %w", notYourTurn)
+
+	tests := []struct {
+		name   string // description of this test case
+		err    error
+		target error
+		want   bool
+	}{
+		{"same code, other reason", notYourTurn, CodedError{Code: NOT_YOUR_TURN}, true},
+		{"wrapped", wrapped, CodedError{Code: NOT_YOUR_TURN}, true},
+		{"other code", wrapped, CodedError{Code: WRONG_STATE}, false},
+		{"plain error", errors.New("Not your turn"), CodedError{Code: NOT_YOUR_TURN}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var coded CodedError
+	if !errors.As(wrapped, &coded) || coded != notYourTurn {
+		t.Errorf("errors.As() gave %+v, want %+v", coded, notYourTurn)
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		err  error
+		want ErrorCode
+	}{
+		{"no error", nil, NO_ERROR},
+		{"coded", CodedError{Code: ARENA_FULL}, ARENA_FULL},
+		{"wrapped", fmt.Errorf("Join failed: %w", CodedError{Code: ARENA_FULL}), ARENA_FULL},
+		{"plain error", errors.New("Something broke"), UNKNOWN_ERROR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package core
 
 import (
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
 
+	crand "crypto/rand"
+	"encoding/hex"
 	"errors"
+	"slices"
 )
 
 type MahjongState uint8
@@ -21,6 +25,8 @@ const (
 // have to re-check a lot of the actions
 
 type MahjongGame struct {
+	Rules GameRules
+
 	Players []Player
 	// Maps Player Index → Order
 	PlayerToOrder []uint8
@@ -32,23 +38,81 @@ type MahjongGame struct {
 	UraDora  []Tile
 	KanDraw  []Tile
 	Tiles    [136]Tile
+	// The seed the tiles were shuffled with. Playing a hand again from
+	// the same seed deals the same tiles.
+	Seed Seed
+	// The salted hash of the tiles published when the hand starts. The
+	// salt is kept secret until the hand ends.
+	WallHash string
+	WallSalt []byte
 
 	// Current turn lasts until everyone has finished their possible actions
 	// CurrentTurnOrder is in range (0, 4)
 	CurrentTurnOrder uint8
 	GameState        MahjongState
 	RoundWind        Wind
+	Kyoku            uint8 // The hand number within the round wind, from 0
 
 	// Represents the next, undrawn tile
 	TileIdx      uint8
 	DoraRevealed uint8
 	KansDrawn    uint8
 
+	// The tile the current player last drew, Invalid if their turn
+	// started with a call
+	DrawnTile Tile
+	// The tile that was last discarded
+	DiscardedTile Tile
+	// Whether the drawn tile is the replacement tile of a kan
+	RinshanDraw bool
+	// Set until the first call of the round. Tenhou, chiihou and
+	// double riichi are only possible during the first go-around
+	FirstGoAround bool
+
+	Honba        uint8
+	RiichiSticks uint8 // Riichi deposits on the table, worth 1000 each
+	// Set when the last discard declared riichi. The deposit is only
+	// made once the discard is not ronned.
+	RiichiPending bool
+
 	Results *GameResult // If game has finished, store the results here
 
 	// The list of potential actions that need to be either taken or skipped
-	// Need to attach a timer to them
+	// The arena skips them once the player runs out of time
 	PendingActions []PendingAction
+	// Whether the post-toss actions for the last discard were sent out
+	ClaimsOffered bool
+	// Calls and rons declared on the last discard. They are settled once
+	// every pending action that could take priority over them has been
+	// taken or skipped.
+	Claims []PendingAction
+	// Set while the tile the current player added to a pon can be
+	// ronned, which robs the kan. The replacement tile is only drawn
+	// once nobody does.
+	RobbingKan bool
+}
+
+// The state a hand starts from, carried over from the previous hands
+// of a match
+type RoundInfo struct {
+	RoundWind    Wind
+	Kyoku        uint8
+	Honba        uint8
+	RiichiSticks uint8
+	Points       []int32 // Indexed by player
+	// The order of each player in the first hand of the match. The
+	// player with order 0 is the first dealer.
+	Seats []uint8
+	// Counts the hands of the match, from 0 for the first one
+	Hand uint32
+	// Seeds the shuffle of the tiles of the hand
+	Seed Seed
+	// The 136 tiles of the hand in wall order, dealt as they are
+	// instead of shuffling when set. Meant for tests.
+	Wall []Tile
+	// Salts the hash of the wall, instead of a random salt when set.
+	// Meant for replays.
+	WallSalt []byte
 }
 
 type PendingAction struct {
@@ -61,36 +125,47 @@ type GameEndError struct{}
 
 func (GameEndError) Error() string { return "Game ended" }
 
-type BadActionError struct{}
-
-func (BadActionError) Error() string { return "Bad action" }
-
 // ==================== PRIVATE FUNCTIONS ====================
 
-// Sets up the game and the tiles for the very start of the game
-func (game *MahjongGame) setupGame() {
+// Sets up the game and the tiles for the start of a hand
+func (game *MahjongGame) setupGame(round RoundInfo) {
 	game.Players = make([]Player, 4)
 	game.PlayerToOrder = make([]uint8, 4)
-	for i := range game.PlayerToOrder {
-		game.PlayerToOrder[i] = uint8(i)
-	}
-	game.OrderToPlayer = make([]uint8, 0)
+	game.OrderToPlayer = make([]uint8, 4)
 
-	PermuteArray(game.PlayerToOrder)
-	for idx, order := range game.PlayerToOrder {
+	// The dealership passes to the next player in turn order every hand
+	for idx, seat := range round.Seats {
+		order := (seat + 4 - round.Kyoku%4) % 4
+		game.PlayerToOrder[idx] = order
 		game.OrderToPlayer[order] = uint8(idx)
 	}
-	game.Tiles = [136]Tile(GetTileList())
-	PermuteArray(game.Tiles[:])
+
+	game.Seed = round.Seed
+	if round.Wall != nil {
+		game.Tiles = [136]Tile(round.Wall)
+	} else if game.Rules.RedFives {
+		game.Tiles = [136]Tile(GetRedFiveTileList())
+		PermuteArrayWith(round.Seed.rng(), game.Tiles[:])
+	} else {
+		game.Tiles = [136]Tile(GetTileList())
+		PermuteArrayWith(round.Seed.rng(), game.Tiles[:])
+	}
+	game.WallSalt = slices.Clone(round.WallSalt)
+	if game.WallSalt == nil {
+		game.WallSalt = make([]byte, 16)
+		crand.Read(game.WallSalt)
+	}
+	game.WallHash = HashWall(game.WallSalt, game.Tiles[:])
 	game.CurrentTurnOrder = 3         // To initiate the first draw
 	game.GameState = POST_TURN_PLAYED // To initiate the first draw
-	game.RoundWind = East
+	game.RoundWind = round.RoundWind
+	game.Kyoku = round.Kyoku
 
 	tileItr := 0
 	for idx, order := range game.PlayerToOrder {
 		player := &game.Players[idx]
 		*player = Player{
-			Points:   25000,
+			Points:   round.Points[idx],
 			SeatWind: Wind(order) + East,
 		}
 		player.FreshHand(game.Tiles[tileItr : tileItr+13])
@@ -107,43 +182,293 @@ func (game *MahjongGame) setupGame() {
 	tileItr += 4
 	game.LiveWall = game.Tiles[tileItr:]
 	game.TileIdx = 0
+	game.DrawnTile = Invalid
+	game.DiscardedTile = Invalid
+	game.RinshanDraw = false
+	game.FirstGoAround = true
+	game.Honba = round.Honba
+	game.RiichiSticks = round.RiichiSticks
+	game.RiichiPending = false
 
 	game.Results = nil
 	game.PendingActions = nil
+	game.ClaimsOffered = false
+	game.Claims = nil
+	game.RobbingKan = false
+}
+
+// The number of tiles left to draw. Every kan takes one tile away
+// from the end of the live wall to replenish the dead wall.
+func (game MahjongGame) wallRemaining() int {
+	return len(game.LiveWall) - int(game.TileIdx) - int(game.KansDrawn)
 }
 
 func (game *MahjongGame) drawNewTile() (Tile, error) {
-	if len(game.LiveWall) == 0 {
+	if game.wallRemaining() <= 0 {
 		return Invalid, GameEndError{}
 	}
 	if game.GameState != POST_TURN_PLAYED {
-		return Invalid, errors.New("Not in right state to draw")
+		return Invalid, CodedError{Code: WRONG_STATE, Reason: "Not in right state to draw"}
 	}
 
 	tile := game.LiveWall[game.TileIdx]
+	if err := game.currentPlayer().Draw(tile); err != nil {
+		return Invalid, err
+	}
 	game.TileIdx += 1
+	game.DrawnTile = tile
+	game.RinshanDraw = false
 	return tile, nil
 }
 
-func (game MahjongGame) lastTile() (Tile, error) {
-	if game.TileIdx == 0 {
-		return Invalid, errors.New("No last tile")
+// Draws the replacement tile after a kan and reveals a new dora indicator
+func (game *MahjongGame) drawKanTile() (Tile, error) {
+	if int(game.KansDrawn) >= len(game.KanDraw) {
+		return Invalid, CodedError{Code: ACTION_NOT_ALLOWED, Reason: "No more kan draws"}
 	}
 
+	tile := game.KanDraw[game.KansDrawn]
+	if err := game.currentPlayer().Draw(tile); err != nil {
+		return Invalid, err
+	}
+	game.KansDrawn += 1
+	game.DoraRevealed += 1
+	game.DrawnTile = tile
+	game.RinshanDraw = true
+	return tile, nil
+}
+
+// Returns the drawn tile during a player's turn, or the discarded
+// tile after it
+func (game MahjongGame) lastTile() (Tile, error) {
+	var tile Tile
 	switch game.GameState {
 	case CURRENT_TURN:
-		// The drawn tile
-		return game.LiveWall[game.TileIdx-1], nil
-	case CURRENT_TURN_PLAYED:
-		// The tile just discarded
-		return game.LiveWall[game.TileIdx-1], nil
-	case POST_TURN_PLAYED:
-		return game.LiveWall[game.TileIdx-1], nil
-	case GAME_ENDED:
-		return game.LiveWall[game.TileIdx-1], nil
+		tile = game.DrawnTile
+	case CURRENT_TURN_PLAYED, POST_TURN_PLAYED:
+		tile = game.DiscardedTile
 	default:
-		return Invalid, nil
+		return Invalid, CodedError{Code: WRONG_STATE, Reason: "No last tile"}
 	}
+
+	if tile == Invalid {
+		return Invalid, CodedError{Code: WRONG_STATE, Reason: "No last tile"}
+	}
+	return tile, nil
+}
+
+// Called once the last discard can no longer be ronned, which
+// makes any riichi declared with it stand
+func (game *MahjongGame) discardPassed() {
+	if !game.RiichiPending {
+		return
+	}
+	game.RiichiPending = false
+	game.currentPlayer().Points -= 1000
+	game.RiichiSticks += 1
+}
+
+// Information needed to settle a win by the player
+func (game MahjongGame) settlementInfo() SettlementInfo {
+	return SettlementInfo{
+		Dealer:       game.OrderToPlayer[0],
+		Discarder:    game.currentPlayerIdx(),
+		NumPlayers:   uint8(len(game.Players)),
+		Honba:        game.Honba,
+		RiichiSticks: game.RiichiSticks,
+	}
+}
+
+// Settles a win and ends the game
+func (game *MahjongGame) finishWithWin(gameResult GameResult) error {
+	if err := gameResult.Apply(game.Players); err != nil {
+		return err
+	}
+
+	game.RiichiSticks = 0
+	game.Results = &gameResult
+	game.GameState = GAME_ENDED
+	return nil
+}
+
+// Ends the game when the live wall runs out, paying the tenpai
+// players or nagashi mangan
+func (game *MahjongGame) finishWithDraw() error {
+	numPlayers := len(game.Players)
+	tenpai := make([]bool, numPlayers)
+	nagashi := make([]bool, numPlayers)
+	hands := make([]Hand, numPlayers)
+	for idx, player := range game.Players {
+		tenpai[idx] = player.IsTenpai()
+		nagashi[idx] = player.HasNagashiMangan()
+		if tenpai[idx] {
+			hands[idx] = player.Hand
+		}
+	}
+
+	gameResult := GenerateDrawResult(tenpai, nagashi, game.settlementInfo())
+	gameResult.RevealedHands = hands
+	if err := gameResult.Apply(game.Players); err != nil {
+		return err
+	}
+
+	game.Results = &gameResult
+	game.GameState = GAME_ENDED
+	return nil
+}
+
+// Ends the hand without any payments
+func (game *MahjongGame) finishWithAbort(resultType ResultType, revealedBy ...uint8) {
+	gameResult := GameResult{
+		Type:            resultType,
+		PointsTransfers: make([]PointsTransfer, 0),
+		RevealedHands:   make([]Hand, len(game.Players)),
+	}
+	for _, playerIdx := range revealedBy {
+		gameResult.RevealedHands[playerIdx] = game.Players[playerIdx].Hand
+	}
+
+	game.Results = &gameResult
+	game.GameState = GAME_ENDED
+}
+
+// Checks for the abortive draws that happen once a discard passes
+// without being ronned. Returns whether the hand was aborted.
+func (game *MahjongGame) checkAbortAfterDiscard() bool {
+	rules := game.Rules
+
+	if rules.SuufonRenda && game.FirstGoAround {
+		first := game.Players[0].Discards
+		suufon := len(first) == 1 && first[0].IsWind()
+		for _, player := range game.Players {
+			suufon = suufon && len(player.Discards) == 1 &&
+				player.Discards[0].ClearRedOrDora() == first[0].ClearRedOrDora()
+		}
+		if suufon {
+			game.finishWithAbort(SUUFON_RENDA_RESULT)
+			return true
+		}
+	}
+
+	if rules.SuuchaRiichi && !slices.ContainsFunc(game.Players, func(player Player) bool {
+		return !player.HandInRiichi
+	}) {
+		game.finishWithAbort(SUUCHA_RIICHI_RESULT, 0, 1, 2, 3)
+		return true
+	}
+
+	if rules.Suukaikan && game.KansDrawn == 4 && !slices.ContainsFunc(game.Players, func(player Player) bool {
+		return len(player.Kans)+len(player.Ankans) == 4
+	}) {
+		game.finishWithAbort(SUUKAIKAN_RESULT)
+		return true
+	}
+
+	return false
+}
+
+// Marks that a call was made, which interrupts the first go-around
+// and every ippatsu
+func (game *MahjongGame) interruptTurnOrder() {
+	game.FirstGoAround = false
+	for idx := range game.Players {
+		game.Players[idx].Ippatsu = false
+	}
+}
+
+// Describes the situation a player would win in right now
+func (game MahjongGame) winContext(playerIdx uint8, tsumo bool) WinContext {
+	player := game.Players[playerIdx]
+	return WinContext{
+		Tsumo:        tsumo,
+		Riichi:       player.HandInRiichi,
+		DoubleRiichi: player.DoubleRiichi,
+		Ippatsu:      player.Ippatsu,
+		LastTile:     game.wallRemaining() == 0 && !game.RobbingKan,
+		Rinshan:      tsumo && game.RinshanDraw,
+		Chankan:      !tsumo && game.RobbingKan,
+		FirstTurn:    game.FirstGoAround && len(player.Discards) == 0,
+		SeatWind:     player.SeatWind,
+		RoundWind:    game.RoundWind,
+
+		DoraIndicators:    game.Dora[:game.DoraRevealed],
+		UraDoraIndicators: game.UraDora[:game.DoraRevealed],
+	}
+}
+
+// Kyuushu kyuuhai can only be declared on a player's first draw, when
+// no calls have been made
+func (game MahjongGame) canDeclareKyuushuKyuuhai(playerIdx uint8) bool {
+	player := game.Players[playerIdx]
+	return game.FirstGoAround && len(player.Discards) == 0 &&
+		game.DrawnTile != Invalid && player.TestKyuushuKyuuhai() == nil
+}
+
+// A hand in riichi can only make an ankan with the tile it just drew,
+// and only when the kan leaves its waits as they were
+func (game MahjongGame) canAnkanInRiichi(tile Tile) bool {
+	return game.DrawnTile != Invalid && game.DrawnTile.SameKind(tile) &&
+		game.Players[game.currentPlayerIdx()].TestRiichiAnkan(game.DrawnTile) == nil
+}
+
+// The actions the current player can take on their turn, other than
+// the toss
+func (game MahjongGame) getTurnActions() []ActionData {
+	playerIdx := game.currentPlayerIdx()
+	player := game.Players[playerIdx]
+	actions := make([]ActionData, 0)
+
+	if game.DrawnTile != Invalid &&
+		player.TestTsumo(game.DrawnTile, game.winContext(playerIdx, true)) == nil {
+		actions = append(actions, ActionData{
+			ActionType: TSUMO,
+			Data:       TsumoData{TileToTsumo: game.DrawnTile},
+		})
+	}
+
+	if game.Rules.KyuushuKyuuhai && game.canDeclareKyuushuKyuuhai(playerIdx) {
+		actions = append(actions, ActionData{
+			ActionType: KYUUSHU_KYUUHAI,
+			Data:       KyuushuKyuuhaiData{},
+		})
+	}
+
+	// Riichi needs at least a tile left for each player to draw
+	if game.wallRemaining() >= 4 {
+		for _, discard := range player.GetRiichiDiscards() {
+			actions = append(actions, ActionData{
+				ActionType: RIICHI,
+				Data:       RiichiData{TileToRiichi: discard},
+			})
+		}
+	}
+
+	if int(game.KansDrawn) < len(game.KanDraw) {
+		offered := make([]Tile, 0)
+		for _, tile := range player.ClosedHand {
+			kind := tile.ClearRedOrDora()
+			if slices.Contains(offered, kind) || player.TestAnkan(tile) != nil ||
+				(player.HandInRiichi && !game.canAnkanInRiichi(kind)) {
+				continue
+			}
+			offered = append(offered, kind)
+			actions = append(actions, ActionData{
+				ActionType: KAN,
+				Data:       KanData{TileToKan: kind},
+			})
+		}
+
+		for _, tile := range player.ClosedHand {
+			if player.TestShouminkan(tile) == nil {
+				actions = append(actions, ActionData{
+					ActionType: KAN,
+					Data:       KanData{TileToKan: tile, Added: true},
+				})
+			}
+		}
+	}
+
+	return actions
 }
 
 func (game *MahjongGame) currentPlayer() *Player {
@@ -155,7 +480,7 @@ func (game MahjongGame) currentPlayerIdx() uint8 {
 }
 
 func (game MahjongGame) nextPlayerIdx() uint8 {
-	return game.OrderToPlayer[game.CurrentTurnOrder+1]
+	return game.OrderToPlayer[(game.CurrentTurnOrder+1)%4]
 }
 
 func (game *MahjongGame) incrementTurn() {
@@ -171,7 +496,19 @@ func (game MahjongGame) findAction(action ActionData, fromPlayer uint8) (int, er
 		}
 	}
 
-	return 0, errors.New("Can't find action")
+	return 0, CodedError{Code: ACTION_NOT_ALLOWED, Reason: "Can't find action"}
+}
+
+// The distinct tiles of the same kind as the tile, so that a red five
+// and a normal five are told apart
+func tilesOfKind(tiles []Tile, kind Tile) []Tile {
+	found := make([]Tile, 0, 2)
+	for _, tile := range tiles {
+		if tile.ClearRedOrDora() == kind && !slices.Contains(found, tile) {
+			found = append(found, tile)
+		}
+	}
+	return found
 }
 
 func encodeBoardEvent(eventType BoardEventType, data any) ArenaBoardEventData {
@@ -223,6 +560,20 @@ func globalPlayerAction(data ActionData, fromPlayer uint8) MessageSendInfo {
 	}
 }
 
+// The draw is only shown in full to the player drawing
+func drawMessage(tile Tile, playerIdx uint8) MessageSendInfo {
+	return makeMessage(
+		PARTIAL,
+		playerIdx,
+		encodePlayerAction(
+			ActionData{
+				ActionType: DRAW,
+				Data:       DrawData{DrawnTile: tile},
+			},
+			playerIdx,
+		))
+}
+
 func privatePlayerAction(data ActionData, fromPlayer uint8) MessageSendInfo {
 	return MessageSendInfo{
 		Events: []ArenaBoardEventData{
@@ -233,14 +584,49 @@ func privatePlayerAction(data ActionData, fromPlayer uint8) MessageSendInfo {
 	}
 }
 
+// The potential actions of the current player during their turn
+func (game MahjongGame) turnActionMessages() []MessageSendInfo {
+	actions := make([]MessageSendInfo, 0)
+	for _, action := range game.turnActions() {
+		actions = append(actions, makeMessage(
+			PLAYER,
+			game.currentPlayerIdx(),
+			encodePotentialAction(action),
+		))
+	}
+	return actions
+}
+
 // ==================== PUBLIC FUNCTIONS ====================
 
+// The first hand of a match. The seats, and the tiles of every hand of
+// the match, are drawn from the match seed.
+func NewRoundInfo(startingPoints int32, matchSeed Seed) RoundInfo {
+	round := RoundInfo{
+		RoundWind: East,
+		Points:    make([]int32, 4),
+		Seats:     []uint8{0, 1, 2, 3},
+	}
+	for idx := range round.Points {
+		round.Points[idx] = startingPoints
+	}
+	PermuteArrayWith(matchSeed.derive("seats", 0).rng(), round.Seats)
+	round.Seed = matchSeed.derive("hand", 0)
+	return round
+}
+
 // Returns data to send to clients when a new game can be started, otherwise an error
-func (game *MahjongGame) StartNewGame() ([][]Setup, error) {
+func (game *MahjongGame) StartNewGame(round RoundInfo) ([][]Setup, error) {
+	if len(round.Points) != 4 || len(round.Seats) != 4 {
+		return nil, errors.New("Round needs points and a seat for every player")
+	}
+	if round.Wall != nil && len(round.Wall) != len(game.Tiles) {
+		return nil, errors.New("Wall needs 136 tiles")
+	}
 
-	game.setupGame()
+	game.setupGame(round)
 	setup := make([][]Setup, 4)
-	startingPoints := [4]uint32{
+	startingPoints := [4]int32{
 		game.Players[0].Points,
 		game.Players[1].Points,
 		game.Players[2].Points,
@@ -248,7 +634,7 @@ func (game *MahjongGame) StartNewGame() ([][]Setup, error) {
 	}
 
 	for idx, player := range game.Players {
-		setup[idx] = make([]Setup, 0, 7)
+		setup[idx] = make([]Setup, 0, 10)
 		setup[idx] = append(setup[idx],
 			Setup{
 				Type: INITIAL_TILES,
@@ -268,7 +654,7 @@ func (game *MahjongGame) StartNewGame() ([][]Setup, error) {
 			},
 			Setup{
 				Type: ROUND_NUMBER,
-				Data: 0,
+				Data: game.Kyoku,
 			},
 			Setup{
 				Type: ROUND_WIND,
@@ -277,6 +663,18 @@ func (game *MahjongGame) StartNewGame() ([][]Setup, error) {
 			Setup{
 				Type: STARTING_POINTS,
 				Data: startingPoints,
+			},
+			Setup{
+				Type: HONBA,
+				Data: game.Honba,
+			},
+			Setup{
+				Type: RIICHI_STICKS,
+				Data: game.RiichiSticks,
+			},
+			Setup{
+				Type: WALL_HASH,
+				Data: game.WallHash,
 			})
 	}
 
@@ -288,93 +686,73 @@ func (game *MahjongGame) GetNextEvent() (actions []MessageSendInfo, shouldEnd bo
 	switch game.GameState {
 
 	case CURRENT_TURN: // The current player can make a toss move
-		// We should only reach this state when someone makes a post-turn action like pon.
-		// Then the player only has the choice to discard or kan
-
-		// TODO: Check if the player can make a kan
-		actions = []MessageSendInfo{
-			makeMessage(
-				PLAYER,
-				game.currentPlayerIdx(),
-				encodePotentialAction(
-					ActionData{
-						ActionType: TOSS,
-						Data:       TossData{Invalid},
-					},
-				)),
-		}
+		// We should only reach this state when someone makes a post-turn action like pon or kan.
+		// Then the player only has the choice to discard, kan, or tsumo on a replacement tile
+		actions = game.turnActionMessages()
 		shouldEnd = false
 
 	case CURRENT_TURN_PLAYED: // Get post-toss actions
 		// We should wait for all post toss actions to finish before moving to the next turn
-		pendingActions, err := game.getPostTossActions()
-		if err != nil {
-			panic(err)
+		if !game.ClaimsOffered {
+			pendingActions, err := game.getPostTossActions()
+			if err != nil {
+				panic(err)
+			}
+			game.PendingActions = pendingActions
+			game.ClaimsOffered = true
+
+			for _, pendingAction := range pendingActions {
+				actions = append(actions, makeMessage(
+					PLAYER,
+					pendingAction.fromPlayer,
+					encodePotentialAction(pendingAction.ActionData),
+				))
+			}
 		}
-		game.PendingActions = pendingActions
 
-		if len(pendingActions) == 0 {
-			game.GameState = POST_TURN_PLAYED
-			// TODO: Get next event again here?
+		if game.claimsDecided() {
+			claimed, err := game.resolveClaims()
+			if err != nil {
+				panic(err)
+			}
+			return append(actions, claimed...), game.GameState == GAME_ENDED
+		}
+
+		if len(game.PendingActions) == 0 && game.RobbingKan {
+			return append(actions, game.finishShouminkan()...), false
 		}
 
-		for _, pendingAction := range pendingActions {
-			actions = append(actions, makeMessage(
-				PLAYER,
-				pendingAction.fromPlayer,
-				encodePotentialAction(pendingAction.ActionData),
-			))
+		if len(game.PendingActions) == 0 {
+			game.GameState = POST_TURN_PLAYED
+			next, shouldEnd := game.GetNextEvent()
+			return append(actions, next...), shouldEnd
 		}
 
 		shouldEnd = false
 
 	case POST_TURN_PLAYED: // The post-toss has been played, we should progress to the next turn
-		game.GameState = CURRENT_TURN
+		game.discardPassed()
+		if game.checkAbortAfterDiscard() {
+			return nil, true
+		}
+
 		game.incrementTurn()
 		tile, err := game.drawNewTile()
 		if errors.Is(err, GameEndError{}) {
-			game.GameState = GAME_ENDED
+			if err := game.finishWithDraw(); err != nil {
+				panic(err)
+			}
 			return nil, true
 		}
-
-		actions = []MessageSendInfo{
-			makeMessage(
-				PARTIAL,
-				game.currentPlayerIdx(),
-				encodeBoardEvent(
-					PlayerActionEventType,
-					PlayerActionEventData{
-						ActionData: ActionData{
-							ActionType: DRAW,
-							Data:       DrawData{tile},
-						},
-						FromPlayer: game.currentPlayerIdx(),
-					})),
-			makeMessage(
-				PLAYER,
-				game.currentPlayerIdx(),
-				encodePotentialAction(
-					ActionData{
-						ActionType: TOSS,
-						Data:       TossData{Invalid},
-					},
-				)),
-		}
-
-		// For performing a Riichi
-		for _, discard := range game.currentPlayer().GetRiichiDiscards() {
-			actions = append(actions,
-				makeMessage(
-					PLAYER,
-					game.currentPlayerIdx(),
-					encodePotentialAction(
-						ActionData{
-							ActionType: RIICHI,
-							Data:       RiichiData{discard},
-						},
-					),
-				))
+		if err != nil {
+			panic(err)
 		}
+		game.GameState = CURRENT_TURN
+
+		actions = append(
+			[]MessageSendInfo{drawMessage(tile, game.currentPlayerIdx())},
+			game.turnActionMessages()...,
+		)
 
 		shouldEnd = false
 
@@ -392,160 +770,104 @@ func (game *MahjongGame) GetNextEvent() (actions []MessageSendInfo, shouldEnd bo
 // func (game *MahjongGame) RespondToAction(action PlayerActionData) ([]MessageSendInfo, bool) {
 // }
 
+// Declares a chii on the last discard, which is made once no other
+// claim can take priority over it
 func (game *MahjongGame) HandleChii(chiiData ChiiData, fromPlayer uint8) ([]MessageSendInfo, error) {
-	onTile := chiiData.TileToChii
-	chiiSequence := chiiData.TilesInHand
-
-	last, err := game.lastTile()
-	if err != nil || onTile != last {
-		return nil, BadActionError{}
-	}
-
-	if game.GameState != CURRENT_TURN_PLAYED {
-		return nil, BadActionError{}
-	}
-	if fromPlayer != game.nextPlayerIdx() {
-		return nil, BadActionError{}
-	}
-	err = game.Players[fromPlayer].Chii(
-		Tile(onTile),
-		[2]Tile{
-			Tile(chiiSequence[0]),
-			Tile(chiiSequence[1]),
-		})
-	if err != nil {
-		return nil, BadActionError{}
-	}
-
-	game.CurrentTurnOrder = fromPlayer
-
-	return []MessageSendInfo{
-		globalPlayerAction(ActionData{CHII, chiiData}, fromPlayer),
-	}, nil
+	return game.declareClaim(ActionData{ActionType: CHII, Data: chiiData}, fromPlayer)
 }
 
 func (game *MahjongGame) HandleKan(kanData KanData, fromPlayer uint8) (info []MessageSendInfo, err error) {
 	switch game.GameState {
-	case CURRENT_TURN: // Ankan
+	case CURRENT_TURN: // Ankan or shouminkan
 
 		if fromPlayer != game.currentPlayerIdx() {
-			return nil, BadActionError{}
+			return nil, CodedError{Code: NOT_YOUR_TURN}
 		}
-
-		err := game.Players[fromPlayer].Ankan(kanData.TileToKan)
-		if err != nil {
-			break
+		// The same checks as the kans offered in getTurnActions, made
+		// before the hand changes
+		if int(game.KansDrawn) >= len(game.KanDraw) {
+			return nil, CodedError{Code: ACTION_NOT_ALLOWED, Reason: "No more kan draws"}
 		}
-
-		info = []MessageSendInfo{
-			makeGlobalMessage(
-				encodePlayerAction(ActionData{KAN, kanData}, fromPlayer),
-			),
+		if kanData.Added {
+			return game.makeShouminkan(kanData, fromPlayer)
 		}
-
-	case CURRENT_TURN_PLAYED: // Daiminkan
-		if fromPlayer == game.currentPlayerIdx() {
-			break
+		if game.Players[fromPlayer].HandInRiichi && !game.canAnkanInRiichi(kanData.TileToKan) {
+			return nil, CodedError{Code: ACTION_NOT_ALLOWED, Reason: "Cannot kan in riichi"}
 		}
 
-		lastTile, err := game.lastTile()
-		if err != nil || lastTile != kanData.TileToKan {
+		tiles := game.Players[fromPlayer].CallTiles(kanData.TileToKan, 4)
+		err = game.Players[fromPlayer].Ankan(kanData.TileToKan)
+		if err != nil {
 			break
 		}
+		kanData = KanData{TileToKan: tiles[3], TilesInHand: [3]Tile(tiles)}
+		game.interruptTurnOrder()
 
-		err = game.Players[fromPlayer].Daiminkan(kanData.TileToKan)
+		var tile Tile
+		tile, err = game.drawKanTile()
 		if err != nil {
 			break
 		}
 
-		game.CurrentTurnOrder = fromPlayer
 		info = []MessageSendInfo{
-			globalPlayerAction(ActionData{KAN, kanData}, fromPlayer),
+			makeGlobalMessage(
+				encodePlayerAction(ActionData{ActionType: KAN, Data: kanData}, fromPlayer),
+			),
+			drawMessage(tile, fromPlayer),
 		}
 
+	case CURRENT_TURN_PLAYED: // Daiminkan
+		return game.declareClaim(ActionData{ActionType: KAN, Data: kanData}, fromPlayer)
+
 	case POST_TURN_PLAYED: // Invalid
-		err = BadActionError{}
+		err = CodedError{Code: WRONG_STATE}
 	case GAME_ENDED: // Invalid
-		err = BadActionError{}
+		err = CodedError{Code: WRONG_STATE}
+	}
+
+	if info == nil && err == nil {
+		err = CodedError{Code: WRONG_STATE}
 	}
 	return info, err
 }
 
+// Declares a pon on the last discard, which is made once no other
+// claim can take priority over it
 func (game *MahjongGame) HandlePon(ponData PonData, fromPlayer uint8) ([]MessageSendInfo, error) {
-	last, err := game.lastTile()
-	onTile := ponData.TileToPon
-	if err != nil || onTile != last {
-		return nil, BadActionError{}
-	}
-	if game.GameState != CURRENT_TURN_PLAYED {
-		return nil, BadActionError{}
-	}
-	if fromPlayer != game.nextPlayerIdx() {
-		return nil, BadActionError{}
-	}
-
-	err = game.Players[fromPlayer].Pon(onTile)
-	if err != nil {
-		return nil, BadActionError{}
-	}
-	game.CurrentTurnOrder = fromPlayer
-
-	return []MessageSendInfo{
-		globalPlayerAction(ActionData{PON, ponData}, fromPlayer),
-	}, nil
-
+	return game.declareClaim(ActionData{ActionType: PON, Data: ponData}, fromPlayer)
 }
 
+// Declares a ron on the last discard. The ron is settled once every
+// other player who can ron has responded.
 func (game *MahjongGame) HandleRon(ronData RonData, fromPlayer uint8) ([]MessageSendInfo, error) {
-
-	if fromPlayer == game.currentPlayerIdx() {
-		return nil, BadActionError{}
-	}
-	_, err := game.findAction(ActionData{RON, ronData}, fromPlayer)
-	if err != nil {
-		return nil, BadActionError{}
-	}
-
-	result, err := game.Players[fromPlayer].Ron(ronData.TileToRon)
-	if err != nil {
-		return nil, BadActionError{}
-	}
-
-	gameResult := GenerateGameResult(result, fromPlayer)
-	// err = gameResult.Apply(game)
-	if err != nil {
-		return nil, BadActionError{}
-	}
-
-	game.Results = &gameResult
-	game.GameState = GAME_ENDED
-	return []MessageSendInfo{
-		globalPlayerAction(ActionData{RON, ronData}, fromPlayer),
-	}, nil
+	return game.declareClaim(ActionData{ActionType: RON, Data: ronData}, fromPlayer)
 }
 
 func (game *MahjongGame) HandleRiichi(riichiData RiichiData, fromPlayer uint8) ([]MessageSendInfo, error) {
 
-	tileDrawn, err := game.lastTile()
-	if err != nil || riichiData.TileToRiichi != tileDrawn {
-		return nil, BadActionError{}
-	}
 	if game.GameState != CURRENT_TURN {
-		return nil, BadActionError{}
+		return nil, CodedError{Code: WRONG_STATE}
 	}
 	if fromPlayer != game.currentPlayerIdx() {
-		return nil, BadActionError{}
+		return nil, CodedError{Code: NOT_YOUR_TURN}
+	}
+	if game.wallRemaining() < 4 {
+		return nil, CodedError{Code: ACTION_NOT_ALLOWED, Reason: "Too few tiles left to riichi"}
 	}
 
-	err = game.Players[fromPlayer].Riichi(riichiData.TileToRiichi)
+	player := &game.Players[fromPlayer]
+	firstTurn := game.FirstGoAround && len(player.Discards) == 0
+	err := player.Riichi(riichiData.TileToRiichi)
 	if err != nil {
-		return nil, BadActionError{}
+		return nil, err
 	}
+	player.DoubleRiichi = firstTurn
 
-	game.GameState = CURRENT_TURN_PLAYED
+	game.RiichiPending = true
+	game.discard(riichiData.TileToRiichi)
 
 	return []MessageSendInfo{
-		globalPlayerAction(ActionData{RIICHI, riichiData}, fromPlayer),
+		globalPlayerAction(ActionData{ActionType: RIICHI, Data: riichiData}, fromPlayer),
 	}, nil
 }
 
@@ -557,12 +879,12 @@ func (game *MahjongGame) HandleSkip(skipData SkipData, fromPlayer uint8) ([]Mess
 		fromPlayer,
 	)
 	if err != nil {
-		return nil, BadActionError{}
+		return nil, err
 	}
 	// TODO: Check if the action is skippable, e.g. a toss is not skippable
 	Remove(&game.PendingActions, idx)
 	return []MessageSendInfo{
-		privatePlayerAction(ActionData{SKIP, skipData}, fromPlayer),
+		privatePlayerAction(ActionData{ActionType: SKIP, Data: skipData}, fromPlayer),
 	}, nil
 }
 
@@ -570,47 +892,54 @@ func (game *MahjongGame) HandleToss(tossData TossData, fromPlayer uint8) ([]Mess
 
 	onTile := tossData.TileToToss
 	if game.GameState != CURRENT_TURN {
-		return nil, BadActionError{}
+		return nil, CodedError{Code: WRONG_STATE}
 	}
 	if fromPlayer != game.currentPlayerIdx() {
-		return nil, BadActionError{}
+		return nil, CodedError{Code: NOT_YOUR_TURN}
+	}
+	// A hand in riichi can only discard the tile it drew
+	if game.Players[fromPlayer].HandInRiichi && onTile != game.DrawnTile {
+		return nil, CodedError{Code: INVALID_TILE}
 	}
 	err := game.Players[fromPlayer].Toss(onTile)
 	if err != nil {
-		return nil, BadActionError{}
+		return nil, err
 	}
 
-	game.GameState = CURRENT_TURN_PLAYED
+	game.discard(onTile)
 	return []MessageSendInfo{
-		globalPlayerAction(ActionData{TOSS, tossData}, fromPlayer),
+		globalPlayerAction(ActionData{ActionType: TOSS, Data: tossData}, fromPlayer),
 	}, nil
 }
 
 func (game *MahjongGame) HandleTsumo(tsumoData TsumoData, fromPlayer uint8) ([]MessageSendInfo, error) {
 
 	if fromPlayer != game.currentPlayerIdx() {
-		return nil, BadActionError{}
+		return nil, CodedError{Code: NOT_YOUR_TURN}
 	}
 	last, err := game.lastTile()
-	if err != nil || tsumoData.TileToTsumo != last {
-		return nil, BadActionError{}
+	if err != nil {
+		return nil, CodedError{Code: WRONG_STATE}
+	}
+	if tsumoData.TileToTsumo != last {
+		return nil, CodedError{Code: INVALID_TILE}
 	}
 
-	result, err := game.Players[fromPlayer].Tsumo(tsumoData.TileToTsumo)
+	result, err := game.Players[fromPlayer].Tsumo(
+		tsumoData.TileToTsumo,
+		game.winContext(fromPlayer, true),
+	)
 	if err != nil {
-		return nil, BadActionError{}
+		return nil, err
 	}
 
-	gameResult := GenerateGameResult(result, fromPlayer)
-	// err = gameResult.Apply(game)
+	err = game.finishWithWin(GenerateGameResult(result, fromPlayer, game.settlementInfo()))
 	if err != nil {
-		return nil, BadActionError{}
+		return nil, err
 	}
 
-	game.Results = &gameResult
-	game.GameState = GAME_ENDED
 	return []MessageSendInfo{
-		globalPlayerAction(ActionData{TSUMO, tsumoData}, fromPlayer),
+		globalPlayerAction(ActionData{ActionType: TSUMO, Data: tsumoData}, fromPlayer),
 	}, nil
 }
 
@@ -618,10 +947,28 @@ func (game *MahjongGame) HandleDraw(drawData DrawData, fromPlayer uint8) ([]Mess
 	panic("NYI")
 }
 
+func (game *MahjongGame) HandleKyuushuKyuuhai(data KyuushuKyuuhaiData, fromPlayer uint8) ([]MessageSendInfo, error) {
+
+	if game.GameState != CURRENT_TURN {
+		return nil, CodedError{Code: WRONG_STATE}
+	}
+	if fromPlayer != game.currentPlayerIdx() {
+		return nil, CodedError{Code: NOT_YOUR_TURN}
+	}
+	if !game.Rules.KyuushuKyuuhai || !game.canDeclareKyuushuKyuuhai(fromPlayer) {
+		return nil, CodedError{Code: ACTION_NOT_ALLOWED}
+	}
+
+	game.finishWithAbort(KYUUSHU_KYUUHAI_RESULT, fromPlayer)
+	return []MessageSendInfo{
+		globalPlayerAction(ActionData{ActionType: KYUUSHU_KYUUHAI, Data: data}, fromPlayer),
+	}, nil
+}
+
 // Checks the post-toss actions that can be made
 func (game *MahjongGame) getPostTossActions() ([]PendingAction, error) {
 	if game.GameState != CURRENT_TURN_PLAYED {
-		return nil, errors.New("Incorrect state")
+		return nil, CodedError{Code: WRONG_STATE, Reason: "Incorrect state"}
 	}
 
 	if len(game.PendingActions) != 0 {
@@ -636,62 +983,77 @@ func (game *MahjongGame) getPostTossActions() ([]PendingAction, error) {
 	nextPlayerIdx := game.nextPlayerIdx()
 	nextPlayer := game.Players[nextPlayerIdx]
 	moves := make([]PendingAction, 0)
+	canCall := game.wallRemaining() > 0
 
 	// Helper that appends a potential move
 	appendMove := func(action ActionData, forPlayer uint8) {
 		moves = append(moves,
-			PendingAction{action, forPlayer})
+			PendingAction{ActionData: action, fromPlayer: forPlayer})
 	}
 
-	// Iterate through all possible combinations of Chii
-	{
-		tileNum := tileTossed.GetTileNumber()
+	// The tile added to a pon can only be ronned
+	if game.RobbingKan {
+		canCall = false
+	}
 
-		// Call when the chii move is valid
-		appendChiiMove := func(chiiSequence [2]Tile) {
-			appendMove(ActionData{CHII, ChiiData{
-				TileToChii:  tileTossed,
-				TilesInHand: chiiSequence,
-			}}, nextPlayerIdx)
+	// Iterate through all possible combinations of Chii
+	if canCall && !nextPlayer.HandInRiichi {
+		kind := tileTossed.ClearRedOrDora()
+		tileNum := kind.GetTileNumber()
+
+		// Call when the chii move is valid. A red five and a normal five
+		// in hand are offered as separate moves.
+		appendChiiMove := func(kinds [2]Tile) {
+			for _, first := range tilesOfKind(nextPlayer.ClosedHand, kinds[0]) {
+				for _, second := range tilesOfKind(nextPlayer.ClosedHand, kinds[1]) {
+					chiiSequence := [2]Tile{first, second}
+					if nextPlayer.TestChii(tileTossed, chiiSequence) != nil {
+						continue
+					}
+					appendMove(ActionData{ActionType: CHII, Data: ChiiData{
+						TileToChii:  tileTossed,
+						TilesInHand: chiiSequence,
+					}}, nextPlayerIdx)
+				}
+			}
 		}
 
 		if tileNum <= 6 { // 6, 7, 8
-			chiiSequence := [2]Tile{tileTossed + 1, tileTossed + 2}
-			if nextPlayer.TestChii(tileTossed, chiiSequence) == nil {
-				appendChiiMove(chiiSequence)
-			}
+			appendChiiMove([2]Tile{kind + 1, kind + 2})
 		}
 		if tileNum >= 2 { // 0, 1, 2
-			chiiSequence := [2]Tile{tileTossed - 1, tileTossed - 2}
-			if nextPlayer.TestChii(tileTossed, chiiSequence) == nil {
-				appendChiiMove(chiiSequence)
-			}
+			appendChiiMove([2]Tile{kind - 2, kind - 1})
 		}
 		if tileNum >= 1 && tileNum <= 7 { // Middle
-			chiiSequence := [2]Tile{tileTossed + 1, tileTossed - 1}
-			if nextPlayer.TestChii(tileTossed, chiiSequence) == nil {
-				appendChiiMove(chiiSequence)
-			}
+			appendChiiMove([2]Tile{kind - 1, kind + 1})
 		}
 	}
 
 	// Iterate through all kans, pons, and rons
 	for idx, player := range game.Players {
-		if player.TestDaiminkan(tileTossed) == nil {
-			appendMove(ActionData{KAN, KanData{
-				TileToKan: tileTossed,
+		if uint8(idx) == game.currentPlayerIdx() {
+			continue
+		}
+
+		if player.TestRon(tileTossed, game.winContext(uint8(idx), false)) == nil {
+			appendMove(ActionData{ActionType: RON, Data: RonData{
+				TileToRon: tileTossed,
 			}}, uint8(idx))
 		}
 
-		if player.TestPon(tileTossed) == nil {
-			appendMove(ActionData{PON, PonData{
-				TileToPon: tileTossed,
+		if !canCall || player.HandInRiichi {
+			continue
+		}
+
+		if int(game.KansDrawn) < len(game.KanDraw) && player.TestDaiminkan(tileTossed) == nil {
+			appendMove(ActionData{ActionType: KAN, Data: KanData{
+				TileToKan: tileTossed,
 			}}, uint8(idx))
 		}
 
-		if player.TestRon(tileTossed) == nil {
-			appendMove(ActionData{RON, RonData{
-				TileToRon: tileTossed,
+		if player.TestPon(tileTossed) == nil {
+			appendMove(ActionData{ActionType: PON, Data: PonData{
+				TileToPon: tileTossed,
 			}}, uint8(idx))
 		}
 	}
@@ -700,8 +1062,91 @@ func (game *MahjongGame) getPostTossActions() ([]PendingAction, error) {
 }
 
 // Return the game results
-func (MahjongGame) GetGameResults() (GameResult, error) {
-	return GameResult{}, nil
+func (game MahjongGame) GetGameResults() (GameResult, error) {
+	if game.GameState != GAME_ENDED || game.Results == nil {
+		return GameResult{}, CodedError{Code: WRONG_STATE, Reason: "Game has not ended"}
+	}
+	return *game.Results, nil
+}
+
+// The wall of the hand with the salt of its hash, which is only to be
+// shown once the hand has ended. Showing it gives nothing away about the
+// hands still to come, since their seeds are derived from the match seed
+// and not from this one.
+func (game MahjongGame) RevealWall() WallReveal {
+	return WallReveal{
+		Salt:  hex.EncodeToString(game.WallSalt),
+		Tiles: slices.Clone(game.Tiles[:]),
+	}
+}
+
+// Whether the dealer keeps their seat for the next hand, which happens
+// when they win it, are tenpai at an exhaustive draw, or the hand is
+// aborted
+func (game MahjongGame) DealerRepeats() bool {
+	if game.Results == nil {
+		return false
+	}
+
+	dealer := game.OrderToPlayer[0]
+	switch game.Results.Type {
+	case WIN_RESULT:
+		return slices.Contains(game.Results.Winners(), dealer)
+	case EXHAUSTIVE_DRAW_RESULT:
+		return game.Results.Tenpai[dealer]
+	default:
+		return game.Results.IsAbortive()
+	}
+}
+
+// The players the game is waiting on to act
+func (game MahjongGame) AwaitedPlayers() []uint8 {
+	switch game.GameState {
+	case CURRENT_TURN:
+		return []uint8{game.currentPlayerIdx()}
+	case CURRENT_TURN_PLAYED:
+		players := make([]uint8, 0, 3)
+		for _, pending := range game.PendingActions {
+			if !slices.Contains(players, pending.fromPlayer) {
+				players = append(players, pending.fromPlayer)
+			}
+		}
+		return players
+	default:
+		return nil
+	}
+}
+
+// The actions taken for a player who ran out of time: skipping every
+// call they could make, or discarding the tile they drew
+func (game MahjongGame) DefaultActions(playerIdx uint8) []ActionData {
+	switch game.GameState {
+	case CURRENT_TURN:
+		if playerIdx != game.currentPlayerIdx() {
+			return nil
+		}
+		hand := game.Players[playerIdx].ClosedHand
+		tile := game.DrawnTile
+		if !slices.Contains(hand, tile) {
+			tile = Last(hand)
+		}
+		return []ActionData{{ActionType: TOSS, Data: TossData{TileToToss: tile}}}
+
+	case CURRENT_TURN_PLAYED:
+		actions := make([]ActionData, 0)
+		for _, pending := range game.PendingActions {
+			if pending.fromPlayer == playerIdx {
+				actions = append(actions, ActionData{
+					ActionType: SKIP,
+					Data:       SkipData{ActionToSkip: pending.ActionData},
+				})
+			}
+		}
+		return actions
+
+	default:
+		return nil
+	}
 }
 
 // Returns the maximum amount of players
@@ -709,12 +1154,27 @@ func (MahjongGame) GetMaxPlayers() int {
 	return 4
 }
 
-func GetAltMessage(msg ArenaMessage) (altMsg ArenaMessage, err error) {
+// The version of a PARTIAL message that the players it is not meant for
+// receive, if they receive it at all
+func GetAltMessage(msg ArenaMessage) (altMsg ArenaMessage, send bool, err error) {
 	if msg.MessageType != ArenaBoardEventType {
-		return altMsg, errors.New("Not correct type")
+		return altMsg, false, CodedError{Code: BAD_MESSAGE, Reason: "Not correct type"}
+	}
+	eventData, ok := msg.Data.(ArenaBoardEventData)
+	if !ok {
+		return altMsg, false, CodedError{Code: BAD_MESSAGE, Reason: "Not correct type"}
+	}
+
+	handler := AltMessageHandler{}
+	if err := BoardEventDispatch(&handler, eventData.BoardEvent); err != nil {
+		return altMsg, false, err
+	}
+	if !handler.Send {
+		return altMsg, false, nil
 	}
-	eventData := msg.Data.(ArenaBoardEventData)
-	BoardEventDispatch(AltMessageHandler{}, eventData.BoardEvent)
 
-	return ArenaMessage{}, nil
+	return ArenaMessage{
+		MessageType: ArenaBoardEventType,
+		Data:        ArenaBoardEventData{BoardEvent: handler.Event},
+	}, true, nil
 }
@@ -18,6 +18,9 @@ const (
 	KAN
 	CHII
 	DRAW
+	// Abort the hand on the first draw with nine different terminals
+	// and honours
+	KYUUSHU_KYUUHAI
 )
 
 type ActionData struct {
@@ -35,11 +38,11 @@ type ActionHandler[T any, E any] interface {
 	HandleKan(KanData, E) (T, error)
 	HandleChii(ChiiData, E) (T, error)
 	HandleDraw(DrawData, E) (T, error)
+	HandleKyuushuKyuuhai(KyuushuKyuuhaiData, E) (T, error)
 }
 
 type RonData struct {
-	TileToRon Tile      `json:"tile_to_ron"`
-	WinResult WinResult `json:"win_result"`
+	TileToRon Tile `json:"tile_to_ron"`
 }
 
 type TsumoData struct {
@@ -60,10 +63,20 @@ type SkipData struct {
 
 type PonData struct {
 	TileToPon Tile `json:"tile_to_pon"`
+	// The tiles taken from the hand, red fives included. Filled in by
+	// the game once the pon is made.
+	TilesInHand [2]Tile `json:"tiles_in_hand"`
 }
 
 type KanData struct {
 	TileToKan Tile `json:"tile_to_kan"`
+	// The other tiles taken from the hand, red fives included. Filled
+	// in by the game once the kan is made, when TileToKan of an ankan
+	// becomes the fourth tile taken from the hand.
+	TilesInHand [3]Tile `json:"tiles_in_hand"`
+	// Whether the kan adds TileToKan to a pon of the player, a
+	// shouminkan. TilesInHand are then the tiles of the pon.
+	Added bool `json:"added"`
 }
 
 type ChiiData struct {
@@ -75,6 +88,8 @@ type DrawData struct {
 	DrawnTile Tile `json:"drawn_tile"`
 }
 
+type KyuushuKyuuhaiData struct{}
+
 func (msg *ActionData) UnmarshalJSON(rawData []byte) error {
 	var raw struct {
 		ActionType ActionType      `json:"action_type"`
@@ -102,6 +117,13 @@ func (msg *ActionData) UnmarshalJSON(rawData []byte) error {
 			return err
 		}
 		msg.Data = message
+	case KYUUSHU_KYUUHAI:
+		message := KyuushuKyuuhaiData{}
+		err := json.Unmarshal(raw.Data, &message)
+		if err != nil {
+			return err
+		}
+		msg.Data = message
 	case KAN:
 		message := KanData{}
 		err := json.Unmarshal(raw.Data, &message)
@@ -152,7 +174,7 @@ func (msg *ActionData) UnmarshalJSON(rawData []byte) error {
 		}
 		msg.Data = message
 	default:
-		return fmt.Errorf("unexpected core.ActionType: %#v", raw.ActionType)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.ActionType: %#v", raw.ActionType)}
 	}
 	return nil
 }
@@ -162,58 +184,64 @@ func ActionDecode[T any, E any](handler ActionHandler[T, E], data ActionData, ex
 	case CHII:
 		message, ok := data.Data.(ChiiData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleChii(message, extraData)
 	case DRAW:
 		message, ok := data.Data.(DrawData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleDraw(message, extraData)
+	case KYUUSHU_KYUUHAI:
+		message, ok := data.Data.(KyuushuKyuuhaiData)
+		if !ok {
+			return ret, CodedError{Code: BAD_MESSAGE}
+		}
+		return handler.HandleKyuushuKyuuhai(message, extraData)
 	case KAN:
 		message, ok := data.Data.(KanData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleKan(message, extraData)
 	case PON:
 		message, ok := data.Data.(PonData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandlePon(message, extraData)
 	case RIICHI:
 		message, ok := data.Data.(RiichiData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleRiichi(message, extraData)
 	case RON:
 		message, ok := data.Data.(RonData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleRon(message, extraData)
 	case SKIP:
 		message, ok := data.Data.(SkipData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleSkip(message, extraData)
 	case TOSS:
 		message, ok := data.Data.(TossData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleToss(message, extraData)
 	case TSUMO:
 		message, ok := data.Data.(TsumoData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleTsumo(message, extraData)
 	default:
-		return ret, fmt.Errorf("unexpected core.ActionType: %#v", data.ActionType)
+		return ret, CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.ActionType: %#v", data.ActionType)}
 	}
 }
@@ -0,0 +1,291 @@
+package core
+
+import "slices"
+
+// ==================== TYPES ====================
+
+type MeldType uint8
+
+const (
+	SEQUENCE MeldType = iota
+	TRIPLET
+	QUAD
+)
+
+// A group of tiles in a complete hand. For sequences, Tile is the
+// lowest tile of the run
+type Meld struct {
+	Type MeldType `json:"type"`
+	Tile Tile     `json:"tile"`
+	Open bool     `json:"open"`
+}
+
+type HandShape uint8
+
+const (
+	STANDARD_SHAPE   HandShape = iota // Four melds and a pair
+	CHIITOITSU_SHAPE                  // Seven pairs
+	KOKUSHI_SHAPE                     // Thirteen orphans
+)
+
+// One way of reading a complete hand
+type Decomposition struct {
+	Shape HandShape `json:"shape"`
+	Melds []Meld    `json:"melds"`
+	// The pair of a standard hand, the seven pairs of chiitoitsu, or
+	// the doubled tile of kokushi musou
+	Pairs []Tile `json:"pairs"`
+	// Index into Melds of the meld completed by the winning tile, -1
+	// if the winning tile completed a pair (or the hand has no melds)
+	WinningMeld int `json:"winning_meld"`
+}
+
+// Number of each tile, indexed by the tile with the red and dora bits cleared
+type tileCounts [Green + 1]uint8
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+func countTiles(tiles []Tile) (counts tileCounts) {
+	for _, tile := range tiles {
+		if tile = tile.ClearRedOrDora(); tile <= Green {
+			counts[tile] += 1
+		}
+	}
+	return counts
+}
+
+func (counts tileCounts) total() (total int) {
+	for _, count := range counts {
+		total += int(count)
+	}
+	return total
+}
+
+// The tiles that have been called or declared as a kan, as melds
+func (hand Hand) calledMelds() []Meld {
+	melds := make([]Meld, 0, 4)
+	for _, tile := range hand.Chiis {
+		melds = append(melds, Meld{Type: SEQUENCE, Tile: tile.ClearRedOrDora(), Open: true})
+	}
+	for _, tile := range hand.Pons {
+		melds = append(melds, Meld{Type: TRIPLET, Tile: tile.ClearRedOrDora(), Open: true})
+	}
+	for _, tile := range hand.Kans {
+		melds = append(melds, Meld{Type: QUAD, Tile: tile.ClearRedOrDora(), Open: true})
+	}
+	for _, tile := range hand.Ankans {
+		melds = append(melds, Meld{Type: QUAD, Tile: tile.ClearRedOrDora(), Open: false})
+	}
+	return melds
+}
+
+// Splits the counted tiles into melds, appending every complete
+// reading to results. Tiles are always consumed from the lowest
+// remaining tile so that each reading is only found once.
+func splitMelds(counts *tileCounts, melds []Meld, results *[][]Meld) {
+	tile := Tile(0)
+	for tile <= Green && counts[tile] == 0 {
+		tile++
+	}
+	if tile > Green {
+		*results = append(*results, slices.Clone(melds))
+		return
+	}
+
+	if counts[tile] >= 3 {
+		counts[tile] -= 3
+		splitMelds(counts, append(melds, Meld{Type: TRIPLET, Tile: tile}), results)
+		counts[tile] += 3
+	}
+
+	if tile.IsSuited() && tile.GetTileNumber() <= 6 &&
+		counts[tile+1] > 0 && counts[tile+2] > 0 {
+		counts[tile]--
+		counts[tile+1]--
+		counts[tile+2]--
+		splitMelds(counts, append(melds, Meld{Type: SEQUENCE, Tile: tile}), results)
+		counts[tile]++
+		counts[tile+1]++
+		counts[tile+2]++
+	}
+}
+
+// Four melds and a pair, where some of the melds may already be called
+func decomposeStandard(counts tileCounts, called []Meld) []Decomposition {
+	results := make([]Decomposition, 0)
+	for pair := Tile(0); pair <= Green; pair++ {
+		if counts[pair] < 2 {
+			continue
+		}
+
+		counts[pair] -= 2
+		readings := make([][]Meld, 0)
+		splitMelds(&counts, make([]Meld, 0, 4), &readings)
+		counts[pair] += 2
+
+		for _, melds := range readings {
+			results = append(results, Decomposition{
+				Shape: STANDARD_SHAPE,
+				Melds: append(slices.Clone(called), melds...),
+				Pairs: []Tile{pair},
+			})
+		}
+	}
+	return results
+}
+
+func decomposeChiitoitsu(counts tileCounts) (Decomposition, bool) {
+	pairs := make([]Tile, 0, 7)
+	for tile, count := range counts {
+		switch count {
+		case 0:
+		case 2:
+			pairs = append(pairs, Tile(tile))
+		default:
+			return Decomposition{}, false
+		}
+	}
+	if len(pairs) != 7 {
+		return Decomposition{}, false
+	}
+
+	return Decomposition{
+		Shape:       CHIITOITSU_SHAPE,
+		Pairs:       pairs,
+		WinningMeld: -1,
+	}, true
+}
+
+func decomposeKokushi(counts tileCounts) (Decomposition, bool) {
+	var pair Tile = Invalid
+	for tile, count := range counts {
+		if count == 0 {
+			continue
+		}
+		if !Tile(tile).IsTerminalOrHonour() || count > 2 {
+			return Decomposition{}, false
+		}
+		if count == 2 {
+			if pair != Invalid {
+				return Decomposition{}, false
+			}
+			pair = Tile(tile)
+		}
+	}
+
+	for _, tile := range GetTileKinds() {
+		if tile.IsTerminalOrHonour() && counts[tile] == 0 {
+			return Decomposition{}, false
+		}
+	}
+
+	return Decomposition{
+		Shape:       KOKUSHI_SHAPE,
+		Pairs:       []Tile{pair},
+		WinningMeld: -1,
+	}, true
+}
+
+// Creates a copy of the decomposition for every closed group the
+// winning tile could have completed
+func placeWinningTile(decomposition Decomposition, winTile Tile, numCalled int) []Decomposition {
+	placed := make([]Decomposition, 0)
+	if decomposition.Pairs[0] == winTile {
+		decomposition.WinningMeld = -1
+		placed = append(placed, decomposition)
+	}
+
+	seen := make([]Meld, 0, 4)
+	for idx := numCalled; idx < len(decomposition.Melds); idx++ {
+		meld := decomposition.Melds[idx]
+		if !meld.Contains(winTile) || slices.Contains(seen, meld) {
+			continue
+		}
+		seen = append(seen, meld)
+
+		decomposition.WinningMeld = idx
+		placed = append(placed, decomposition)
+	}
+	return placed
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Whether the tile is part of the meld
+func (meld Meld) Contains(tile Tile) bool {
+	tile = tile.ClearRedOrDora()
+	if meld.Type != SEQUENCE {
+		return tile == meld.Tile
+	}
+	return tile.SameSuit(meld.Tile) && tile >= meld.Tile && tile <= meld.Tile+2
+}
+
+// The tiles that make up the meld
+func (meld Meld) Tiles() []Tile {
+	switch meld.Type {
+	case SEQUENCE:
+		return []Tile{meld.Tile, meld.Tile + 1, meld.Tile + 2}
+	case TRIPLET:
+		return []Tile{meld.Tile, meld.Tile, meld.Tile}
+	default:
+		return []Tile{meld.Tile, meld.Tile, meld.Tile, meld.Tile}
+	}
+}
+
+// Returns every reading of the hand as a complete hand once winTile
+// is added to the closed hand. The closed hand should not already
+// contain the winning tile. Returns an empty list if the hand is not
+// complete.
+func DecomposeHand(hand Hand, winTile Tile) []Decomposition {
+	winTile = winTile.ClearRedOrDora()
+	if winTile > Green {
+		return nil
+	}
+
+	called := hand.calledMelds()
+	counts := countTiles(hand.ClosedHand)
+	counts[winTile] += 1
+	if counts.total()+3*len(called) != 14 {
+		return nil
+	}
+
+	results := make([]Decomposition, 0)
+	for _, decomposition := range decomposeStandard(counts, called) {
+		results = append(results, placeWinningTile(decomposition, winTile, len(called))...)
+	}
+
+	if len(called) == 0 {
+		if decomposition, ok := decomposeChiitoitsu(counts); ok {
+			results = append(results, decomposition)
+		}
+		if decomposition, ok := decomposeKokushi(counts); ok {
+			results = append(results, decomposition)
+		}
+	}
+
+	return results
+}
+
+// Finds the tiles that complete the hand. The hand should be one
+// tile short of complete. Returns an empty list if the hand is not in
+// tenpai. Tiles that the hand already holds all four copies of are not
+// counted as waits.
+func WaitingTiles(hand Hand) []Tile {
+	owned := countTiles(hand.ClosedHand)
+	for _, meld := range hand.calledMelds() {
+		for _, tile := range meld.Tiles() {
+			owned[tile] += 1
+		}
+	}
+
+	waits := make([]Tile, 0)
+	for _, tile := range GetTileKinds() {
+		if owned[tile] >= 4 {
+			continue
+		}
+		if len(DecomposeHand(hand, tile)) != 0 {
+			waits = append(waits, tile)
+		}
+	}
+	return waits
+}
@@ -1,20 +1,224 @@
 package core
 
+import (
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+	"errors"
+	"slices"
+)
+
+type PointsTransfer struct {
+	From   uint8  `json:"from"`
+	To     uint8  `json:"to"`
+	Amount uint32 `json:"amount"`
+}
+
+type ResultType uint8
+
+const (
+	WIN_RESULT ResultType = iota
+	// The live wall ran out without anyone winning
+	EXHAUSTIVE_DRAW_RESULT
+
+	// Abortive draws, which end the hand without any payments
+	KYUUSHU_KYUUHAI_RESULT // Nine different terminals and honours on the first draw
+	SUUFON_RENDA_RESULT    // The same wind discarded by everyone on the first go-around
+	SUUCHA_RIICHI_RESULT   // Every player declared riichi
+	SUUKAIKAN_RESULT       // Four kans declared by more than one player
+	SANCHAHOU_RESULT       // Three players ronned the same discard
+)
+
 type GameResult struct {
-	Result          WinResult
-	WonBy           uint8
-	PointsTransfers []struct {
-		From   uint8
-		Amount uint32
-	}
+	Type            ResultType       `json:"type"`
+	Result          WinResult        `json:"result"`
+	WonBy           uint8            `json:"won_by"`
+	Score           Score            `json:"score"`
+	PointsTransfers []PointsTransfer `json:"points_transfers"`
+	// Riichi sticks on the table that go to the winner, worth 1000 each
+	RiichiSticks uint8 `json:"riichi_sticks"`
+	// The other players who ronned the same discard, when more than one
+	// ron is allowed. Honba and riichi sticks only go to WonBy, who is
+	// the closest to the discarder in turn order.
+	OtherWins []GameResult `json:"other_wins"`
+
+	// Which players were tenpai at an exhaustive draw, indexed by player
+	Tenpai []bool `json:"tenpai"`
+	// The hands of the tenpai players, or the players who caused an
+	// abortive draw, which are shown to everyone
+	RevealedHands []Hand `json:"revealed_hands"`
+	// Players who were paid for nagashi mangan at an exhaustive draw
+	NagashiMangan []uint8 `json:"nagashi_mangan"`
+}
+
+// The state of the table that a win is settled against
+type SettlementInfo struct {
+	Dealer       uint8 // Player index of the dealer
+	Discarder    uint8 // Player index of who dealt in, only used on a ron
+	NumPlayers   uint8
+	Honba        uint8
+	RiichiSticks uint8
 }
 
-// TODO: Compute points from result
+// Computes the points the winner receives for the hand, not counting
+// honba or riichi sticks
 func ComputeMahjongScore(result WinResult) uint32 {
-	return 0
+	score := ComputeScore(result)
+	switch {
+	case result.WonByRon:
+		return score.RonPayment
+	case result.Context.SeatWind == East:
+		return 3 * score.NonDealerPayment
+	default:
+		return score.DealerPayment + 2*score.NonDealerPayment
+	}
+}
+
+// Works out who pays the winner, including 300 points per honba
+func GenerateGameResult(result WinResult, wonBy uint8, info SettlementInfo) GameResult {
+	score := ComputeScore(result)
+	gameResult := GameResult{
+		Type:            WIN_RESULT,
+		Result:          result,
+		WonBy:           wonBy,
+		Score:           score,
+		PointsTransfers: make([]PointsTransfer, 0, 3),
+		RiichiSticks:    info.RiichiSticks,
+	}
+
+	if result.WonByRon {
+		gameResult.PointsTransfers = append(gameResult.PointsTransfers, PointsTransfer{
+			From:   info.Discarder,
+			To:     wonBy,
+			Amount: score.RonPayment + 300*uint32(info.Honba),
+		})
+		return gameResult
+	}
+
+	for payer := range info.NumPlayers {
+		if payer == wonBy {
+			continue
+		}
+
+		amount := score.NonDealerPayment
+		if payer == info.Dealer {
+			amount = score.DealerPayment
+		}
+		gameResult.PointsTransfers = append(gameResult.PointsTransfers, PointsTransfer{
+			From:   payer,
+			To:     wonBy,
+			Amount: amount + 100*uint32(info.Honba),
+		})
+	}
+	return gameResult
+}
+
+// Works out the payments at an exhaustive draw. Nagashi mangan is paid
+// like a mangan tsumo, and replaces the 3000 points that noten players
+// pay to the tenpai players.
+func GenerateDrawResult(tenpai []bool, nagashi []bool, info SettlementInfo) GameResult {
+	gameResult := GameResult{
+		Type:            EXHAUSTIVE_DRAW_RESULT,
+		PointsTransfers: make([]PointsTransfer, 0),
+		Tenpai:          tenpai,
+		NagashiMangan:   make([]uint8, 0),
+	}
+
+	for player := range info.NumPlayers {
+		if !nagashi[player] {
+			continue
+		}
+		gameResult.NagashiMangan = append(gameResult.NagashiMangan, player)
+
+		for payer := range info.NumPlayers {
+			if payer == player {
+				continue
+			}
+			amount := uint32(2000)
+			if payer == info.Dealer || player == info.Dealer {
+				amount = 4000
+			}
+			gameResult.PointsTransfers = append(gameResult.PointsTransfers, PointsTransfer{
+				From:   payer,
+				To:     player,
+				Amount: amount,
+			})
+		}
+	}
+	if len(gameResult.NagashiMangan) != 0 {
+		return gameResult
+	}
+
+	numTenpai := uint32(Count(tenpai, true))
+	if numTenpai == 0 || numTenpai == uint32(info.NumPlayers) {
+		return gameResult
+	}
+
+	amount := 3000 / (numTenpai * (uint32(info.NumPlayers) - numTenpai))
+	for payer := range info.NumPlayers {
+		if tenpai[payer] {
+			continue
+		}
+		for receiver := range info.NumPlayers {
+			if !tenpai[receiver] {
+				continue
+			}
+			gameResult.PointsTransfers = append(gameResult.PointsTransfers, PointsTransfer{
+				From:   payer,
+				To:     receiver,
+				Amount: amount,
+			})
+		}
+	}
+	return gameResult
+}
+
+// Whether the hand ended in an abortive draw
+func (result GameResult) IsAbortive() bool {
+	return result.Type >= KYUUSHU_KYUUHAI_RESULT
 }
 
-// TODO
-func GenerateGameResult(result WinResult, wonBy uint8) GameResult {
-	return GameResult{}
+// Every player who won the hand
+func (result GameResult) Winners() []uint8 {
+	if result.Type != WIN_RESULT {
+		return nil
+	}
+
+	winners := []uint8{result.WonBy}
+	for _, other := range result.OtherWins {
+		winners = append(winners, other.WonBy)
+	}
+	return winners
+}
+
+// Settles the result on the players' points. Either every transfer
+// is applied or none of them are.
+func (result GameResult) Apply(players []Player) error {
+	points := make([]int32, len(players))
+	for idx, player := range players {
+		points[idx] = player.Points
+	}
+
+	transfers := slices.Clone(result.PointsTransfers)
+	for _, other := range result.OtherWins {
+		transfers = append(transfers, other.PointsTransfers...)
+	}
+
+	for _, transfer := range transfers {
+		if int(transfer.From) >= len(players) || int(transfer.To) >= len(players) {
+			return errors.New("Transfer between unknown players")
+		}
+		points[transfer.From] -= int32(transfer.Amount)
+		points[transfer.To] += int32(transfer.Amount)
+	}
+
+	if result.Type == WIN_RESULT && result.RiichiSticks != 0 {
+		if int(result.WonBy) >= len(players) {
+			return errors.New("Unknown winner")
+		}
+		points[result.WonBy] += 1000 * int32(result.RiichiSticks)
+	}
+
+	for idx := range players {
+		players[idx].Points = points[idx]
+	}
+	return nil
 }
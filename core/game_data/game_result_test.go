@@ -0,0 +1,128 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGenerateGameResult(t *testing.T) {
+	win := func(seat Wind, ron bool) WinResult {
+		return WinResult{
+			YakuResult: YakuResult{Yakus: RIICHI_YAKU, Han: 3, Fu: 30},
+			WonByRon:   ron,
+			Context:    WinContext{SeatWind: seat, RoundWind: East, Tsumo: !ron},
+		}
+	}
+	info := SettlementInfo{Dealer: 0, Discarder: 3, NumPlayers: 4, Honba: 2, RiichiSticks: 1}
+
+	tests := []struct {
+		name   string // description of this test case
+		result WinResult
+		wonBy  uint8
+		want   []int32
+	}{
+		{"non-dealer ron", win(South, true), 1, []int32{25000, 25000 + 3900 + 600 + 1000, 25000, 25000 - 3900 - 600}},
+		{"dealer ron", win(East, true), 0, []int32{25000 + 5800 + 600 + 1000, 25000, 25000, 25000 - 5800 - 600}},
+		{"non-dealer tsumo", win(West, false), 2, []int32{25000 - 2000 - 200, 25000 - 1000 - 200, 25000 + 4000 + 600 + 1000, 25000 - 1000 - 200}},
+		{"dealer tsumo", win(East, false), 0, []int32{25000 + 6000 + 600 + 1000, 25000 - 2000 - 200, 25000 - 2000 - 200, 25000 - 2000 - 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := make([]Player, 4)
+			for idx := range players {
+				players[idx].Points = 25000
+			}
+
+			result := GenerateGameResult(tt.result, tt.wonBy, info)
+			if err := result.Apply(players); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			got := make([]int32, 0, 4)
+			for _, player := range players {
+				got = append(got, player.Points)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("points after Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	players := []Player{{Points: 25000}, {Points: 25000}}
+	result := GameResult{
+		WonBy: 0,
+		PointsTransfers: []PointsTransfer{
+			{From: 1, To: 0, Amount: 1000},
+			{From: 5, To: 0, Amount: 1000},
+		},
+	}
+
+	if err := result.Apply(players); err == nil {
+		t.Fatalf("Apply() with an unknown player succeeded")
+	}
+	if players[0].Points != 25000 || players[1].Points != 25000 {
+		t.Errorf("Apply() changed points on failure: %v, %v", players[0].Points, players[1].Points)
+	}
+}
+
+func TestGenerateDrawResult(t *testing.T) {
+	info := SettlementInfo{Dealer: 0, NumPlayers: 4, Honba: 1}
+
+	tests := []struct {
+		name    string // description of this test case
+		tenpai  []bool
+		nagashi []bool
+		want    []int32
+	}{
+		{"all noten", []bool{false, false, false, false}, make([]bool, 4), []int32{25000, 25000, 25000, 25000}},
+		{"all tenpai", []bool{true, true, true, true}, make([]bool, 4), []int32{25000, 25000, 25000, 25000}},
+		{"one tenpai", []bool{false, true, false, false}, make([]bool, 4), []int32{24000, 28000, 24000, 24000}},
+		{"two tenpai", []bool{true, false, true, false}, make([]bool, 4), []int32{26500, 23500, 26500, 23500}},
+		{"three tenpai", []bool{true, true, false, true}, make([]bool, 4), []int32{26000, 26000, 22000, 26000}},
+		{"non-dealer nagashi", []bool{true, false, false, false}, []bool{false, false, true, false}, []int32{21000, 23000, 33000, 23000}},
+		{"dealer nagashi", []bool{false, false, false, false}, []bool{true, false, false, false}, []int32{37000, 21000, 21000, 21000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := make([]Player, 4)
+			for idx := range players {
+				players[idx].Points = 25000
+			}
+
+			result := GenerateDrawResult(tt.tenpai, tt.nagashi, info)
+			if result.Type != EXHAUSTIVE_DRAW_RESULT {
+				t.Errorf("Type = %v, want EXHAUSTIVE_DRAW_RESULT", result.Type)
+			}
+			if err := result.Apply(players); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			got := make([]int32, 0, 4)
+			for _, player := range players {
+				got = append(got, player.Points)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("points after Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasNagashiMangan(t *testing.T) {
+	player := Player{Discards: parseTiles("19m1p9s1234567z")}
+	if !player.HasNagashiMangan() {
+		t.Errorf("Expected nagashi mangan with only terminal and honour discards")
+	}
+
+	player.DiscardCalled = true
+	if player.HasNagashiMangan() {
+		t.Errorf("Expected no nagashi mangan once a discard was called")
+	}
+
+	player = Player{Discards: parseTiles("19m2p")}
+	if player.HasNagashiMangan() {
+		t.Errorf("Expected no nagashi mangan with a simple discarded")
+	}
+}
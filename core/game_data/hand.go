@@ -1,8 +1,11 @@
 package core
 
+// Called melds are kept as one tile each, which has the red bit set when
+// the meld holds a red five
 type Hand struct {
 	ClosedHand   []Tile
-	Kans         []Tile
+	Kans         []Tile // Open kans, either called (daiminkan) or added to a pon (shouminkan)
+	Ankans       []Tile // Closed kans
 	Pons         []Tile
 	Chiis        []Tile // Chiis are the start of the sequence
 	HandOpen     bool
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"slices"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+)
+
+// Parses tiles written as e.g. "123m456p789s11z", where 1z-4z are the
+// winds and 5z, 6z, 7z are the white, green and red dragons. A 0 is a
+// red five.
+func parseTiles(notation string) []Tile {
+	honours := []Tile{EastTile, SouthTile, WestTile, NorthTile, White, Green, Red}
+	tiles := make([]Tile, 0)
+	numbers := make([]byte, 0)
+	for _, char := range []byte(notation) {
+		if char >= '0' && char <= '9' {
+			numbers = append(numbers, char-'0')
+			continue
+		}
+
+		for _, number := range numbers {
+			var tile Tile
+			switch char {
+			case 'm':
+				tile = Manzu
+			case 'p':
+				tile = Pinzu
+			case 's':
+				tile = Souzu
+			case 'z':
+				tiles = append(tiles, honours[number-1])
+				continue
+			}
+
+			if number == 0 {
+				tiles = append(tiles, tile.SetTileNumber(4).SetRedTile())
+			} else {
+				tiles = append(tiles, tile.SetTileNumber(number-1))
+			}
+		}
+		numbers = numbers[:0]
+	}
+	return tiles
+}
+
+func parseTile(notation string) Tile {
+	return parseTiles(notation)[0]
+}
+
+func TestWaitingTiles(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		hand Hand
+		want []Tile
+	}{
+		{
+			name: "ryanmen",
+			hand: Hand{ClosedHand: parseTiles("123456789m23p55s")},
+			want: parseTiles("14p"),
+		},
+		{
+			name: "nobetan",
+			hand: Hand{ClosedHand: parseTiles("123m456p789s1234s")},
+			want: parseTiles("14s"),
+		},
+		{
+			name: "chuuren nine waits",
+			hand: Hand{ClosedHand: parseTiles("1112345678999m")},
+			want: parseTiles("123456789m"),
+		},
+		{
+			name: "chiitoitsu tanki",
+			hand: Hand{ClosedHand: parseTiles("1122m3344p5566s7z")},
+			want: parseTiles("7z"),
+		},
+		{
+			name: "chiitoitsu or ryanpeikou",
+			hand: Hand{ClosedHand: parseTiles("112233m445566p7s")},
+			want: parseTiles("7s"),
+		},
+		{
+			name: "kokushi single wait",
+			hand: Hand{ClosedHand: parseTiles("19m19p19s1234566z")},
+			want: parseTiles("7z"),
+		},
+		{
+			name: "kokushi thirteen waits",
+			hand: Hand{ClosedHand: parseTiles("19m19p19s1234567z")},
+			want: parseTiles("19m19p19s1234567z"),
+		},
+		{
+			name: "with called melds",
+			hand: Hand{
+				ClosedHand: parseTiles("55s67p"),
+				Pons:       parseTiles("1z"),
+				Chiis:      parseTiles("1m"),
+				Kans:       parseTiles("9p"),
+				HandOpen:   true,
+			},
+			want: parseTiles("58p"),
+		},
+		{
+			name: "red five counts as five",
+			hand: Hand{ClosedHand: parseTiles("123456789m0p6p11s")},
+			want: parseTiles("47p"),
+		},
+		{
+			name: "does not wait on a fifth tile",
+			hand: Hand{ClosedHand: parseTiles("1111m234p567s789s")},
+			want: []Tile{},
+		},
+		{
+			name: "noten",
+			hand: Hand{ClosedHand: parseTiles("147m258p369s1234z")},
+			want: []Tile{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WaitingTiles(tt.hand)
+			slices.Sort(tt.want)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("WaitingTiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecomposeHand(t *testing.T) {
+	tests := []struct {
+		name    string // description of this test case
+		hand    Hand
+		winTile Tile
+		shapes  []HandShape
+	}{
+		{
+			name:    "sanrenkou read as triplets or sequences",
+			hand:    Hand{ClosedHand: parseTiles("111222333m456p5s")},
+			winTile: parseTile("5s"),
+			shapes:  []HandShape{STANDARD_SHAPE, STANDARD_SHAPE},
+		},
+		{
+			name:    "ryanpeikou is also chiitoitsu",
+			hand:    Hand{ClosedHand: parseTiles("112233m445566p7s")},
+			winTile: parseTile("7s"),
+			shapes:  []HandShape{STANDARD_SHAPE, CHIITOITSU_SHAPE},
+		},
+		{
+			name:    "tanki on an honour",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s1113z")},
+			winTile: parseTile("3z"),
+			shapes:  []HandShape{STANDARD_SHAPE},
+		},
+		{
+			name:    "kokushi",
+			hand:    Hand{ClosedHand: parseTiles("19m19p19s1234567z")},
+			winTile: parseTile("1m"),
+			shapes:  []HandShape{KOKUSHI_SHAPE},
+		},
+		{
+			name:    "incomplete",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s1234z")},
+			winTile: parseTile("5z"),
+			shapes:  []HandShape{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decompositions := DecomposeHand(tt.hand, tt.winTile)
+			got := make([]HandShape, 0)
+			for _, decomposition := range decompositions {
+				got = append(got, decomposition.Shape)
+			}
+			if !slices.Equal(got, tt.shapes) {
+				t.Errorf("DecomposeHand() shapes = %v, want %v", got, tt.shapes)
+			}
+		})
+	}
+}
+
+func TestTestRonFuriten(t *testing.T) {
+	player := Player{
+		Hand:     Hand{ClosedHand: parseTiles("123456789m23p55s")},
+		Discards: parseTiles("4p"),
+	}
+	if err := player.TestRon(parseTile("1p"), WinContext{}); err == nil {
+		t.Errorf("TestRon() on a furiten hand succeeded")
+	}
+}
+
+func TestTestRiichiAnkan(t *testing.T) {
+	tests := []struct {
+		name  string // description of this test case
+		hand  string // The closed hand with the drawn tile
+		drawn string
+		want  ErrorCode
+	}{
+		{"kan keeps the waits", "1111m456p789s23s55z", "1m", NO_ERROR},
+		{"kan takes away a wait", "1111m23m456p789s55z", "1m", ACTION_NOT_ALLOWED},
+		{"no quad", "111m456p789s123s55z", "1m", NOT_ENOUGH_TILES},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := Player{Hand: Hand{ClosedHand: parseTiles(tt.hand), HandInRiichi: true}}
+			if got := player.TestRiichiAnkan(parseTile(tt.drawn)); CodeOf(got) != tt.want {
+				t.Errorf("TestRiichiAnkan() = %v, want code %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,65 +1,164 @@
 package core
 
 import (
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
-	"errors"
 	"slices"
 )
 
-// TODO: Separate Kans into the different types
-
 // ==================== TYPES ====================
 
 type Player struct {
 	Hand
 	Discards []Tile // For furiten
 
-	Points   uint32
+	Points   int32
 	SeatWind Wind
+
+	Ippatsu      bool // Set on riichi, cleared on the next discard or any call
+	DoubleRiichi bool
+	// Whether another player called one of the discards, which rules
+	// out nagashi mangan
+	DiscardCalled bool
 }
 
 // ==================== PRIVATE FUNCTIONS ====================
 
+// Counts the copies of the tile in the closed hand, red fives included
 func (player Player) countNumInClosedHand(tile Tile) int {
 	count := 0
 	for _, handTile := range player.ClosedHand {
-		if tile == handTile {
+		if tile.ClearRedOrDora() == handTile.ClearRedOrDora() {
 			count++
 		}
 	}
 	return count
 }
 
+// Removes count copies of the tile from the closed hand, red fives
+// included, for a call. Returns the tiles removed.
+func (player *Player) removeForCall(tile Tile, count int) []Tile {
+	kind := tile.ClearRedOrDora()
+	removed := make([]Tile, 0, count)
+	for range count {
+		idx := slices.IndexFunc(player.ClosedHand, func(handTile Tile) bool {
+			return handTile.ClearRedOrDora() == kind
+		})
+		if idx < 0 {
+			panic("Tile to call with not in hand")
+		}
+		removed = append(removed, player.ClosedHand[idx])
+		player.ClosedHand = slices.Delete(player.ClosedHand, idx, idx+1)
+	}
+	return removed
+}
+
+// The tile a meld is kept as, which is red if any of its tiles is
+func meldTile(kind Tile, tiles ...Tile) Tile {
+	kind = kind.ClearRedOrDora()
+	for _, tile := range tiles {
+		if tile&RedTile != 0 {
+			return kind.SetRedTile()
+		}
+	}
+	return kind
+}
+
+// The tiles a call on the tile takes from the closed hand, red fives
+// included
+func (player Player) CallTiles(tile Tile, count int) []Tile {
+	tiles := make([]Tile, 0, count)
+	for _, handTile := range player.ClosedHand {
+		if len(tiles) < count && handTile.SameKind(tile) {
+			tiles = append(tiles, handTile)
+		}
+	}
+	return tiles
+}
+
 func (player Player) idxOfTile(tile Tile) (int, error) {
 	for idx, handTile := range player.ClosedHand {
 		if tile == handTile {
 			return idx, nil
 		}
 	}
-	return 0, errors.New("Not found")
+	return 0, CodedError{Code: TILE_NOT_IN_HAND}
+}
+
+// Returns a copy of the hand with a single copy of the tile removed
+// from the closed hand
+func (player Player) handWithout(tile Tile) (Hand, error) {
+	idx, err := player.idxOfTile(tile)
+	if err != nil {
+		return Hand{}, err
+	}
+
+	hand := player.Hand
+	hand.ClosedHand = slices.Delete(slices.Clone(player.ClosedHand), idx, idx+1)
+	return hand, nil
 }
 
 // Finds the tiles that results in a winning hand. Returns an empty list if the hand is not in Tenpai.
 func (player Player) checkWaitingTiles() []Tile {
 	// TODO: Memoize it later?
-	return nil
+	return WaitingTiles(player.Hand)
 }
 
 // ==================== PUBLIC FUNCTIONS ====================
 
+// Whether the hand is one tile away from being complete
+func (player Player) IsTenpai() bool {
+	return len(player.checkWaitingTiles()) != 0
+}
+
+// Whether the closed hand holds at least nine different terminals and
+// honours
+func (player Player) TestKyuushuKyuuhai() error {
+	kinds := make([]Tile, 0, 13)
+	for _, tile := range player.ClosedHand {
+		tile = tile.ClearRedOrDora()
+		if tile.IsTerminalOrHonour() && !slices.Contains(kinds, tile) {
+			kinds = append(kinds, tile)
+		}
+	}
+	if len(kinds) < 9 {
+		return CodedError{Code: TOO_FEW_TERMINALS}
+	}
+	return nil
+}
+
+// Whether every discard was a terminal or honour, and none of them
+// were called
+func (player Player) HasNagashiMangan() bool {
+	if player.DiscardCalled || len(player.Discards) == 0 {
+		return false
+	}
+	for _, discard := range player.Discards {
+		if !discard.IsTerminalOrHonour() {
+			return false
+		}
+	}
+	return true
+}
+
 func (player *Player) FreshHand(tiles []Tile) {
 	if len(tiles) != 13 {
 		panic("Not equal to 13")
 	}
 
-	player.ClosedHand = make([]Tile, 14)
+	player.ClosedHand = make([]Tile, 13, 14)
 	copy(player.ClosedHand, tiles)
 	player.Kans = nil
+	player.Ankans = nil
 	player.Pons = nil
 	player.Chiis = nil
 	player.Discards = nil
 
 	player.HandOpen = false
+	player.HandInRiichi = false
+	player.Ippatsu = false
+	player.DoubleRiichi = false
+	player.DiscardCalled = false
 }
 
 // This function essentially keeps track of the player turn. If it's
@@ -67,6 +166,7 @@ func (player *Player) FreshHand(tiles []Tile) {
 func (player Player) ExtraTileInHand() bool {
 	numOpenTriplets := 0
 	numOpenTriplets += len(player.Kans)
+	numOpenTriplets += len(player.Ankans)
 	numOpenTriplets += len(player.Chiis)
 	numOpenTriplets += len(player.Pons)
 	return (len(player.ClosedHand) + numOpenTriplets*3) == 14
@@ -79,7 +179,7 @@ func (player Player) IsPlayerTurn() bool {
 
 func (player *Player) Draw(drawn Tile) error {
 	if player.ExtraTileInHand() {
-		return TooManyTilesErr{}
+		return CodedError{Code: TOO_MANY_TILES}
 	}
 
 	player.ClosedHand = append(player.ClosedHand, drawn)
@@ -88,7 +188,7 @@ func (player *Player) Draw(drawn Tile) error {
 
 func (player *Player) Toss(discarded Tile) error {
 	if !player.ExtraTileInHand() {
-		return TooLittleTilesErr{}
+		return CodedError{Code: TOO_FEW_TILES}
 	}
 
 	for i := range player.ClosedHand {
@@ -96,34 +196,34 @@ func (player *Player) Toss(discarded Tile) error {
 			player.ClosedHand[i] = Last(player.ClosedHand)
 			Pop(&player.ClosedHand)
 			player.Discards = append(player.Discards, discarded)
+			player.Ippatsu = false
 			return nil
 		}
 	}
-	return errors.New("Tile not found")
+	return CodedError{Code: TILE_NOT_IN_HAND}
 }
 
 func (player Player) TestChii(tossedTile Tile, tilesInHand [2]Tile) error {
 	if player.ExtraTileInHand() {
-		return TooManyTilesErr{}
+		return CodedError{Code: TOO_MANY_TILES}
 	}
 
-	tiles := [3]Tile{tossedTile, tilesInHand[0], tilesInHand[1]}
+	tiles := [3]Tile{
+		tossedTile.ClearRedOrDora(),
+		tilesInHand[0].ClearRedOrDora(),
+		tilesInHand[1].ClearRedOrDora(),
+	}
 	slices.Sort(tiles[:])
-	if tiles[1]-tiles[0] != 1 || tiles[2]-tiles[1] != 1 {
-		return errors.New("Tiles are not in a sequence")
+	if !tiles[0].IsSuited() || !tiles[0].SameSuit(tiles[2]) ||
+		tiles[1]-tiles[0] != 1 || tiles[2]-tiles[1] != 1 {
+		return CodedError{Code: NOT_A_SEQUENCE}
 	}
 
-	exist := 0
-	for _, seq := range tilesInHand {
-		for _, tile := range player.ClosedHand {
-			if tile == seq {
-				exist += 1
-			}
+	for _, tile := range tilesInHand {
+		if _, err := player.idxOfTile(tile); err != nil {
+			return CodedError{Code: TILE_NOT_IN_HAND, Reason: "Non suitable tiles"}
 		}
 	}
-	if exist != 3 {
-		return errors.New("Non suitable tiles")
-	}
 
 	return nil
 }
@@ -135,32 +235,17 @@ func (player *Player) Chii(onTile Tile, chiiSequence [2]Tile) error {
 		return err
 	}
 
-	indices := make([]int, 0, 2)
-	for _, seq := range chiiSequence {
-		for i, tile := range player.ClosedHand {
-			if tile == seq {
-				indices = append(indices, i)
-			}
-		}
-	}
-	if len(indices) != 2 {
-		return errors.New("Does not have all the tiles")
-	}
-
-	// Pop the larger index first
-	if indices[1] > indices[0] {
-		Swap(indices, 0, 1)
-	}
-	for _, index := range indices {
-		last := Pop(&player.ClosedHand)
-		if index >= len(player.ClosedHand) {
-			continue
-		} else {
-			player.ClosedHand[index] = last
-		}
+	for _, tile := range chiiSequence {
+		idx, _ := player.idxOfTile(tile)
+		player.ClosedHand = slices.Delete(player.ClosedHand, idx, idx+1)
 	}
 
-	player.Chiis = append(player.Chiis, min(onTile, chiiSequence[0], chiiSequence[1]))
+	start := min(
+		onTile.ClearRedOrDora(),
+		chiiSequence[0].ClearRedOrDora(),
+		chiiSequence[1].ClearRedOrDora(),
+	)
+	player.Chiis = append(player.Chiis, meldTile(start, onTile, chiiSequence[0], chiiSequence[1]))
 	player.HandOpen = true
 
 	return nil
@@ -168,96 +253,111 @@ func (player *Player) Chii(onTile Tile, chiiSequence [2]Tile) error {
 
 func (player Player) TestAnkan(onTile Tile) error {
 	if !player.ExtraTileInHand() {
-		return TooLittleTilesErr{}
+		return CodedError{Code: TOO_FEW_TILES}
 	}
 
 	if player.countNumInClosedHand(onTile) == 4 {
 		return nil
 	}
-	return errors.New("Not enough tiles to kan")
+	return CodedError{Code: NOT_ENOUGH_TILES, Reason: "Not enough tiles to kan"}
 }
 
-func (player *Player) Ankan(onTile Tile) error {
-	if err := player.TestAnkan(onTile); err != nil {
+// Whether a hand in riichi can make an ankan with the tile it drew.
+// The kan is only allowed when the hand waits on the same tiles after
+// it as before.
+func (player Player) TestRiichiAnkan(drawn Tile) error {
+	if err := player.TestAnkan(drawn); err != nil {
+		return err
+	}
+	before, err := player.handWithout(drawn)
+	if err != nil {
 		return err
 	}
-	for range 4 {
-		tileIdx, err := player.idxOfTile(onTile)
-		if err != nil {
-			panic(err)
-		}
 
-		Swap(player.ClosedHand, uint(tileIdx), uint(len(player.ClosedHand)-1))
-		Pop(&player.ClosedHand)
+	after := player
+	after.ClosedHand = slices.Clone(player.ClosedHand)
+	after.Ankans = slices.Clone(player.Ankans)
+	if err := after.Ankan(drawn); err != nil {
+		return err
 	}
 
-	player.Kans = append(player.Kans, onTile)
+	waits := WaitingTiles(before)
+	if len(waits) == 0 || !slices.Equal(waits, WaitingTiles(after.Hand)) {
+		return CodedError{Code: ACTION_NOT_ALLOWED, Reason: "Kan would change the waits"}
+	}
+	return nil
+}
+
+func (player *Player) Ankan(onTile Tile) error {
+	if err := player.TestAnkan(onTile); err != nil {
+		return err
+	}
+	removed := player.removeForCall(onTile, 4)
+
+	player.Ankans = append(player.Ankans, meldTile(onTile, removed...))
 	return nil
 }
 
 func (player Player) TestDaiminkan(onTile Tile) error {
 	if player.ExtraTileInHand() {
-		return TooManyTilesErr{}
+		return CodedError{Code: TOO_MANY_TILES}
 	}
 
 	if player.countNumInClosedHand(onTile) == 3 {
 		return nil
 	}
-	return errors.New("Not enough tiles")
+	return CodedError{Code: NOT_ENOUGH_TILES}
 }
 
 func (player *Player) Daiminkan(onTile Tile) error {
 	if err := player.TestDaiminkan(onTile); err != nil {
 		return err
 	}
-	for range 3 {
-		tileIdx, err := player.idxOfTile(onTile)
-		if err != nil {
-			panic(err)
-		}
+	removed := player.removeForCall(onTile, 3)
 
-		Swap(player.ClosedHand, uint(tileIdx), uint(len(player.ClosedHand)-1))
-		Pop(&player.ClosedHand)
-	}
-
-	player.Kans = append(player.Kans, onTile)
+	player.Kans = append(player.Kans, meldTile(onTile, append(removed, onTile)...))
 	player.HandOpen = true
 	return nil
 }
 
-func (player *Player) TestShouminkan(onTile Tile) error {
-	if player.ExtraTileInHand() {
-		return TooManyTilesErr{}
+// Whether the tile from the closed hand can be added to a pon of the
+// player during their turn
+func (player Player) TestShouminkan(onTile Tile) error {
+	if !player.ExtraTileInHand() {
+		return CodedError{Code: TOO_FEW_TILES}
+	}
+	if _, err := player.idxOfTile(onTile); err != nil {
+		return err
 	}
 
-	if slices.Contains(player.Pons, onTile) {
+	if slices.ContainsFunc(player.Pons, onTile.SameKind) {
 		return nil
 	}
-	return errors.New("Does not have a pon")
+	return CodedError{Code: NO_PON}
 }
 
+// Adds the tile from the closed hand to the pon of its kind
 func (player *Player) Shouminkan(onTile Tile) error {
 	if err := player.TestShouminkan(onTile); err != nil {
 		return err
 	}
-	for idx, tile := range player.Pons {
-		if tile == onTile {
-			Swap(player.Pons, uint(idx), uint(len(player.Pons)-1))
-			Pop(&player.Pons)
-		}
-	}
-	player.Kans = append(player.Kans, onTile)
-	player.HandOpen = true
+	handIdx, _ := player.idxOfTile(onTile)
+	player.ClosedHand = slices.Delete(player.ClosedHand, handIdx, handIdx+1)
+
+	idx := slices.IndexFunc(player.Pons, onTile.SameKind)
+	pon := player.Pons[idx]
+	Remove(&player.Pons, idx)
+	player.Kans = append(player.Kans, meldTile(onTile, pon, onTile))
 	return nil
 }
 
 func (player Player) TestPon(onTile Tile) error {
 	if player.ExtraTileInHand() {
-		return TooManyTilesErr{}
+		return CodedError{Code: TOO_MANY_TILES}
 	}
 
 	if player.countNumInClosedHand(onTile) < 2 {
-		return errors.New("Cannot pon: Not enough tiles")
+		return CodedError{Code: NOT_ENOUGH_TILES, Reason: "Cannot pon: Not enough tiles"}
 	}
 	return nil
 }
@@ -266,82 +366,140 @@ func (player *Player) Pon(onTile Tile) error {
 	if err := player.TestPon(onTile); err != nil {
 		return err
 	}
-	for range 2 {
-		tileIdx, err := player.idxOfTile(onTile)
-		if err != nil {
-			panic(err)
-		}
-
-		Swap(player.ClosedHand, uint(tileIdx), uint(len(player.ClosedHand)-1))
-		Pop(&player.ClosedHand)
-	}
+	removed := player.removeForCall(onTile, 2)
 
-	player.Pons = append(player.Pons, onTile)
+	player.Pons = append(player.Pons, meldTile(onTile, append(removed, onTile)...))
 	player.HandOpen = true
 	return nil
 }
 
-func (player Player) TestRon(onTile Tile) error {
+func (player Player) TestRon(onTile Tile, context WinContext) error {
 	// The player needs to have a hand with the correct tile, and waits cannot be in the discard pile
 	if player.ExtraTileInHand() {
-		return TooManyTilesErr{}
+		return CodedError{Code: TOO_MANY_TILES}
 	}
 
 	waitingTiles := player.checkWaitingTiles()
-	if idx := slices.Index(waitingTiles, onTile); idx == -1 {
-		return errors.New("Tile is not part of waiting tiles")
+	if !slices.Contains(waitingTiles, onTile.ClearRedOrDora()) {
+		return CodedError{Code: NOT_WAITING}
 	}
 
-	for _, waitingTile := range waitingTiles {
-		if slices.Index(player.Discards, waitingTile) != -1 {
-			return errors.New("Hand in furiten, cannot discard")
+	for _, discard := range player.Discards {
+		if slices.Contains(waitingTiles, discard.ClearRedOrDora()) {
+			return CodedError{Code: FURITEN, Reason: "Hand in furiten, cannot discard"}
 		}
 	}
 
-	yakus := GetYaku(player.Hand, onTile)
+	context.Tsumo = false
+	yakus := GetYaku(player.Hand, onTile, context)
 	if yakus == NO_YAKU {
-		return errors.New("No yaku")
+		return CodedError{Code: NO_YAKU_TO_WIN}
 	}
 
 	return nil
 }
 
 // Returns the game result or an error
-func (player *Player) Ron(onTile Tile) (WinResult, error) {
+func (player *Player) Ron(onTile Tile, context WinContext) (WinResult, error) {
 
-	if err := player.TestRon(onTile); err != nil {
+	if err := player.TestRon(onTile, context); err != nil {
 		return WinResult{}, err
 	}
 
-	yakus := GetYaku(player.Hand, onTile)
-	if yakus == NO_YAKU {
+	context.Tsumo = false
+	yakus := EvaluateYaku(player.Hand, onTile, context)
+	if yakus.Yakus == NO_YAKU {
 		panic("Logic error")
 	}
 
 	return WinResult{
-		Yakus:       yakus,
+		YakuResult:  yakus,
+		Dora:        CountDora(player.Hand, onTile, context),
 		WinningHand: player.Hand,
 		WinningTile: onTile,
 		WonByRon:    true,
+		Context:     context,
 	}, nil
 }
 
-func (player Player) TestTsumo() {
+func (player Player) TestTsumo(tsumoTile Tile, context WinContext) error {
+	if !player.ExtraTileInHand() {
+		return CodedError{Code: TOO_FEW_TILES}
+	}
+
+	hand, err := player.handWithout(tsumoTile)
+	if err != nil {
+		return err
+	}
 
+	context.Tsumo = true
+	if GetYaku(hand, tsumoTile, context) == NO_YAKU {
+		return CodedError{Code: NO_YAKU_TO_WIN}
+	}
+	return nil
 }
 
-func (player Player) Tsumo(tsumoTile Tile) (WinResult, error) {
-	return WinResult{}, nil
+func (player Player) Tsumo(tsumoTile Tile, context WinContext) (WinResult, error) {
+	if err := player.TestTsumo(tsumoTile, context); err != nil {
+		return WinResult{}, err
+	}
+
+	hand, err := player.handWithout(tsumoTile)
+	if err != nil {
+		panic(err)
+	}
+
+	context.Tsumo = true
+	return WinResult{
+		YakuResult:  EvaluateYaku(hand, tsumoTile, context),
+		Dora:        CountDora(hand, tsumoTile, context),
+		WinningHand: hand,
+		WinningTile: tsumoTile,
+		WonByRon:    false,
+		Context:     context,
+	}, nil
 }
 
+// Returns the tiles that can be discarded to declare riichi
 func (player Player) GetRiichiDiscards() []Tile {
-	return nil
+	if player.HandOpen || player.HandInRiichi || player.Points < 1000 || !player.ExtraTileInHand() {
+		return nil
+	}
+
+	discards := make([]Tile, 0)
+	for _, tile := range player.ClosedHand {
+		if slices.Contains(discards, tile) {
+			continue
+		}
+
+		hand, err := player.handWithout(tile)
+		if err != nil {
+			panic(err)
+		}
+		if len(WaitingTiles(hand)) != 0 {
+			discards = append(discards, tile)
+		}
+	}
+	return discards
 }
 
 func (player Player) TestRiichi(onTile Tile) error {
+	if !slices.Contains(player.GetRiichiDiscards(), onTile) {
+		return CodedError{Code: NOT_TENPAI}
+	}
 	return nil
 }
 
-func (player Player) Riichi(onTile Tile) error {
+// Discards the tile and declares riichi
+func (player *Player) Riichi(onTile Tile) error {
+	if err := player.TestRiichi(onTile); err != nil {
+		return err
+	}
+	if err := player.Toss(onTile); err != nil {
+		return err
+	}
+
+	player.HandInRiichi = true
+	player.Ippatsu = true
 	return nil
 }
@@ -0,0 +1,38 @@
+package core
+
+// Everything one player can know about a hand in progress
+type PlayerView struct {
+	Player     uint8  `json:"player"`
+	ClosedHand []Tile `json:"closed_hand"`
+	// The tile the player just drew, Invalid if it is not their turn or
+	// their turn started with a call
+	DrawnTile Tile       `json:"drawn_tile"`
+	Seats     []SeatView `json:"seats"` // Indexed by player
+
+	RoundWind      Wind   `json:"round_wind"`
+	Kyoku          uint8  `json:"kyoku"`
+	Honba          uint8  `json:"honba"`
+	RiichiSticks   uint8  `json:"riichi_sticks"`
+	DoraIndicators []Tile `json:"dora_indicators"`
+	WallRemaining  int    `json:"wall_remaining"`
+
+	CurrentPlayer uint8 `json:"current_player"`
+	// The tile that was last discarded, Invalid if the discard can no
+	// longer be called
+	DiscardedTile Tile `json:"discarded_tile"`
+	// The actions the player can take right now
+	PendingActions []ActionData `json:"pending_actions"`
+}
+
+// What every player can see of one player
+type SeatView struct {
+	Discards     []Tile `json:"discards"`
+	Kans         []Tile `json:"kans"`
+	Ankans       []Tile `json:"ankans"`
+	Pons         []Tile `json:"pons"`
+	Chiis        []Tile `json:"chiis"`
+	ClosedTiles  int    `json:"closed_tiles"` // How many tiles are in the closed hand
+	HandInRiichi bool   `json:"hand_in_riichi"`
+	Points       int32  `json:"points"`
+	SeatWind     Wind   `json:"seat_wind"`
+}
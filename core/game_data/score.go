@@ -0,0 +1,223 @@
+package core
+
+import (
+	"slices"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+)
+
+// ==================== TYPES ====================
+
+type ScoreLimit uint8
+
+const (
+	NO_LIMIT ScoreLimit = iota
+	MANGAN
+	HANEMAN
+	BAIMAN
+	SANBAIMAN
+	KAZOE_YAKUMAN
+	YAKUMAN
+)
+
+// The value of a winning hand and what each player pays for it
+type Score struct {
+	Han        int        `json:"han"` // Including dora
+	Fu         int        `json:"fu"`
+	Limit      ScoreLimit `json:"limit"`
+	BasePoints uint32     `json:"base_points"`
+
+	// Paid by the discarder on a ron
+	RonPayment uint32 `json:"ron_payment"`
+	// Paid by the dealer when a non-dealer wins by tsumo
+	DealerPayment uint32 `json:"dealer_payment"`
+	// Paid by each non-dealer on a tsumo
+	NonDealerPayment uint32 `json:"non_dealer_payment"`
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+func roundUp(points uint32, to uint32) uint32 {
+	return (points + to - 1) / to * to
+}
+
+// Fu for a triplet or quad
+func meldFu(meld Meld, concealed bool) int {
+	fu := 2
+	if meld.Tile.IsTerminalOrHonour() {
+		fu *= 2
+	}
+	if concealed {
+		fu *= 2
+	}
+	if meld.Type == QUAD {
+		fu *= 4
+	}
+	return fu
+}
+
+// Fu for a wait other than a two-sided wait or a wait on either pair
+func waitFu(decomposition Decomposition, winTile Tile) int {
+	if decomposition.WinningMeld < 0 {
+		return 2 // Tanki
+	}
+
+	meld := decomposition.Melds[decomposition.WinningMeld]
+	if meld.Type != SEQUENCE {
+		return 0 // Shanpon
+	}
+	if winTile == meld.Tile+1 {
+		return 2 // Kanchan
+	}
+	if !isRyanmen(meld, winTile) {
+		return 2 // Penchan
+	}
+	return 0
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// The tile a dora indicator points to
+func DoraFromIndicator(indicator Tile) Tile {
+	indicator = indicator.ClearRedOrDora()
+	switch {
+	case indicator.IsSuited():
+		return indicator.SetTileNumber((indicator.GetTileNumber() + 1) % 9)
+	case indicator == NorthTile:
+		return EastTile
+	case indicator.IsWind():
+		return indicator + 1
+	case indicator == White:
+		return Green
+	case indicator == Green:
+		return Red
+	default:
+		return White
+	}
+}
+
+// Counts the dora, ura dora and red fives in a hand completed by winTile
+func CountDora(hand Hand, winTile Tile, context WinContext) int {
+	tiles := handTiles(hand, winTile.ClearRedOrDora())
+	indicators := context.DoraIndicators
+	if context.Riichi || context.DoubleRiichi {
+		indicators = slices.Concat(indicators, context.UraDoraIndicators)
+	}
+
+	dora := 0
+	for _, indicator := range indicators {
+		dora += Count(tiles, DoraFromIndicator(indicator))
+	}
+
+	// Called melds have the red bit set when they hold a red five
+	for _, tile := range slices.Concat(hand.ClosedHand, []Tile{winTile}, hand.Chiis, hand.Pons, hand.Kans, hand.Ankans) {
+		if tile&RedTile != 0 {
+			dora += 1
+		}
+	}
+	return dora
+}
+
+// Counts the fu of a reading of a winning hand, rounded up to the
+// nearest 10. Chiitoitsu is always 25 fu.
+func ComputeFu(hand Hand, decomposition Decomposition, winTile Tile, context WinContext, yakus YakuType) int {
+	winTile = winTile.ClearRedOrDora()
+	switch decomposition.Shape {
+	case CHIITOITSU_SHAPE:
+		return 25
+	case KOKUSHI_SHAPE:
+		return 30
+	}
+
+	closed := !hand.HandOpen
+	if yakus.Has(PINFU_YAKU) {
+		if context.Tsumo {
+			return 20
+		}
+		return 30
+	}
+
+	fu := 20
+	if closed && !context.Tsumo {
+		fu += 10
+	}
+	if context.Tsumo {
+		fu += 2
+	}
+
+	for idx, meld := range decomposition.Melds {
+		if meld.Type == SEQUENCE {
+			continue
+		}
+		// A triplet completed by a discard counts as open
+		concealed := !meld.Open && !(idx == decomposition.WinningMeld && !context.Tsumo)
+		fu += meldFu(meld, concealed)
+	}
+
+	pair := decomposition.Pairs[0]
+	if pair.IsDragon() {
+		fu += 2
+	}
+	if SameWind(context.SeatWind, pair) {
+		fu += 2
+	}
+	if SameWind(context.RoundWind, pair) {
+		fu += 2
+	}
+
+	fu += waitFu(decomposition, winTile)
+
+	// An open hand with nothing but sequences is still worth 30
+	if fu == 20 {
+		fu = 30
+	}
+	return int(roundUp(uint32(fu), 10))
+}
+
+// Computes the value of the winning hand, and the payments owed for it
+func ComputeScore(result WinResult) Score {
+	score := Score{
+		Han: result.Han + result.Dora,
+		Fu:  result.Fu,
+	}
+	if result.Yakus == NO_YAKU {
+		return score
+	}
+
+	switch {
+	case result.Yakus.IsYakuman():
+		score.Limit = YAKUMAN
+		score.BasePoints = 8000 * uint32(max(result.Han/13, 1))
+	case score.Han >= 13:
+		score.Limit = KAZOE_YAKUMAN
+		score.BasePoints = 8000
+	case score.Han >= 11:
+		score.Limit = SANBAIMAN
+		score.BasePoints = 6000
+	case score.Han >= 8:
+		score.Limit = BAIMAN
+		score.BasePoints = 4000
+	case score.Han >= 6:
+		score.Limit = HANEMAN
+		score.BasePoints = 3000
+	default:
+		score.BasePoints = uint32(score.Fu) << (score.Han + 2)
+		score.Limit = NO_LIMIT
+		if score.Han >= 5 || score.BasePoints >= 2000 {
+			score.Limit = MANGAN
+			score.BasePoints = 2000
+		}
+	}
+
+	base := score.BasePoints
+	dealer := result.Context.SeatWind == East
+	if dealer {
+		score.RonPayment = roundUp(6*base, 100)
+		score.NonDealerPayment = roundUp(2*base, 100)
+	} else {
+		score.RonPayment = roundUp(4*base, 100)
+		score.DealerPayment = roundUp(2*base, 100)
+		score.NonDealerPayment = roundUp(base, 100)
+	}
+	return score
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestComputeFu(t *testing.T) {
+	south := WinContext{SeatWind: South, RoundWind: East}
+	tests := []struct {
+		name    string // description of this test case
+		hand    Hand
+		winTile Tile
+		context WinContext
+		want    int
+	}{
+		{
+			name:    "pinfu ron",
+			hand:    Hand{ClosedHand: parseTiles("234m345p22p678s34s")},
+			winTile: parseTile("5s"),
+			context: south,
+			want:    30,
+		},
+		{
+			name:    "pinfu tsumo",
+			hand:    Hand{ClosedHand: parseTiles("234m345p22p678s34s")},
+			winTile: parseTile("5s"),
+			context: WinContext{SeatWind: South, RoundWind: East, Tsumo: true},
+			want:    20,
+		},
+		{
+			name:    "closed ron on a kanchan",
+			hand:    Hand{ClosedHand: parseTiles("234m345p678s2455s")},
+			winTile: parseTile("3s"),
+			context: WinContext{SeatWind: South, RoundWind: East, Riichi: true},
+			want:    40,
+		},
+		{
+			name:    "tsumo on a concealed honour triplet",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s4455z")},
+			winTile: parseTile("5z"),
+			context: WinContext{SeatWind: South, RoundWind: East, Riichi: true, Tsumo: true},
+			want:    30,
+		},
+		{
+			name:    "open pon and tanki",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s5p"), Pons: parseTiles("1z"), HandOpen: true},
+			winTile: parseTile("5p"),
+			context: WinContext{SeatWind: East, RoundWind: East},
+			want:    30,
+		},
+		{
+			name:    "closed terminal kan",
+			hand:    Hand{ClosedHand: parseTiles("234m345p67s55s"), Ankans: parseTiles("9m")},
+			winTile: parseTile("8s"),
+			context: WinContext{SeatWind: South, RoundWind: East, Riichi: true},
+			want:    70,
+		},
+		{
+			name:    "chiitoitsu",
+			hand:    Hand{ClosedHand: parseTiles("1122m3344p5566s7z")},
+			winTile: parseTile("7z"),
+			context: south,
+			want:    25,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateYaku(tt.hand, tt.winTile, tt.context)
+			if got.Fu != tt.want {
+				t.Errorf("EvaluateYaku().Fu = %v, want %v", got.Fu, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeScore(t *testing.T) {
+	result := func(yakus YakuType, han, fu int, dealer, ron bool) WinResult {
+		seat := South
+		if dealer {
+			seat = East
+		}
+		return WinResult{
+			YakuResult: YakuResult{Yakus: yakus, Han: han, Fu: fu},
+			WonByRon:   ron,
+			Context:    WinContext{SeatWind: seat, RoundWind: East, Tsumo: !ron},
+		}
+	}
+
+	tests := []struct {
+		name      string // description of this test case
+		result    WinResult
+		limit     ScoreLimit
+		total     uint32
+		dealer    uint32
+		nonDealer uint32
+	}{
+		{"1 han 30 fu ron", result(RIICHI_YAKU, 1, 30, false, true), NO_LIMIT, 1000, 0, 0},
+		{"1 han 30 fu dealer ron", result(RIICHI_YAKU, 1, 30, true, true), NO_LIMIT, 1500, 0, 0},
+		{"3 han 30 fu tsumo", result(RIICHI_YAKU, 3, 30, false, false), NO_LIMIT, 4000, 2000, 1000},
+		{"4 han 30 fu ron", result(RIICHI_YAKU, 4, 30, false, true), NO_LIMIT, 7700, 0, 0},
+		{"4 han 40 fu ron", result(RIICHI_YAKU, 4, 40, false, true), MANGAN, 8000, 0, 0},
+		{"2 han 25 fu ron", result(CHIITOITSU_YAKU, 2, 25, false, true), NO_LIMIT, 1600, 0, 0},
+		{"pinfu tsumo dealer", result(PINFU_YAKU, 2, 20, true, false), NO_LIMIT, 2100, 0, 700},
+		{"haneman dealer tsumo", result(CHINITSU_YAKU, 6, 30, true, false), HANEMAN, 18000, 0, 6000},
+		{"kazoe yakuman", result(CHINITSU_YAKU, 13, 30, false, true), KAZOE_YAKUMAN, 32000, 0, 0},
+		{"double yakuman", result(DAISUUSHII_YAKU, 26, 30, false, true), YAKUMAN, 64000, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := ComputeScore(tt.result)
+			if score.Limit != tt.limit {
+				t.Errorf("ComputeScore().Limit = %v, want %v", score.Limit, tt.limit)
+			}
+			if !tt.result.WonByRon &&
+				(score.DealerPayment != tt.dealer || score.NonDealerPayment != tt.nonDealer) {
+				t.Errorf("ComputeScore() tsumo payments = %v/%v, want %v/%v",
+					score.DealerPayment, score.NonDealerPayment, tt.dealer, tt.nonDealer)
+			}
+			if got := ComputeMahjongScore(tt.result); got != tt.total {
+				t.Errorf("ComputeMahjongScore() = %v, want %v", got, tt.total)
+			}
+		})
+	}
+}
+
+func TestCountDora(t *testing.T) {
+	hand := Hand{ClosedHand: parseTiles("123m406p789s1155z")}
+	context := WinContext{
+		DoraIndicators:    parseTiles("4z"),
+		UraDoraIndicators: parseTiles("7z"),
+	}
+	if got := CountDora(hand, parseTile("5z"), context); got != 3 {
+		t.Errorf("CountDora() = %v, want 3", got)
+	}
+
+	context.Riichi = true
+	if got := CountDora(hand, parseTile("5z"), context); got != 6 {
+		t.Errorf("CountDora() with riichi = %v, want 6", got)
+	}
+}
+
+func TestCountDoraInMelds(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		hand string
+		call func(player *Player) error
+		want int
+	}{
+		{"red five from the hand in a pon", "055p123m456s789s1z", func(player *Player) error {
+			return player.Pon(parseTile("5p"))
+		}, 1},
+		{"red five called for a pon", "55p123m456s789s11z", func(player *Player) error {
+			return player.Pon(parseTile("0p"))
+		}, 1},
+		{"red five called for a chii", "34m123p456s789s11z", func(player *Player) error {
+			return player.Chii(parseTile("0m"), [2]Tile{parseTile("3m"), parseTile("4m")})
+		}, 1},
+		{"red five in a daiminkan", "055s123m456p789p1z", func(player *Player) error {
+			return player.Daiminkan(parseTile("5s"))
+		}, 1},
+		{"red five in an ankan", "0555s123m456p789p1z", func(player *Player) error {
+			return player.Ankan(parseTile("5s"))
+		}, 1},
+		{"no red five", "555p123m456s789s1z", func(player *Player) error {
+			return player.Pon(parseTile("5p"))
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := Player{Hand: Hand{ClosedHand: parseTiles(tt.hand)}}
+			if err := tt.call(&player); err != nil {
+				t.Fatal(err)
+			}
+			if got := CountDora(player.Hand, parseTile("1z"), WinContext{}); got != tt.want {
+				t.Errorf("CountDora() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
 )
 
 type SetupType uint8
@@ -15,6 +17,9 @@ const (
 	PLAYER_ORDER
 	ROUND_WIND
 	ROUND_NUMBER
+	HONBA
+	RIICHI_STICKS
+	WALL_HASH // Commits to the wall, which is revealed when the hand ends
 )
 
 type Setup struct {
@@ -34,18 +39,33 @@ func (msg *Setup) UnmarshalJSON(rawData []byte) error {
 
 	msg.Type = raw.SetupType
 
+	// The data has the type the game sets up the hand with
+	var err error
 	switch msg.Type {
 	case DORA:
+		msg.Data, err = decodeSetup[Tile](raw.Data)
 	case INITIAL_TILES:
-	case PLAYER_NUMBER:
+		msg.Data, err = decodeSetup[[]Tile](raw.Data)
+	case PLAYER_NUMBER, ROUND_NUMBER, HONBA, RIICHI_STICKS:
+		msg.Data, err = decodeSetup[uint8](raw.Data)
 	case PLAYER_ORDER:
-	case ROUND_NUMBER:
+		msg.Data, err = decodeSetup[[]uint8](raw.Data)
 	case ROUND_WIND:
+		msg.Data, err = decodeSetup[Wind](raw.Data)
 	case STARTING_POINTS:
+		msg.Data, err = decodeSetup[[4]int32](raw.Data)
+	case WALL_HASH:
+		msg.Data, err = decodeSetup[string](raw.Data)
 	default:
-		return fmt.Errorf("unexpected core.SetupType: %#v", msg.Type)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.SetupType: %#v", msg.Type)}
 	}
-	return nil
+	return err
+}
+
+func decodeSetup[T any](data json.RawMessage) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
 }
 
 func SetupDecode[T any, E any](handler ActionHandler[T, E], data ActionData) error {
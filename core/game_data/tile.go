@@ -1,5 +1,7 @@
 package core
 
+import "slices"
+
 type Tile uint8
 
 const (
@@ -46,8 +48,12 @@ func (s Tile) IsHidden() bool {
 	return s == Hidden
 }
 
+func (s Tile) suitBits() Tile {
+	return (s & TileMask) >> 4
+}
+
 func (s Tile) IsHonour() bool {
-	return (s & TileMask) == HonourBit
+	return s.suitBits() == HonourBit
 }
 
 func (s Tile) IsWind() bool {
@@ -59,11 +65,40 @@ func (s Tile) IsDragon() bool {
 }
 
 func (s Tile) IsManzu() bool {
-	return s&(TileMask) == ManzuBit
+	return s.suitBits() == ManzuBit
 }
 
 func (s Tile) IsPinzu() bool {
-	return s&(TileMask) == PinzuBit
+	return s.suitBits() == PinzuBit
+}
+
+func (s Tile) IsSouzu() bool {
+	return s.suitBits() == SouzuBit
+}
+
+// Whether the tile is a number tile (manzu, pinzu or souzu)
+func (s Tile) IsSuited() bool {
+	return !s.IsHonour()
+}
+
+// Whether the tile is a 1 or a 9
+func (s Tile) IsTerminal() bool {
+	return s.IsSuited() && (s.GetTileNumber() == 0 || s.GetTileNumber() == 8)
+}
+
+// Whether the tile is a terminal or an honour
+func (s Tile) IsTerminalOrHonour() bool {
+	return s.IsHonour() || s.IsTerminal()
+}
+
+// Whether two tiles are of the same suit
+func (s Tile) SameSuit(other Tile) bool {
+	return s.suitBits() == other.suitBits()
+}
+
+// Whether both are the same tile, red fives and dora aside
+func (s Tile) SameKind(other Tile) bool {
+	return s.ClearRedOrDora() == other.ClearRedOrDora()
 }
 
 func (s Tile) GetTileNumber() uint8 {
@@ -94,13 +129,13 @@ func GetTileList() []Tile {
 
 	}
 
-	for i := Manzu; i < Manzu+10; i++ {
+	for i := Manzu; i < Manzu+9; i++ {
 		addFour(i)
 	}
-	for i := Pinzu; i < Pinzu+10; i++ {
+	for i := Pinzu; i < Pinzu+9; i++ {
 		addFour(i)
 	}
-	for i := Souzu; i < Souzu+10; i++ {
+	for i := Souzu; i < Souzu+9; i++ {
 		addFour(i)
 	}
 	for i := Kazehai; i <= Green; i++ {
@@ -109,3 +144,23 @@ func GetTileList() []Tile {
 
 	return tiles
 }
+
+// Return the list of tiles, with one five of each suit red
+func GetRedFiveTileList() []Tile {
+	tiles := GetTileList()
+	for _, suit := range []Tile{Manzu, Pinzu, Souzu} {
+		idx := slices.Index(tiles, suit+4)
+		tiles[idx] = tiles[idx].SetRedTile()
+	}
+	return tiles
+}
+
+// Return one of each of the 34 different tiles
+func GetTileKinds() []Tile {
+	tiles := GetTileList()
+	kinds := make([]Tile, 0, 34)
+	for i := 0; i < len(tiles); i += 4 {
+		kinds = append(kinds, tiles[i])
+	}
+	return kinds
+}
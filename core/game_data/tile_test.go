@@ -1,23 +1,96 @@
 package core
 
 import (
+	"slices"
 	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
 )
 
 func TestGetTileList(t *testing.T) {
 	tests := []struct {
-		name string // description of this test case
-		want []Tile
+		name  string // description of this test case
+		tile  Tile
+		count int
+	}{
+		{"first manzu", Manzu, 4},
+		{"last manzu", Manzu + 8, 4},
+		{"no tenth manzu", Manzu + 9, 0},
+		{"pinzu", Pinzu + 4, 4},
+		{"souzu", Souzu + 8, 4},
+		{"east", EastTile, 4},
+		{"green", Green, 4},
+		{"red five", Manzu.SetTileNumber(4).SetRedTile(), 0},
+	}
+
+	got := GetTileList()
+	if len(got) != 136 {
+		t.Fatalf("len(GetTileList()) = %v, want 136", len(got))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count := 0
+			for _, tile := range got {
+				if tile == tt.tile {
+					count++
+				}
+			}
+			if count != tt.count {
+				t.Errorf("GetTileList() has %v of %v, want %v", count, tt.tile, tt.count)
+			}
+		})
+	}
+}
+
+func TestGetTileKinds(t *testing.T) {
+	kinds := GetTileKinds()
+	if len(kinds) != 34 {
+		t.Fatalf("len(GetTileKinds()) = %v, want 34", len(kinds))
+	}
+	if !slices.IsSorted(kinds) || len(slices.Compact(slices.Clone(kinds))) != 34 {
+		t.Errorf("GetTileKinds() = %v, want 34 distinct sorted tiles", kinds)
+	}
+}
+
+func TestTileClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		tile     Tile
+		honour   bool
+		terminal bool
 	}{
-		// TODO: Add test cases.
+		{"1m", Manzu, false, true},
+		{"5p", Pinzu + 4, false, false},
+		{"red 5s", (Souzu + 4).SetRedTile(), false, false},
+		{"9s", Souzu + 8, false, true},
+		{"north", NorthTile, true, false},
+		{"white", White, true, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetTileList()
-			// TODO: update the condition below to compare got with tt.want.
-			if true {
-				t.Errorf("GetTileList() = %v, want %v", got, tt.want)
+			if got := tt.tile.IsHonour(); got != tt.honour {
+				t.Errorf("IsHonour() = %v, want %v", got, tt.honour)
+			}
+			if got := tt.tile.IsTerminal(); got != tt.terminal {
+				t.Errorf("IsTerminal() = %v, want %v", got, tt.terminal)
 			}
 		})
 	}
 }
+
+func TestGetRedFiveTileList(t *testing.T) {
+	got := GetRedFiveTileList()
+	if len(got) != 136 {
+		t.Fatalf("len(GetRedFiveTileList()) = %v, want 136", len(got))
+	}
+	for _, suit := range []Tile{Manzu, Pinzu, Souzu} {
+		five := suit.SetTileNumber(4)
+		if red := Count(got, five.SetRedTile()); red != 1 {
+			t.Errorf("GetRedFiveTileList() has %v red fives of suit %v, want 1", red, suit)
+		}
+		if normal := Count(got, five); normal != 3 {
+			t.Errorf("GetRedFiveTileList() has %v normal fives of suit %v, want 3", normal, suit)
+		}
+	}
+}
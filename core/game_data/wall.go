@@ -0,0 +1,34 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// The wall of a hand, revealed once the hand is over so that anyone can
+// check it against the hash published when the hand started
+type WallReveal struct {
+	Salt  string `json:"salt"`  // Hex encoded
+	Tiles []Tile `json:"tiles"` // All 136 tiles in wall order
+}
+
+// The hex encoded SHA-256 of the salt followed by the tiles, one byte
+// each. The salt keeps anyone from telling which wall the hash is for
+// before it is revealed.
+func HashWall(salt []byte, tiles []Tile) string {
+	hash := sha256.New()
+	hash.Write(salt)
+	for _, tile := range tiles {
+		hash.Write([]byte{byte(tile)})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Whether the revealed wall is the one the hash was published for
+func (reveal WallReveal) Verify(hash string) bool {
+	salt, err := hex.DecodeString(reveal.Salt)
+	if err != nil || len(reveal.Tiles) != 136 {
+		return false
+	}
+	return HashWall(salt, reveal.Tiles) == hash
+}
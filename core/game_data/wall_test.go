@@ -0,0 +1,35 @@
+package core
+
+import (
+	"encoding/hex"
+	"slices"
+	"testing"
+)
+
+func TestWallRevealVerify(t *testing.T) {
+	salt := []byte("not very random")
+	tiles := GetTileList()
+	hash := HashWall(salt, tiles)
+
+	swapped := slices.Clone(tiles)
+	swapped[0], swapped[135] = swapped[135], swapped[0]
+
+	tests := []struct {
+		name   string // description of this test case
+		reveal WallReveal
+		want   bool
+	}{
+		{"same wall", WallReveal{Salt: hex.EncodeToString(salt), Tiles: tiles}, true},
+		{"tiles swapped", WallReveal{Salt: hex.EncodeToString(salt), Tiles: swapped}, false},
+		{"other salt", WallReveal{Salt: hex.EncodeToString([]byte("other")), Tiles: tiles}, false},
+		{"salt not hex", WallReveal{Salt: "salt", Tiles: tiles}, false},
+		{"missing tiles", WallReveal{Salt: hex.EncodeToString(salt), Tiles: tiles[:135]}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reveal.Verify(hash); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package core
 
 type WinResult struct {
-	Yakus       YakuType
+	YakuResult
+	Dora        int // Including ura dora and red fives
 	WinningHand Hand
 	WinningTile Tile
 	WonByRon    bool
+	Context     WinContext
 }
@@ -1,5 +1,11 @@
 package core
 
+import (
+	"slices"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+)
+
 // TODO: Consider generating this by parsing from a file instead
 // Indexing from a map seems like a bad idea performance-wise, we
 // should really be generating this code at compile-time instead
@@ -127,42 +133,24 @@ func (yaku YakuType) Han() int {
 	return totalHan
 }
 
-func (yaku YakuType) IsYakuman() bool {
-	yakuman := []YakuType{
-		KAZOE_YAKUMAN_YAKU,
-		KOKUSHI_MUSOU_YAKU,
-		SUUANKOU_YAKU,
-		DAISANGEN_YAKU,
-		SHOUSUUSHII_YAKU,
-		DAISUUSHII_YAKU,
-		TSUUIISOU_YAKU,
-		CHINROUTOU_YAKU,
-		RYUUIISOU_YAKU,
-		CHUUREN_POUTOU_YAKU,
-		SUUKANTSU_YAKU,
-		TENHOU_YAKU,
-		CHIIHOU_YAKU,
-	}
-
-	yakuman_bits := NO_YAKU
-	for _, ym := range yakuman {
-		yakuman_bits |= ym
-	}
-
-	if (yaku & yakuman_bits) != 0 {
-		return true
-	}
-
-	hanCount := 0
-	iterateYaku(yaku, func(singleYaku YakuType) {
-		hanCount += singleYaku.Han()
-	})
-
-	if hanCount == 13 {
-		return true
-	}
+// The yaku that are worth a yakuman or more
+const yakumanBits = KAZOE_YAKUMAN_YAKU |
+	KOKUSHI_MUSOU_YAKU |
+	KOKUSHI_MUSOU_THIRTEEN_WAITS_YAKU |
+	SUUANKOU_YAKU |
+	DAISANGEN_YAKU |
+	SHOUSUUSHII_YAKU |
+	DAISUUSHII_YAKU |
+	TSUUIISOU_YAKU |
+	CHINROUTOU_YAKU |
+	RYUUIISOU_YAKU |
+	CHUUREN_POUTOU_YAKU |
+	SUUKANTSU_YAKU |
+	TENHOU_YAKU |
+	CHIIHOU_YAKU
 
-	return false
+func (yaku YakuType) IsYakuman() bool {
+	return yaku&yakumanBits != 0
 }
 
 // Whether or not the hand can be open.
@@ -270,11 +258,413 @@ func (yaku YakuType) HanLossOnOpen() int {
 	return totalHanLoss
 }
 
-func (yaku *YakuType) Set() {
+func (yaku *YakuType) Set(yakus YakuType) {
+	*yaku |= yakus
+}
+
+func (yaku YakuType) Has(yakus YakuType) bool {
+	return yaku&yakus != 0
+}
 
+// ==================== YAKU DETECTION ====================
+
+// The situation a hand was won in, which cannot be told from the
+// tiles alone
+type WinContext struct {
+	Tsumo        bool `json:"tsumo"`
+	Riichi       bool `json:"riichi"`
+	DoubleRiichi bool `json:"double_riichi"`
+	Ippatsu      bool `json:"ippatsu"`
+	LastTile     bool `json:"last_tile"` // Won on the last tile of the wall
+	Rinshan      bool `json:"rinshan"`   // Won on the replacement tile of a kan
+	Chankan      bool `json:"chankan"`   // Won on a tile added to a pon
+	FirstTurn    bool `json:"first_turn"`
+	SeatWind     Wind `json:"seat_wind"`
+	RoundWind    Wind `json:"round_wind"`
+
+	// The revealed dora indicators. Ura dora only count for riichi hands
+	DoraIndicators    []Tile `json:"dora_indicators"`
+	UraDoraIndicators []Tile `json:"ura_dora_indicators"`
+}
+
+// The yaku of a winning hand, together with the reading of the hand
+// they were found in
+type YakuResult struct {
+	Yakus         YakuType      `json:"yakus"`
+	YakuhaiCount  int           `json:"yakuhai_count"` // Each yakuhai triplet is worth a han
+	Han           int           `json:"han"`
+	Fu            int           `json:"fu"`
+	Decomposition Decomposition `json:"decomposition"`
+}
+
+// Finds the yaku of a hand completed by winTile. The closed hand
+// should not contain the winning tile. Every reading of the hand is
+// tried and the highest scoring one is returned. Returns NO_YAKU if
+// the hand is not complete or has no yaku.
+func EvaluateYaku(hand Hand, winTile Tile, context WinContext) YakuResult {
+	best := YakuResult{Yakus: NO_YAKU}
+	for _, decomposition := range DecomposeHand(hand, winTile) {
+		result := detectYaku(hand, decomposition, winTile.ClearRedOrDora(), context)
+		if result.Yakus == NO_YAKU {
+			continue
+		}
+		if result.Han > best.Han || (result.Han == best.Han && result.Fu > best.Fu) {
+			best = result
+		}
+	}
+	return best
+}
+
+func GetYaku(hand Hand, winTile Tile, context WinContext) YakuType {
+	return EvaluateYaku(hand, winTile, context).Yakus
+}
+
+// All of the tiles of the complete hand, with red and dora bits cleared
+func handTiles(hand Hand, winTile Tile) []Tile {
+	tiles := make([]Tile, 0, 18)
+	for _, tile := range hand.ClosedHand {
+		tiles = append(tiles, tile.ClearRedOrDora())
+	}
+	tiles = append(tiles, winTile)
+	for _, meld := range hand.calledMelds() {
+		tiles = append(tiles, meld.Tiles()...)
+	}
+	return tiles
+}
+
+func allTiles(tiles []Tile, predicate func(Tile) bool) bool {
+	for _, tile := range tiles {
+		if !predicate(tile) {
+			return false
+		}
+	}
+	return true
 }
 
-// TODO: Do Yaku calculations
-func GetYaku(hand Hand, winTile Tile) YakuType {
-	return NO_YAKU
+func anyTile(tiles []Tile, predicate func(Tile) bool) bool {
+	for _, tile := range tiles {
+		if predicate(tile) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGreen(tile Tile) bool {
+	if tile == Green {
+		return true
+	}
+	if !tile.IsSouzu() {
+		return false
+	}
+	switch tile.GetTileNumber() + 1 {
+	case 2, 3, 4, 6, 8:
+		return true
+	default:
+		return false
+	}
+}
+
+// Yaku that depend on how the hand was won rather than its shape
+func situationalYaku(hand Hand, context WinContext) (yakus YakuType) {
+	closed := !hand.HandOpen
+	if context.Tsumo && closed {
+		yakus.Set(MENZEN_TSUMO_YAKU)
+	}
+	if context.DoubleRiichi {
+		yakus.Set(DOUBLE_RIICHI_YAKU)
+	} else if context.Riichi {
+		yakus.Set(RIICHI_YAKU)
+	}
+	if context.Ippatsu && (context.Riichi || context.DoubleRiichi) {
+		yakus.Set(IPPATSU_YAKU)
+	}
+	if context.Rinshan && context.Tsumo {
+		yakus.Set(RINSHAN_KAIHOU_YAKU)
+	} else if context.LastTile && context.Tsumo {
+		yakus.Set(HAITEI_YAOYUE_YAKU)
+	}
+	if context.LastTile && !context.Tsumo {
+		yakus.Set(HOUTEI_RAOYUI_YAKU)
+	}
+	if context.Chankan && !context.Tsumo {
+		yakus.Set(CHANKAN_YAKU)
+	}
+	if context.FirstTurn && context.Tsumo && closed {
+		if context.SeatWind == East {
+			yakus.Set(TENHOU_YAKU)
+		} else {
+			yakus.Set(CHIIHOU_YAKU)
+		}
+	}
+	return yakus
+}
+
+// Yaku that only depend on which tiles are in the hand
+func tileYaku(tiles []Tile) (yakus YakuType) {
+	if allTiles(tiles, func(tile Tile) bool { return !tile.IsTerminalOrHonour() }) {
+		yakus.Set(TANYAO_YAKU)
+	}
+
+	suited := make([]Tile, 0, len(tiles))
+	for _, tile := range tiles {
+		if tile.IsSuited() {
+			suited = append(suited, tile)
+		}
+	}
+	oneSuit := len(suited) != 0 && allTiles(suited, suited[0].SameSuit)
+	switch {
+	case oneSuit && len(suited) == len(tiles):
+		yakus.Set(CHINITSU_YAKU)
+	case oneSuit:
+		yakus.Set(HONITSU_YAKU)
+	}
+
+	switch {
+	case len(suited) == 0:
+		yakus.Set(TSUUIISOU_YAKU)
+	case allTiles(tiles, Tile.IsTerminal):
+		yakus.Set(CHINROUTOU_YAKU)
+	case allTiles(tiles, Tile.IsTerminalOrHonour):
+		yakus.Set(HONROUTOU_YAKU)
+	}
+
+	if allTiles(tiles, isGreen) {
+		yakus.Set(RYUUIISOU_YAKU)
+	}
+	return yakus
+}
+
+// Whether the closed hand is one suit in the shape 1112345678999
+// plus any one tile of the suit
+func isChuuren(hand Hand, tiles []Tile) bool {
+	if hand.HandOpen || len(hand.Ankans) != 0 {
+		return false
+	}
+	if !allTiles(tiles, func(tile Tile) bool { return tile.IsSuited() && tile.SameSuit(tiles[0]) }) {
+		return false
+	}
+
+	counts := countTiles(tiles)
+	base := tiles[0].SetTileNumber(0)
+	for number := range Tile(9) {
+		needed := uint8(1)
+		if number == 0 || number == 8 {
+			needed = 3
+		}
+		if counts[base+number] < needed {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether the winning tile completed a two-sided wait on a sequence
+func isRyanmen(meld Meld, winTile Tile) bool {
+	if meld.Type != SEQUENCE {
+		return false
+	}
+	number := meld.Tile.GetTileNumber()
+	return (winTile == meld.Tile && number <= 5) ||
+		(winTile == meld.Tile+2 && number >= 1)
+}
+
+// Yaku that depend on how a standard hand splits into melds
+func meldYaku(hand Hand, decomposition Decomposition, winTile Tile, context WinContext) (yakus YakuType, yakuhai int) {
+	closed := !hand.HandOpen
+	melds := decomposition.Melds
+	pair := decomposition.Pairs[0]
+	isYakuhai := func(tile Tile) int {
+		count := 0
+		if tile.IsDragon() {
+			count += 1
+		}
+		if SameWind(context.SeatWind, tile) {
+			count += 1
+		}
+		if SameWind(context.RoundWind, tile) {
+			count += 1
+		}
+		return count
+	}
+
+	sequences := make([]Tile, 0, 4)
+	triplets := make([]Tile, 0, 4)
+	concealed, quads := 0, 0
+	for idx, meld := range melds {
+		if meld.Type == SEQUENCE {
+			sequences = append(sequences, meld.Tile)
+			continue
+		}
+
+		triplets = append(triplets, meld.Tile)
+		yakuhai += isYakuhai(meld.Tile)
+		if meld.Type == QUAD {
+			quads += 1
+		}
+		// A triplet completed by a discard counts as open
+		if !meld.Open && !(idx == decomposition.WinningMeld && !context.Tsumo) {
+			concealed += 1
+		}
+	}
+
+	if yakuhai != 0 {
+		yakus.Set(YAKUHAI_YAKU)
+	}
+
+	if closed && len(sequences) == 4 && isYakuhai(pair) == 0 &&
+		decomposition.WinningMeld >= 0 && isRyanmen(melds[decomposition.WinningMeld], winTile) {
+		yakus.Set(PINFU_YAKU)
+	}
+
+	if closed {
+		identical := 0
+		counts := countTiles(sequences)
+		for _, count := range counts {
+			identical += int(count) / 2
+		}
+		switch identical {
+		case 0:
+		case 1:
+			yakus.Set(IIPEIKOU_YAKU)
+		default:
+			yakus.Set(RYANPEIKOU_YAKU)
+		}
+	}
+
+	hasTerminal := func(meld Meld) bool {
+		return anyTile(meld.Tiles(), Tile.IsTerminalOrHonour)
+	}
+	if len(sequences) != 0 && pair.IsTerminalOrHonour() {
+		outside := true
+		for _, meld := range melds {
+			outside = outside && hasTerminal(meld)
+		}
+		if outside && (pair.IsHonour() || anyTile(triplets, Tile.IsHonour)) {
+			yakus.Set(CHANTAIYAO_YAKU)
+		} else if outside {
+			yakus.Set(JUNCHAN_YAKU)
+		}
+	}
+
+	hasInEachSuit := func(tiles []Tile, number uint8) bool {
+		return slices.Contains(tiles, Manzu.SetTileNumber(number)) &&
+			slices.Contains(tiles, Pinzu.SetTileNumber(number)) &&
+			slices.Contains(tiles, Souzu.SetTileNumber(number))
+	}
+	for number := range uint8(9) {
+		if hasInEachSuit(sequences, number) {
+			yakus.Set(SANSHOKU_DOUJUN_YAKU)
+		}
+		if hasInEachSuit(triplets, number) {
+			yakus.Set(SANSHOKU_DOUKOU_YAKU)
+		}
+	}
+
+	for _, suit := range []Tile{Manzu, Pinzu, Souzu} {
+		if slices.Contains(sequences, suit) &&
+			slices.Contains(sequences, suit.SetTileNumber(3)) &&
+			slices.Contains(sequences, suit.SetTileNumber(6)) {
+			yakus.Set(ITTSU_YAKU)
+		}
+	}
+
+	if len(triplets) == 4 {
+		yakus.Set(TOITOI_YAKU)
+	}
+	switch concealed {
+	case 3:
+		yakus.Set(SANANKOU_YAKU)
+	case 4:
+		yakus.Set(SUUANKOU_YAKU)
+	}
+	switch quads {
+	case 3:
+		yakus.Set(SANKANTSU_YAKU)
+	case 4:
+		yakus.Set(SUUKANTSU_YAKU)
+	}
+
+	dragons := Count(triplets, White) +
+		Count(triplets, Green) + Count(triplets, Red)
+	switch {
+	case dragons == 3:
+		yakus.Set(DAISANGEN_YAKU)
+	case dragons == 2 && pair.IsDragon():
+		yakus.Set(SHOUSANGEN_YAKU)
+	}
+
+	winds := 0
+	for _, tile := range triplets {
+		if tile.IsWind() {
+			winds += 1
+		}
+	}
+	switch {
+	case winds == 4:
+		yakus.Set(DAISUUSHII_YAKU)
+	case winds == 3 && pair.IsWind():
+		yakus.Set(SHOUSUUSHII_YAKU)
+	}
+
+	return yakus, yakuhai
+}
+
+// Finds the yaku of a single reading of the hand
+func detectYaku(hand Hand, decomposition Decomposition, winTile Tile, context WinContext) YakuResult {
+	tiles := handTiles(hand, winTile)
+	yakus := situationalYaku(hand, context) | tileYaku(tiles)
+	yakuhai := 0
+
+	switch decomposition.Shape {
+	case STANDARD_SHAPE:
+		var shapeYakus YakuType
+		shapeYakus, yakuhai = meldYaku(hand, decomposition, winTile, context)
+		yakus.Set(shapeYakus)
+		if isChuuren(hand, tiles) {
+			yakus.Set(CHUUREN_POUTOU_YAKU)
+		}
+	case CHIITOITSU_SHAPE:
+		yakus.Set(CHIITOITSU_YAKU)
+	case KOKUSHI_SHAPE:
+		if decomposition.Pairs[0] == winTile {
+			yakus.Set(KOKUSHI_MUSOU_THIRTEEN_WAITS_YAKU)
+		} else {
+			yakus.Set(KOKUSHI_MUSOU_YAKU)
+		}
+	}
+
+	// Remove the yaku that are contained in a stronger one
+	if yakus.Has(CHINITSU_YAKU) {
+		yakus &^= HONITSU_YAKU
+	}
+	if yakus.Has(RYANPEIKOU_YAKU) {
+		yakus &^= IIPEIKOU_YAKU
+	}
+	if yakus.Has(HONROUTOU_YAKU) {
+		yakus &^= CHANTAIYAO_YAKU
+	}
+	if yakus.IsYakuman() {
+		yakus &= yakumanBits
+	}
+
+	result := YakuResult{
+		Yakus:         yakus,
+		YakuhaiCount:  yakuhai,
+		Decomposition: decomposition,
+	}
+	if yakus == 0 {
+		result.Yakus = NO_YAKU
+		return result
+	}
+
+	result.Han = yakus.Han()
+	if hand.HandOpen {
+		result.Han -= yakus.HanLossOnOpen()
+	}
+	if yakus.Has(YAKUHAI_YAKU) {
+		result.Han += yakuhai - 1
+	}
+	result.Fu = ComputeFu(hand, decomposition, winTile, context, yakus)
+	return result
 }
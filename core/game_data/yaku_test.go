@@ -0,0 +1,206 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestEvaluateYaku(t *testing.T) {
+	dealer := WinContext{SeatWind: East, RoundWind: East}
+	south := WinContext{SeatWind: South, RoundWind: East}
+	tests := []struct {
+		name    string // description of this test case
+		hand    Hand
+		winTile Tile
+		context WinContext
+		want    YakuType
+		han     int
+	}{
+		{
+			name:    "no yaku",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s1123z"), HandOpen: true},
+			winTile: parseTile("3z"),
+			context: south,
+			want:    NO_YAKU,
+			han:     0,
+		},
+		{
+			name:    "pinfu tanyao",
+			hand:    Hand{ClosedHand: parseTiles("234m345p22p678s34s")},
+			winTile: parseTile("5s"),
+			context: south,
+			want:    PINFU_YAKU | TANYAO_YAKU,
+			han:     2,
+		},
+		{
+			name:    "no pinfu on a kanchan wait",
+			hand:    Hand{ClosedHand: parseTiles("234m345p678s2455s")},
+			winTile: parseTile("3s"),
+			context: WinContext{SeatWind: South, RoundWind: East, Riichi: true},
+			want:    RIICHI_YAKU | TANYAO_YAKU,
+			han:     2,
+		},
+		{
+			name:    "riichi ippatsu tsumo",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s4455z"), HandInRiichi: true},
+			winTile: parseTile("5z"),
+			context: WinContext{SeatWind: South, RoundWind: East, Riichi: true, Ippatsu: true, Tsumo: true},
+			want:    RIICHI_YAKU | IPPATSU_YAKU | MENZEN_TSUMO_YAKU | YAKUHAI_YAKU,
+			han:     4,
+		},
+		{
+			name:    "double east",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s5p"), Pons: parseTiles("1z"), HandOpen: true},
+			winTile: parseTile("5p"),
+			context: dealer,
+			want:    YAKUHAI_YAKU,
+			han:     2,
+		},
+		{
+			name:    "open honitsu loses a han",
+			hand:    Hand{ClosedHand: parseTiles("123456m99m77z"), Chiis: parseTiles("7m"), HandOpen: true},
+			winTile: parseTile("9m"),
+			context: south,
+			want:    HONITSU_YAKU | ITTSU_YAKU,
+			han:     3,
+		},
+		{
+			name:    "iipeikou read over sanankou",
+			hand:    Hand{ClosedHand: parseTiles("111222333m456p5s")},
+			winTile: parseTile("5s"),
+			context: WinContext{SeatWind: South, RoundWind: East, Tsumo: true},
+			want:    MENZEN_TSUMO_YAKU | SANANKOU_YAKU,
+			han:     3,
+		},
+		{
+			name:    "ron on a triplet is not concealed",
+			hand:    Hand{ClosedHand: parseTiles("111m222p33s55s789p")},
+			winTile: parseTile("3s"),
+			context: south,
+			want:    NO_YAKU,
+			han:     0,
+		},
+		{
+			name:    "chiitoitsu",
+			hand:    Hand{ClosedHand: parseTiles("1122m3344p5566s7z")},
+			winTile: parseTile("7z"),
+			context: south,
+			want:    CHIITOITSU_YAKU,
+			han:     2,
+		},
+		{
+			name:    "ryanpeikou over chiitoitsu",
+			hand:    Hand{ClosedHand: parseTiles("223344m556677p8s")},
+			winTile: parseTile("8s"),
+			context: south,
+			want:    RYANPEIKOU_YAKU | TANYAO_YAKU,
+			han:     4,
+		},
+		{
+			name:    "junchan sanshoku",
+			hand:    Hand{ClosedHand: parseTiles("123m123p123s999m1p")},
+			winTile: parseTile("1p"),
+			context: south,
+			want:    JUNCHAN_YAKU | SANSHOKU_DOUJUN_YAKU,
+			han:     5,
+		},
+		{
+			name:    "chanta",
+			hand:    Hand{ClosedHand: parseTiles("123m789p123s444z5z")},
+			winTile: parseTile("5z"),
+			context: south,
+			want:    CHANTAIYAO_YAKU,
+			han:     2,
+		},
+		{
+			name:    "shousangen",
+			hand:    Hand{ClosedHand: parseTiles("555z666z77z123m44p")},
+			winTile: parseTile("4p"),
+			context: south,
+			want:    YAKUHAI_YAKU | SHOUSANGEN_YAKU,
+			han:     4,
+		},
+		{
+			name:    "kokushi",
+			hand:    Hand{ClosedHand: parseTiles("19m19p19s1234566z")},
+			winTile: parseTile("7z"),
+			context: south,
+			want:    KOKUSHI_MUSOU_YAKU,
+			han:     13,
+		},
+		{
+			name:    "kokushi thirteen waits",
+			hand:    Hand{ClosedHand: parseTiles("19m19p19s1234567z")},
+			winTile: parseTile("9p"),
+			context: south,
+			want:    KOKUSHI_MUSOU_THIRTEEN_WAITS_YAKU,
+			han:     26,
+		},
+		{
+			name:    "chuuren poutou",
+			hand:    Hand{ClosedHand: parseTiles("1112345678999p")},
+			winTile: parseTile("5p"),
+			context: south,
+			want:    CHUUREN_POUTOU_YAKU,
+			han:     13,
+		},
+		{
+			name:    "suuankou tsumo",
+			hand:    Hand{ClosedHand: parseTiles("111m222p333s44s55z")},
+			winTile: parseTile("5z"),
+			context: WinContext{SeatWind: South, RoundWind: East, Tsumo: true},
+			want:    SUUANKOU_YAKU,
+			han:     13,
+		},
+		{
+			name:    "daisangen with an open pon",
+			hand:    Hand{ClosedHand: parseTiles("555z666z12m99p"), Pons: parseTiles("7z"), HandOpen: true},
+			winTile: parseTile("3m"),
+			context: south,
+			want:    DAISANGEN_YAKU,
+			han:     13,
+		},
+		{
+			name:    "ryuuiisou",
+			hand:    Hand{ClosedHand: parseTiles("223344s666s888s6z")},
+			winTile: parseTile("6z"),
+			context: south,
+			want:    RYUUIISOU_YAKU,
+			han:     13,
+		},
+		{
+			name:    "tenhou",
+			hand:    Hand{ClosedHand: parseTiles("123m456p789s1155z")},
+			winTile: parseTile("5z"),
+			context: WinContext{SeatWind: East, RoundWind: East, Tsumo: true, FirstTurn: true},
+			want:    TENHOU_YAKU,
+			han:     13,
+		},
+		{
+			name:    "rinshan on an ankan",
+			hand:    Hand{ClosedHand: parseTiles("123m456p78s11z"), Ankans: parseTiles("9m")},
+			winTile: parseTile("9s"),
+			context: WinContext{SeatWind: South, RoundWind: East, Tsumo: true, Rinshan: true},
+			want:    MENZEN_TSUMO_YAKU | RINSHAN_KAIHOU_YAKU,
+			han:     2,
+		},
+		{
+			name:    "houtei",
+			hand:    Hand{ClosedHand: parseTiles("123m456p78s11z"), Chiis: parseTiles("1s"), HandOpen: true},
+			winTile: parseTile("9s"),
+			context: WinContext{SeatWind: South, RoundWind: East, LastTile: true},
+			want:    HOUTEI_RAOYUI_YAKU,
+			han:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateYaku(tt.hand, tt.winTile, tt.context)
+			if got.Yakus != tt.want {
+				t.Errorf("EvaluateYaku().Yakus = %b, want %b", got.Yakus, tt.want)
+			}
+			if got.Han != tt.han {
+				t.Errorf("EvaluateYaku().Han = %v, want %v", got.Han, tt.han)
+			}
+		})
+	}
+}
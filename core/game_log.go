@@ -0,0 +1,200 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/google/uuid"
+)
+
+type LogEntryType uint8
+
+const (
+	MATCH_START_ENTRY LogEntryType = iota
+	HAND_START_ENTRY
+	ACTION_ENTRY // An action a player took
+	EVENT_ENTRY  // A board event the arena sent out
+)
+
+// One line of a game log. Only the fields of its type are set.
+type LogEntry struct {
+	Time time.Time    `json:"time"`
+	Type LogEntryType `json:"type"`
+
+	// MATCH_START_ENTRY
+	Seed    Seed        `json:"seed,omitzero"`
+	Rules   *MatchRules `json:"rules,omitempty"`
+	Players []AgentInfo `json:"players,omitempty"`
+
+	// HAND_START_ENTRY, with the salt the wall was hashed with
+	Round *RoundInfo `json:"round,omitempty"`
+
+	// ACTION_ENTRY
+	Action *ActionData `json:"action,omitempty"`
+	Player uint8       `json:"player"`
+
+	// EVENT_ENTRY. The event is kept as it was encoded when sent, along
+	// with who it was sent to, before any of it was hidden.
+	Event      json.RawMessage `json:"event,omitempty"`
+	Visibility Visibility      `json:"visibility"`
+	SendTo     uint8           `json:"send_to"`
+}
+
+// Where game logs are kept
+type LogSink interface {
+	// Appends entries to the log of a game. Entries that were appended
+	// are never changed afterwards.
+	Append(gameID uuid.UUID, entries []LogEntry) error
+}
+
+// A sink that game logs can be read back from
+type LogSource interface {
+	Read(gameID uuid.UUID) ([]LogEntry, error)
+}
+
+// The sink every new arena writes its logs to, none if nil
+var GlobalLogSink LogSink
+
+// Everything that happened in a match, in the order it happened
+type GameLog struct {
+	ID      uuid.UUID
+	Entries []LogEntry
+
+	sink    LogSink
+	flushed int // How many entries were appended to the sink
+}
+
+// Keeps every game log as a file of JSON lines, named after the game
+type FileLogSink struct {
+	Dir string
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+func (log *GameLog) record(entry LogEntry) {
+	entry.Time = time.Now()
+	log.Entries = append(log.Entries, entry)
+}
+
+func (log *GameLog) recordEvent(event ArenaBoardEventData, visibility Visibility, sendTo uint8) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		panic(err)
+	}
+	log.record(LogEntry{Type: EVENT_ENTRY, Event: data, Visibility: visibility, SendTo: sendTo})
+}
+
+// Starts the log of a new match in the arena
+func (arena *Arena) startLog() {
+	players := make([]AgentInfo, 0, len(arena.agents))
+	for _, agent := range arena.agents {
+		players = append(players, agent.Info())
+	}
+
+	rules := arena.match.Rules
+	arena.log = NewGameLog(arena.logSink)
+	arena.log.record(LogEntry{
+		Type:    MATCH_START_ENTRY,
+		Seed:    arena.match.Seed,
+		Rules:   &rules,
+		Players: players,
+	})
+}
+
+func (arena *Arena) flushLog() {
+	if err := arena.log.Flush(); err != nil {
+		fmt.Println("Failed to write the game log:", err)
+	}
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+func NewGameLog(sink LogSink) GameLog {
+	return GameLog{ID: uuid.New(), sink: sink}
+}
+
+// Records the start of a hand that was just dealt
+func (log *GameLog) RecordHand(game MahjongGame, round RoundInfo) {
+	// The match keeps updating the round for the hands after this one
+	round.Points = slices.Clone(round.Points)
+	round.Seats = slices.Clone(round.Seats)
+	round.WallSalt = game.WallSalt
+	log.record(LogEntry{Type: HAND_START_ENTRY, Round: &round})
+}
+
+func (log *GameLog) RecordAction(action ActionData, fromPlayer uint8) {
+	log.record(LogEntry{Type: ACTION_ENTRY, Action: &action, Player: fromPlayer})
+}
+
+// Appends the entries recorded since the last flush to the sink
+func (log *GameLog) Flush() error {
+	if log.sink == nil || log.flushed == len(log.Entries) {
+		return nil
+	}
+	if err := log.sink.Append(log.ID, log.Entries[log.flushed:]); err != nil {
+		return err
+	}
+	log.flushed = len(log.Entries)
+	return nil
+}
+
+func (sink FileLogSink) Path(gameID uuid.UUID) string {
+	return filepath.Join(sink.Dir, gameID.String()+".jsonl")
+}
+
+// Append implements LogSink.
+func (sink FileLogSink) Append(gameID uuid.UUID, entries []LogEntry) error {
+	if err := os.MkdirAll(sink.Dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(sink.Path(gameID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Read implements LogSource.
+func (sink FileLogSink) Read(gameID uuid.UUID) ([]LogEntry, error) {
+	return ReadLogFile(sink.Path(gameID))
+}
+
+// Reads back a log written by a FileLogSink
+func ReadLogFile(path string) ([]LogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := make([]LogEntry, 0)
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var entry LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+func TestGameLog(t *testing.T) {
+	sink := FileLogSink{Dir: t.TempDir()}
+	arena := CreateArena("test", [16]byte{})
+	arena.logSink = sink
+	for _, name := range []string{"east", "south", "west", "north"} {
+		if err := arena.JoinArena(NewBot(name), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadLogFile(sink.Path(arena.log.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(arena.log.Entries) {
+		t.Fatalf("Read %d entries back, %d were recorded", len(entries), len(arena.log.Entries))
+	}
+	if entries[0].Type != MATCH_START_ENTRY || entries[0].Seed != arena.match.Seed || len(entries[0].Players) != 4 {
+		t.Errorf("First entry = %+v, want the start of the match", entries[0])
+	}
+
+	counts := make(map[LogEntryType]int)
+	ends := make(map[BoardEventType]int)
+	for idx, entry := range entries {
+		counts[entry.Type] += 1
+		if idx > 0 && entry.Time.Before(entries[idx-1].Time) {
+			t.Errorf("Entry %d was recorded before the one preceding it", idx)
+		}
+
+		switch entry.Type {
+		case ACTION_ENTRY:
+			if entry.Action == nil {
+				t.Errorf("Entry %d has no action", idx)
+			}
+		case EVENT_ENTRY:
+			var event ArenaBoardEventData
+			if err := json.Unmarshal(entry.Event, &event); err != nil {
+				t.Fatalf("Entry %d: %v", idx, err)
+			}
+			ends[event.EventType] += 1
+		}
+	}
+	if counts[HAND_START_ENTRY] == 0 || counts[HAND_START_ENTRY] != ends[GameEndEventType] {
+		t.Errorf("%d hands started and %d ended", counts[HAND_START_ENTRY], ends[GameEndEventType])
+	}
+	if counts[ACTION_ENTRY] == 0 || ends[MatchEndEventType] != 1 {
+		t.Errorf("Logged %d actions and %d match ends", counts[ACTION_ENTRY], ends[MatchEndEventType])
+	}
+}
@@ -0,0 +1,628 @@
+package core
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+// Picks one of the potential actions sent out, filling in the tile to
+// toss. Wins are always taken.
+func pickAction(rng *rand.Rand, game *MahjongGame, sendInfos []MessageSendInfo) (ActionData, uint8, bool) {
+	type option struct {
+		action ActionData
+		player uint8
+	}
+	options := make([]option, 0)
+	for _, sendInfo := range sendInfos {
+		for _, event := range sendInfo.Events {
+			data, ok := event.Data.(PotentialActionEventData)
+			if !ok {
+				continue
+			}
+			if data.ActionType == RON || data.ActionType == TSUMO {
+				return data.ActionData, sendInfo.SendTo, true
+			}
+			options = append(options, option{data.ActionData, sendInfo.SendTo})
+		}
+	}
+	if len(options) == 0 {
+		return ActionData{}, 0, false
+	}
+
+	picked := options[rng.Intn(len(options))]
+	if picked.action.ActionType == TOSS {
+		tile := isolatedTile(game.Players[picked.player].ClosedHand)
+		if game.Players[picked.player].HandInRiichi {
+			tile = game.DrawnTile
+		}
+		picked.action.Data = TossData{TileToToss: tile}
+	}
+	return picked.action, picked.player, true
+}
+
+// Skips every pending action, which is what a player who does not
+// want to call does
+func skipPending(game *MahjongGame) error {
+	for len(game.PendingActions) != 0 {
+		pending := game.PendingActions[0]
+		_, err := game.HandleSkip(SkipData{ActionToSkip: pending.ActionData}, pending.fromPlayer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Plays a hand with random legal actions until it ends
+func playRandomHand(t *testing.T, rng *rand.Rand, game *MahjongGame) {
+	t.Helper()
+	sendInfos, shouldEnd := game.GetNextEvent()
+	for steps := 0; !shouldEnd; steps++ {
+		if steps > 1000 {
+			t.Fatal("Hand did not end")
+		}
+
+		action, player, ok := pickAction(rng, game, sendInfos)
+		if !ok || (action.ActionType != RON && rng.Intn(4) != 0 && len(game.PendingActions) != 0) {
+			if err := skipPending(game); err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := ActionDecode(game, action, player); err != nil {
+			t.Fatalf("%v for %+v from %d in state %d", err, action, player, game.GameState)
+		}
+		sendInfos, shouldEnd = game.GetNextEvent()
+	}
+}
+
+func TestRandomMatches(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 20 {
+		match := NewMatch(DefaultMatchRules())
+		for hands := 0; !match.Finished; hands++ {
+			if hands > 100 {
+				t.Fatal("Match did not end")
+			}
+			if _, err := match.StartNextHand(); err != nil {
+				t.Fatal(err)
+			}
+			playRandomHand(t, rng, &match.Game)
+
+			if _, err := match.Game.GetGameResults(); err != nil {
+				t.Fatal(err)
+			}
+			if err := match.FinishHand(); err != nil {
+				t.Fatal(err)
+			}
+
+			total := 1000 * int32(match.Round.RiichiSticks)
+			for _, points := range match.Round.Points {
+				total += points
+			}
+			if total != 4*match.Rules.StartingPoints {
+				t.Fatalf("Points are not conserved: %v with %d riichi sticks", match.Round.Points, match.Round.RiichiSticks)
+			}
+		}
+	}
+}
+
+// Starts a hand where player index and order are the same, and the
+// given tiles replace the dealt hands and the start of the live wall
+func newScenarioGame(t *testing.T, rules GameRules, hands [4][]Tile, wall ...Tile) *MahjongGame {
+	t.Helper()
+	game := &MahjongGame{Rules: rules}
+	round := NewRoundInfo(25000, Seed{})
+	round.Seats = []uint8{0, 1, 2, 3}
+	if _, err := game.StartNewGame(round); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx, hand := range hands {
+		game.Players[idx].FreshHand(hand)
+	}
+	copy(game.LiveWall, wall)
+	return game
+}
+
+func hasPotentialAction(sendInfos []MessageSendInfo, actionType ActionType, player uint8) bool {
+	for _, sendInfo := range sendInfos {
+		for _, event := range sendInfo.Events {
+			data, ok := event.Data.(PotentialActionEventData)
+			if ok && data.ActionType == actionType && sendInfo.SendTo == player {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func tiles(suit Tile, numbers ...Tile) []Tile {
+	result := make([]Tile, 0, len(numbers))
+	for _, number := range numbers {
+		result = append(result, suit+number-1)
+	}
+	return result
+}
+
+// A hand that is far from tenpai and holds no terminals or honours
+var filler = slices.Concat(tiles(Manzu, 2, 4, 6, 8), tiles(Pinzu, 2, 4, 6, 8), tiles(Souzu, 2, 4, 6, 8))
+
+func TestKyuushuKyuuhai(t *testing.T) {
+	orphans := slices.Concat(tiles(Manzu, 1, 9), tiles(Pinzu, 1, 9), tiles(Souzu, 1, 9),
+		[]Tile{EastTile, SouthTile, White}, tiles(Manzu, 2, 3, 4, 5))
+	hands := [4][]Tile{orphans, append(filler, Manzu+6), append(filler, Manzu+6), append(filler, Manzu+6)}
+
+	for _, enabled := range []bool{true, false} {
+		game := newScenarioGame(t, GameRules{KyuushuKyuuhai: enabled}, hands, Souzu+8)
+		sendInfos, _ := game.GetNextEvent()
+		if hasPotentialAction(sendInfos, KYUUSHU_KYUUHAI, 0) != enabled {
+			t.Fatalf("Kyuushu kyuuhai offered = %v with the rule set to %v", !enabled, enabled)
+		}
+
+		_, err := game.HandleKyuushuKyuuhai(KyuushuKyuuhaiData{}, 0)
+		if !enabled {
+			if err == nil {
+				t.Error("Expected kyuushu kyuuhai to be rejected when the rule is off")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, shouldEnd := game.GetNextEvent(); !shouldEnd {
+			t.Error("Expected the hand to end")
+		}
+		if game.Results.Type != KYUUSHU_KYUUHAI_RESULT || !game.DealerRepeats() {
+			t.Errorf("Got result %v, want a kyuushu kyuuhai with the dealer repeating", game.Results.Type)
+		}
+	}
+}
+
+func TestSuufonRenda(t *testing.T) {
+	hand := append(slices.Clone(filler), EastTile)
+	game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{hand, hand, hand, hand},
+		Manzu+8, Manzu+8, Manzu+8, Manzu+8)
+
+	shouldEnd := false
+	for player := range uint8(4) {
+		if _, shouldEnd = game.GetNextEvent(); shouldEnd {
+			t.Fatalf("Hand ended before player %d discarded", player)
+		}
+		if _, err := game.HandleToss(TossData{TileToToss: EastTile}, player); err != nil {
+			t.Fatal(err)
+		}
+		_, shouldEnd = game.GetNextEvent()
+	}
+
+	if !shouldEnd || game.Results == nil || game.Results.Type != SUUFON_RENDA_RESULT {
+		t.Fatalf("Expected suufon renda after four East discards, got %+v", game.Results)
+	}
+}
+
+func TestSanchahou(t *testing.T) {
+	// Every other player waits on the 5p with tanyao
+	waiting := slices.Concat(tiles(Manzu, 2, 3, 4, 6, 7, 8), tiles(Souzu, 2, 3, 4, 6, 7, 8), tiles(Pinzu, 5))
+	dealer := slices.Concat(tiles(Pinzu, 5), tiles(Manzu, 1, 9), tiles(Souzu, 1, 9), filler[:8])
+	hands := [4][]Tile{dealer, waiting, waiting, waiting}
+
+	tests := []struct {
+		name      string
+		sanchahou bool
+		want      ResultType
+	}{
+		{"sanchahou aborts", true, SANCHAHOU_RESULT},
+		{"closest player wins", false, WIN_RESULT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newScenarioGame(t, GameRules{Sanchahou: tt.sanchahou}, hands, Manzu+0)
+			game.GetNextEvent()
+			if _, err := game.HandleToss(TossData{TileToToss: Pinzu + 4}, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			sendInfos, _ := game.GetNextEvent()
+			shouldEnd := false
+			for player := range uint8(4) {
+				if player == 0 {
+					continue
+				}
+				if !hasPotentialAction(sendInfos, RON, player) {
+					t.Fatalf("Player %d was not offered ron", player)
+				}
+				if _, err := game.HandleRon(RonData{TileToRon: Pinzu + 4}, player); err != nil {
+					t.Fatal(err)
+				}
+				_, shouldEnd = game.GetNextEvent()
+				if player != 3 && shouldEnd {
+					t.Fatal("Rons were settled before every player responded")
+				}
+			}
+
+			if !shouldEnd || game.Results.Type != tt.want {
+				t.Fatalf("Got %+v, want result type %v", game.Results, tt.want)
+			}
+			if tt.want == WIN_RESULT && game.Results.WonBy != 1 {
+				t.Errorf("WonBy = %d, want the player after the discarder", game.Results.WonBy)
+			}
+		})
+	}
+}
+
+func TestClaimPriority(t *testing.T) {
+	dealer := slices.Concat(tiles(Pinzu, 5), tiles(Manzu, 1, 9), tiles(Souzu, 1, 9), filler[:8])
+	chii := append(slices.Clone(filler), Manzu+0)
+	pon := slices.Concat(tiles(Manzu, 2, 4, 6, 8), tiles(Souzu, 2, 4, 6, 8), tiles(Pinzu, 5, 5), []Tile{EastTile, SouthTile, WestTile})
+	ron := slices.Concat(tiles(Manzu, 2, 3, 4, 6, 7, 8), tiles(Souzu, 2, 3, 4, 6, 7, 8), tiles(Pinzu, 5))
+	hands := [4][]Tile{dealer, chii, pon, ron}
+
+	chiiAction := ActionData{ActionType: CHII, Data: ChiiData{TileToChii: Pinzu + 4, TilesInHand: [2]Tile{Pinzu + 3, Pinzu + 5}}}
+	ponAction := ActionData{ActionType: PON, Data: PonData{TileToPon: Pinzu + 4}}
+	ronAction := ActionData{ActionType: RON, Data: RonData{TileToRon: Pinzu + 4}}
+	skip := func(action ActionData) ActionData {
+		return ActionData{ActionType: SKIP, Data: SkipData{ActionToSkip: action}}
+	}
+
+	tests := []struct {
+		name      string
+		responses [3]ActionData // From players 1, 2 and 3
+		wantTurn  uint8
+		wantEnd   bool
+	}{
+		{"pon beats an earlier chii", [3]ActionData{chiiAction, ponAction, skip(ronAction)}, 2, false},
+		{"ron beats pon and chii", [3]ActionData{chiiAction, ponAction, ronAction}, 0, true},
+		{"chii when nobody else calls", [3]ActionData{chiiAction, skip(ponAction), skip(ronAction)}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newScenarioGame(t, DefaultGameRules(), hands, Manzu+0)
+			game.GetNextEvent()
+			if _, err := game.HandleToss(TossData{TileToToss: Pinzu + 4}, 0); err != nil {
+				t.Fatal(err)
+			}
+			game.GetNextEvent()
+
+			shouldEnd := false
+			for idx, response := range tt.responses {
+				player := uint8(idx + 1)
+				if _, err := ActionDecode(game, response, player); err != nil {
+					t.Fatalf("%+v from %d: %v", response, player, err)
+				}
+				if player != 3 && game.GameState != CURRENT_TURN_PLAYED {
+					t.Fatalf("Claims were settled before player %d responded", player+1)
+				}
+				_, shouldEnd = game.GetNextEvent()
+			}
+
+			if shouldEnd != tt.wantEnd {
+				t.Fatalf("shouldEnd = %v, want %v", shouldEnd, tt.wantEnd)
+			}
+			if tt.wantEnd {
+				if game.Results.Type != WIN_RESULT || game.Results.WonBy != 3 {
+					t.Errorf("Expected player 3 to win, got %+v", game.Results)
+				}
+				return
+			}
+			if game.currentPlayerIdx() != tt.wantTurn || game.GameState != CURRENT_TURN {
+				t.Errorf("Turn went to %d in state %d, want %d", game.currentPlayerIdx(), game.GameState, tt.wantTurn)
+			}
+		})
+	}
+}
+
+func TestCallKeepsRedFives(t *testing.T) {
+	red := (Pinzu + 4).SetRedTile()
+	dealer := slices.Concat(tiles(Pinzu, 5), tiles(Manzu, 1, 9), tiles(Souzu, 1, 9), filler[:8])
+	caller := slices.Concat([]Tile{red, Pinzu + 4}, tiles(Manzu, 2, 4, 6, 8), tiles(Souzu, 2, 4, 6, 8), []Tile{EastTile, SouthTile, WestTile})
+	other := append(slices.Clone(filler), NorthTile)
+	game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{dealer, caller, other, other}, Manzu+0)
+	game.GetNextEvent()
+	if _, err := game.HandleToss(TossData{TileToToss: Pinzu + 4}, 0); err != nil {
+		t.Fatal(err)
+	}
+	game.GetNextEvent()
+
+	if _, err := ActionDecode(game, ActionData{ActionType: PON, Data: PonData{TileToPon: Pinzu + 4}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	sendInfos, _ := game.GetNextEvent()
+
+	var pon *PonData
+	for _, sendInfo := range sendInfos {
+		for _, event := range sendInfo.Events {
+			if data, ok := event.Data.(PlayerActionEventData); ok && data.ActionType == PON {
+				ponData := data.Data.(PonData)
+				pon = &ponData
+			}
+		}
+	}
+	if pon == nil || pon.TilesInHand != [2]Tile{red, Pinzu + 4} {
+		t.Errorf("Pon event has %+v, want the red five taken from the hand", pon)
+	}
+	if pons := game.Players[1].Pons; len(pons) != 1 || pons[0] != red {
+		t.Errorf("Pons = %v, want the red five kept in the meld", pons)
+	}
+}
+
+func TestDoubleRon(t *testing.T) {
+	waiting := slices.Concat(tiles(Manzu, 2, 3, 4, 6, 7, 8), tiles(Souzu, 2, 3, 4, 6, 7, 8), tiles(Pinzu, 5))
+	dealer := slices.Concat(tiles(Pinzu, 5), tiles(Manzu, 1, 9), tiles(Souzu, 1, 9), filler[:8])
+	hands := [4][]Tile{dealer, waiting, waiting, waiting}
+
+	for _, doubleRon := range []bool{true, false} {
+		game := newScenarioGame(t, GameRules{DoubleRon: doubleRon}, hands, Manzu+0)
+		game.Honba = 1
+		game.GetNextEvent()
+		if _, err := game.HandleToss(TossData{TileToToss: Pinzu + 4}, 0); err != nil {
+			t.Fatal(err)
+		}
+		game.GetNextEvent()
+
+		// The player furthest from the discarder declares first
+		ron := RonData{TileToRon: Pinzu + 4}
+		if _, err := game.HandleRon(ron, 2); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := game.HandleRon(ron, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := game.HandleSkip(SkipData{ActionToSkip: ActionData{ActionType: RON, Data: ron}}, 3); err != nil {
+			t.Fatal(err)
+		}
+		if _, shouldEnd := game.GetNextEvent(); !shouldEnd {
+			t.Fatal("Expected the hand to end")
+		}
+
+		want := []uint8{1}
+		if doubleRon {
+			want = []uint8{1, 2}
+		}
+		if winners := game.Results.Winners(); !slices.Equal(winners, want) {
+			t.Errorf("DoubleRon = %v: winners = %v, want %v", doubleRon, winners, want)
+		}
+
+		// Only the closest winner is paid the honba
+		if doubleRon && game.Players[1].Points-game.Players[2].Points != 300 {
+			t.Errorf("Expected the honba to go to the closest winner, got %d and %d",
+				game.Players[1].Points, game.Players[2].Points)
+		}
+	}
+}
+
+func TestWallCommitment(t *testing.T) {
+	game := &MahjongGame{Rules: DefaultGameRules()}
+	setups, err := game.StartNewGame(NewRoundInfo(25000, SeedFromInt(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for idx, setup := range setups {
+		i := slices.IndexFunc(setup, func(s Setup) bool { return s.Type == WALL_HASH })
+		if i < 0 || setup[i].Data != game.WallHash {
+			t.Fatalf("Player %d was not sent the wall hash", idx)
+		}
+	}
+	if !game.RevealWall().Verify(game.WallHash) {
+		t.Error("The revealed wall does not match its hash")
+	}
+
+	other := &MahjongGame{Rules: DefaultGameRules()}
+	other.StartNewGame(NewRoundInfo(25000, SeedFromInt(7)))
+	if other.WallHash == game.WallHash {
+		t.Error("The same wall was hashed with the same salt twice")
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	hand := append(slices.Clone(filler), Manzu+6)
+	game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{hand, hand, hand, hand}, Souzu+8)
+	game.GetNextEvent() // Player 0 draws
+
+	tests := []struct {
+		name   string // description of this test case
+		action ActionData
+		player uint8
+		want   ErrorCode
+	}{
+		{"toss out of turn", ActionData{ActionType: TOSS, Data: TossData{TileToToss: Manzu + 6}}, 1, NOT_YOUR_TURN},
+		{"toss a tile not in hand", ActionData{ActionType: TOSS, Data: TossData{TileToToss: White}}, 0, TILE_NOT_IN_HAND},
+		{"tsumo on another tile", ActionData{ActionType: TSUMO, Data: TsumoData{TileToTsumo: Manzu + 6}}, 0, INVALID_TILE},
+		{"tsumo without yaku", ActionData{ActionType: TSUMO, Data: TsumoData{TileToTsumo: Souzu + 8}}, 0, NO_YAKU_TO_WIN},
+		{"riichi far from tenpai", ActionData{ActionType: RIICHI, Data: RiichiData{TileToRiichi: Souzu + 8}}, 0, NOT_TENPAI},
+		{"pon before a discard", ActionData{ActionType: PON, Data: PonData{TileToPon: Manzu + 6}}, 1, WRONG_STATE},
+		{"skip an action not offered", ActionData{ActionType: SKIP, Data: SkipData{
+			ActionToSkip: ActionData{ActionType: RON, Data: RonData{TileToRon: Manzu + 6}},
+		}}, 2, ACTION_NOT_ALLOWED},
+		{"data of another action", ActionData{ActionType: TOSS, Data: PonData{}}, 0, BAD_MESSAGE},
+		{"unknown action", ActionData{ActionType: ActionType(255)}, 0, BAD_MESSAGE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ActionDecode(game, tt.action, tt.player)
+			if got := CodeOf(err); got != tt.want {
+				t.Errorf("ActionDecode() = %v, want code %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoDaiminkanAfterLastKanDraw(t *testing.T) {
+	hand := append(slices.Clone(filler), Manzu+6)
+	triplet := slices.Concat([]Tile{EastTile, EastTile, EastTile}, filler[:10])
+	game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{append(slices.Clone(filler), EastTile), triplet, hand, hand}, Souzu+8)
+
+	game.GetNextEvent()
+	if _, err := game.HandleToss(TossData{TileToToss: EastTile}, 0); err != nil {
+		t.Fatal(err)
+	}
+	game.KansDrawn = uint8(len(game.KanDraw))
+	sendInfos, _ := game.GetNextEvent()
+	if hasPotentialAction(sendInfos, KAN, 1) {
+		t.Error("Daiminkan offered with no kan draws left")
+	}
+	if !hasPotentialAction(sendInfos, PON, 1) {
+		t.Error("Pon not offered")
+	}
+
+	if _, err := game.makeDaiminkan(KanData{TileToKan: EastTile}, 1); err == nil {
+		t.Error("Daiminkan made with no kan draws left")
+	}
+	if len(game.Players[1].ClosedHand) != 13 || len(game.Players[1].Kans) != 0 {
+		t.Errorf("Hand changed to %v with kans %v", game.Players[1].ClosedHand, game.Players[1].Kans)
+	}
+}
+
+func TestAnkanNotOffered(t *testing.T) {
+	quad := slices.Concat([]Tile{EastTile, EastTile, EastTile}, filler[:10])
+	hand := append(slices.Clone(filler), Manzu+6)
+
+	tests := []struct {
+		name  string // description of this test case
+		setup func(game *MahjongGame)
+	}{
+		{"no kan draws left", func(game *MahjongGame) { game.KansDrawn = uint8(len(game.KanDraw)) }},
+		{"hand in riichi", func(game *MahjongGame) { game.Players[0].HandInRiichi = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{quad, hand, hand, hand}, EastTile)
+			game.GetNextEvent() // Player 0 draws the fourth east
+			tt.setup(game)
+
+			_, err := game.HandleKan(KanData{TileToKan: EastTile}, 0)
+			if CodeOf(err) != ACTION_NOT_ALLOWED {
+				t.Errorf("HandleKan() = %v, want code %v", err, ACTION_NOT_ALLOWED)
+			}
+			if len(game.Players[0].ClosedHand) != 14 || len(game.Players[0].Ankans) != 0 {
+				t.Errorf("Hand changed to %v with ankans %v", game.Players[0].ClosedHand, game.Players[0].Ankans)
+			}
+		})
+	}
+}
+
+func TestChankan(t *testing.T) {
+	// Player 0 has a pon of east and draws the fourth one, which player 1
+	// waits on with no other yaku than robbing the kan
+	ponHand := slices.Concat(filler[:10], []Tile{EastTile, EastTile, EastTile})
+	waiting := slices.Concat(tiles(Manzu, 2, 3, 4, 6, 7, 8), tiles(Souzu, 2, 3, 4, 6, 7, 8), []Tile{EastTile})
+	hand := append(slices.Clone(filler), Manzu+6)
+	kan := KanData{TileToKan: EastTile, Added: true}
+
+	tests := []struct {
+		name string // description of this test case
+		ron  bool   // Whether player 1 robs the kan
+	}{
+		{"ron robs the kan", true},
+		{"kan made once the ron is skipped", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{ponHand, waiting, hand, hand}, EastTile)
+			player := &game.Players[0]
+			player.ClosedHand = player.ClosedHand[:10]
+			player.Pons = []Tile{EastTile}
+			player.HandOpen = true
+
+			sendInfos, _ := game.GetNextEvent() // Player 0 draws the fourth east
+			if !slices.ContainsFunc(game.getTurnActions(), func(action ActionData) bool {
+				return action == ActionData{ActionType: KAN, Data: kan}
+			}) {
+				t.Fatalf("Shouminkan not offered in %+v", sendInfos)
+			}
+			if _, err := game.HandleKan(kan, 0); err != nil {
+				t.Fatal(err)
+			}
+			if len(player.Kans) != 1 || len(player.Pons) != 0 || len(player.ClosedHand) != 10 {
+				t.Fatalf("Hand is %v with kans %v and pons %v", player.ClosedHand, player.Kans, player.Pons)
+			}
+
+			sendInfos, _ = game.GetNextEvent()
+			if !hasPotentialAction(sendInfos, RON, 1) {
+				t.Fatal("Ron on the added tile not offered")
+			}
+			if hasPotentialAction(sendInfos, PON, 2) || hasPotentialAction(sendInfos, KAN, 2) {
+				t.Error("Calls offered on the added tile")
+			}
+
+			ron := ActionData{ActionType: RON, Data: RonData{TileToRon: EastTile}}
+			if tt.ron {
+				_, err := game.HandleRon(RonData{TileToRon: EastTile}, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, shouldEnd := game.GetNextEvent(); !shouldEnd {
+					t.Fatal("Expected the hand to end")
+				}
+				if game.Results.WonBy != 1 || !game.Results.Result.Yakus.Has(CHANKAN_YAKU) {
+					t.Errorf("Expected player 1 to win with chankan, got %+v", game.Results)
+				}
+				return
+			}
+
+			if _, err := game.HandleSkip(SkipData{ActionToSkip: ron}, 1); err != nil {
+				t.Fatal(err)
+			}
+			game.GetNextEvent()
+			if game.GameState != CURRENT_TURN || game.currentPlayerIdx() != 0 || !game.RinshanDraw {
+				t.Errorf("Expected player 0 to draw the replacement tile, state %d turn %d", game.GameState, game.currentPlayerIdx())
+			}
+			if len(player.ClosedHand) != 11 || game.DoraRevealed != 2 {
+				t.Errorf("Hand is %v with %d dora revealed", player.ClosedHand, game.DoraRevealed)
+			}
+		})
+	}
+}
+
+func TestRedFivesRule(t *testing.T) {
+	tests := []struct {
+		name     string // description of this test case
+		redFives bool
+		want     int
+	}{
+		{"without red fives", false, 0},
+		{"with red fives", true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultGameRules()
+			rules.RedFives = tt.redFives
+			game := &MahjongGame{Rules: rules}
+			if _, err := game.StartNewGame(NewRoundInfo(25000, SeedFromInt(3))); err != nil {
+				t.Fatal(err)
+			}
+			red := 0
+			for _, tile := range game.Tiles {
+				if tile&RedTile != 0 {
+					red++
+				}
+			}
+			if red != tt.want {
+				t.Errorf("Wall has %d red fives, want %d", red, tt.want)
+			}
+		})
+	}
+}
+
+func TestRiichiAnkan(t *testing.T) {
+	// Waits on 1s and 4s with or without the kan of 1m
+	riichi := slices.Concat(tiles(Manzu, 1, 1, 1), tiles(Pinzu, 4, 5, 6), tiles(Souzu, 7, 8, 9, 2, 3), []Tile{White, White})
+	hand := append(slices.Clone(filler), Manzu+6)
+	game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{riichi, hand, hand, hand}, Manzu+0)
+	game.Players[0].HandInRiichi = true
+	game.GetNextEvent() // Player 0 draws the fourth 1m
+
+	kan := ActionData{ActionType: KAN, Data: KanData{TileToKan: Manzu + 0}}
+	if !slices.Contains(game.getTurnActions(), kan) {
+		t.Fatalf("Ankan that keeps the waits not offered in %+v", game.getTurnActions())
+	}
+	if _, err := game.HandleKan(KanData{TileToKan: Manzu + 0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Players[0].Ankans) != 1 || !game.Players[0].HandInRiichi {
+		t.Errorf("Ankans = %v, want the kan made in riichi", game.Players[0].Ankans)
+	}
+}
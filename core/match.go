@@ -0,0 +1,181 @@
+package core
+
+import (
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+
+	"slices"
+)
+
+type MatchLength uint8
+
+const (
+	TONPUUSEN MatchLength = iota // East round only
+	HANCHAN                      // East and South rounds
+)
+
+type MatchRules struct {
+	GameRules // The rules of every hand
+
+	Length         MatchLength
+	StartingPoints int32
+	// Points someone needs at the end of the last hand for the match
+	// to end. Otherwise the match goes into the next round wind, and
+	// ends as soon as someone reaches them.
+	TargetPoints int32
+}
+
+// A series of hands played by the same players, keeping track of the
+// round wind, dealer, honba and riichi sticks between hands
+type Match struct {
+	Rules MatchRules
+	Game  MahjongGame
+	// The hand being played, or the next hand to play once the
+	// current one has been finished
+	Round    RoundInfo
+	Finished bool
+	// Decides the seats and the tiles of every hand. Playing a match
+	// again from its seed deals the same tiles in every hand.
+	// The seed is kept secret, since it gives away the tiles of hands
+	// still to be played.
+	Seed Seed
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The round wind of the last hand without overtime
+func (match Match) lastWind() Wind {
+	if match.Rules.Length == TONPUUSEN {
+		return East
+	}
+	return South
+}
+
+func (match Match) anyoneReached(points int32) bool {
+	return slices.ContainsFunc(match.Round.Points, func(p int32) bool {
+		return p >= points
+	})
+}
+
+// Whether the match is over after the hand that was just played
+func (match Match) isOver(dealerRepeats bool) bool {
+	round := match.Round
+	if slices.ContainsFunc(round.Points, func(p int32) bool { return p < 0 }) {
+		return true
+	}
+
+	lastWind := match.lastWind()
+	lastHand := round.Kyoku == 3
+	reachedTarget := match.anyoneReached(match.Rules.TargetPoints)
+	switch {
+	case round.RoundWind < lastWind:
+		return false
+	case round.RoundWind == lastWind:
+		if !lastHand || !reachedTarget {
+			return false
+		}
+		if !dealerRepeats {
+			return true
+		}
+		// A dealer in first place can end the match instead of repeating
+		dealer := slices.Index(round.Seats, round.Kyoku)
+		return match.Placements()[0] == uint8(dealer) &&
+			round.Points[dealer] >= match.Rules.TargetPoints
+	default:
+		// Overtime lasts for at most one more round wind
+		return reachedTarget || (lastHand && !dealerRepeats)
+	}
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+func DefaultMatchRules() MatchRules {
+	return MatchRules{
+		GameRules:      DefaultGameRules(),
+		Length:         HANCHAN,
+		StartingPoints: 25000,
+		TargetPoints:   30000,
+	}
+}
+
+// A match with a random seed
+func NewMatch(rules MatchRules) Match {
+	return NewSeededMatch(rules, NewSeed())
+}
+
+func NewSeededMatch(rules MatchRules, seed Seed) Match {
+	return Match{
+		Rules: rules,
+		Round: NewRoundInfo(rules.StartingPoints, seed),
+		Seed:  seed,
+	}
+}
+
+// Starts the hand described by Round
+func (match *Match) StartNextHand() ([][]Setup, error) {
+	if match.Finished {
+		return nil, CodedError{Code: WRONG_STATE, Reason: "Match has finished"}
+	}
+	match.Game.Rules = match.Rules.GameRules
+	return match.Game.StartNewGame(match.Round)
+}
+
+// Carries the result of the finished hand over to the next one, and
+// decides whether the match is over
+func (match *Match) FinishHand() error {
+	game := &match.Game
+	if game.GameState != GAME_ENDED {
+		return CodedError{Code: WRONG_STATE, Reason: "Hand has not ended"}
+	}
+
+	round := &match.Round
+	for idx, player := range game.Players {
+		round.Points[idx] = player.Points
+	}
+	round.RiichiSticks = game.RiichiSticks
+	// Every hand gets its own seed derived from the match seed, so the
+	// seed of one hand does not give away the tiles of the next
+	round.Hand += 1
+	round.Seed = match.Seed.derive("hand", round.Hand)
+	round.Wall = nil
+	round.WallSalt = nil
+
+	dealerRepeats := game.DealerRepeats()
+	if game.Results != nil && game.Results.Type == WIN_RESULT && !dealerRepeats {
+		round.Honba = 0
+	} else {
+		round.Honba += 1
+	}
+
+	if match.isOver(dealerRepeats) {
+		// Leftover riichi sticks go to first place
+		first := match.Placements()[0]
+		round.Points[first] += 1000 * int32(round.RiichiSticks)
+		round.RiichiSticks = 0
+		match.Finished = true
+		return nil
+	}
+
+	if !dealerRepeats {
+		round.Kyoku += 1
+		if round.Kyoku == 4 {
+			round.Kyoku = 0
+			round.RoundWind += 1
+		}
+	}
+	return nil
+}
+
+// Player indices from first to last place. Ties go to whoever was
+// seated closer to the first dealer.
+func (match Match) Placements() []uint8 {
+	round := match.Round
+	placements := []uint8{0, 1, 2, 3}
+	slices.SortStableFunc(placements, func(a, b uint8) int {
+		if round.Points[a] != round.Points[b] {
+			return int(round.Points[b] - round.Points[a])
+		}
+		return int(round.Seats[a]) - int(round.Seats[b])
+	})
+	return placements
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"slices"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+)
+
+// Ends the current hand with the given points. A winner of -1 means
+// an exhaustive draw where nobody was tenpai.
+func endHand(t *testing.T, match *Match, winner int, points [4]int32) {
+	t.Helper()
+	if _, err := match.StartNextHand(); err != nil {
+		t.Fatal(err)
+	}
+
+	game := &match.Game
+	for idx := range game.Players {
+		game.Players[idx].Points = points[idx]
+	}
+	game.GameState = GAME_ENDED
+	game.Results = &GameResult{Type: WIN_RESULT, WonBy: uint8(max(winner, 0))}
+	if winner < 0 {
+		game.Results = &GameResult{
+			Type:   EXHAUSTIVE_DRAW_RESULT,
+			Tenpai: make([]bool, 4),
+		}
+	}
+
+	if err := match.FinishHand(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestMatch(length MatchLength) Match {
+	match := NewMatch(MatchRules{
+		Length:         length,
+		StartingPoints: 25000,
+		TargetPoints:   30000,
+	})
+	match.Round.Seats = []uint8{0, 1, 2, 3}
+	return match
+}
+
+func TestDealerRotation(t *testing.T) {
+	match := newTestMatch(HANCHAN)
+	even := [4]int32{25000, 25000, 25000, 25000}
+
+	endHand(t, &match, 0, even)
+	if match.Round.Kyoku != 0 || match.Round.Honba != 1 {
+		t.Errorf("Dealer win: got kyoku %d honba %d, want dealer repeat", match.Round.Kyoku, match.Round.Honba)
+	}
+	if match.Game.OrderToPlayer[0] != 0 {
+		t.Errorf("Expected player 0 to deal, got %d", match.Game.OrderToPlayer[0])
+	}
+
+	endHand(t, &match, 2, even)
+	if match.Round.Kyoku != 1 || match.Round.Honba != 0 {
+		t.Errorf("Non-dealer win: got kyoku %d honba %d", match.Round.Kyoku, match.Round.Honba)
+	}
+
+	_, err := match.StartNextHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Game.OrderToPlayer[0] != 1 || match.Game.Players[1].SeatWind != East {
+		t.Errorf("Expected player 1 to deal after rotation")
+	}
+	if match.Game.Players[0].SeatWind != North {
+		t.Errorf("Expected the first dealer to be North, got %v", match.Game.Players[0].SeatWind)
+	}
+
+	// A hand without a winner keeps the honba
+	endHand(t, &match, -1, even)
+	if match.Round.Kyoku != 2 || match.Round.Honba != 1 {
+		t.Errorf("Draw: got kyoku %d honba %d", match.Round.Kyoku, match.Round.Honba)
+	}
+
+	// A tenpai dealer repeats at a draw
+	if _, err := match.StartNextHand(); err != nil {
+		t.Fatal(err)
+	}
+	match.Game.GameState = GAME_ENDED
+	match.Game.Results = &GameResult{
+		Type:   EXHAUSTIVE_DRAW_RESULT,
+		Tenpai: []bool{false, false, true, false},
+	}
+	if err := match.FinishHand(); err != nil {
+		t.Fatal(err)
+	}
+	if match.Round.Kyoku != 2 || match.Round.Honba != 2 {
+		t.Errorf("Tenpai dealer: got kyoku %d honba %d", match.Round.Kyoku, match.Round.Honba)
+	}
+}
+
+func TestMatchLength(t *testing.T) {
+	even := [4]int32{25000, 25000, 25000, 25000}
+	ahead := [4]int32{31000, 23000, 23000, 23000}
+
+	tests := []struct {
+		name     string
+		length   MatchLength
+		hands    int
+		points   [4]int32
+		finished bool
+		wind     Wind
+	}{
+		{"Tonpuusen ends after East 4", TONPUUSEN, 4, ahead, true, East},
+		{"Tonpuusen goes into South without a winner", TONPUUSEN, 4, even, false, South},
+		{"Hanchan continues into South", HANCHAN, 4, ahead, false, South},
+		{"Hanchan ends after South 4", HANCHAN, 8, ahead, true, South},
+		{"Hanchan goes into West", HANCHAN, 8, even, false, West},
+		{"Overtime ends after West 4", HANCHAN, 12, even, true, West},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match := newTestMatch(test.length)
+			for range test.hands {
+				nonDealer := int(match.Round.Kyoku+1) % 4
+				endHand(t, &match, nonDealer, test.points)
+			}
+			if match.Finished != test.finished {
+				t.Errorf("Finished = %v, want %v", match.Finished, test.finished)
+			}
+			if match.Round.RoundWind != test.wind {
+				t.Errorf("RoundWind = %v, want %v", match.Round.RoundWind, test.wind)
+			}
+		})
+	}
+}
+
+func TestOvertimeSuddenDeath(t *testing.T) {
+	match := newTestMatch(TONPUUSEN)
+	even := [4]int32{25000, 25000, 25000, 25000}
+	for kyoku := range 4 {
+		endHand(t, &match, (kyoku+1)%4, even)
+	}
+
+	endHand(t, &match, 2, [4]int32{20000, 20000, 30000, 30000})
+	if !match.Finished {
+		t.Fatal("Expected the match to end once someone reached the target")
+	}
+	if placements := match.Placements(); !slices.Equal(placements, []uint8{2, 3, 0, 1}) {
+		t.Errorf("Placements = %v, ties should go to the earlier seat", placements)
+	}
+}
+
+func TestBustingEndsMatch(t *testing.T) {
+	match := newTestMatch(HANCHAN)
+	endHand(t, &match, 1, [4]int32{-1000, 51000, 25000, 25000})
+
+	if !match.Finished {
+		t.Fatal("Expected the match to end when a player goes below zero")
+	}
+
+	match = newTestMatch(HANCHAN)
+	_, err := match.StartNextHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match.Game.RiichiSticks = 2
+	match.Game.Players[0].Points = -500
+	match.Game.Players[1].Points = 50000
+	match.Game.GameState = GAME_ENDED
+	match.Game.Results = &GameResult{Type: WIN_RESULT, WonBy: 1}
+	if err := match.FinishHand(); err != nil {
+		t.Fatal(err)
+	}
+	if match.Round.Points[1] != 52000 || match.Round.RiichiSticks != 0 {
+		t.Errorf("Leftover riichi sticks should go to first place, got %v", match.Round.Points)
+	}
+}
+
+func TestSeededMatch(t *testing.T) {
+	// Deals every hand of a match from the seed, ending them with draws
+	deal := func(seed Seed) (seats []uint8, hands [][136]Tile) {
+		match := NewSeededMatch(DefaultMatchRules(), seed)
+		seats = slices.Clone(match.Round.Seats)
+		for hand := range uint32(3) {
+			if _, err := match.StartNextHand(); err != nil {
+				t.Fatal(err)
+			}
+			hands = append(hands, match.Game.Tiles)
+			if match.Game.Seed != match.Round.Seed {
+				t.Errorf("Game seed = %v, want %v", match.Game.Seed, match.Round.Seed)
+			}
+			// Hand seeds come from the match seed, not from the hand before
+			if want := seed.derive("hand", hand); match.Round.Seed != want {
+				t.Errorf("Seed of hand %d = %v, want %v", hand, match.Round.Seed, want)
+			}
+			match.Game.GameState = GAME_ENDED
+			match.Game.Results = &GameResult{Type: EXHAUSTIVE_DRAW_RESULT, Tenpai: make([]bool, 4)}
+			if err := match.FinishHand(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return seats, hands
+	}
+
+	seats, hands := deal(SeedFromInt(42))
+	againSeats, againHands := deal(SeedFromInt(42))
+	if !slices.Equal(seats, againSeats) || !slices.Equal(hands, againHands) {
+		t.Error("The same seed dealt different seats or tiles")
+	}
+	if hands[0] == hands[1] {
+		t.Error("Two hands of the match were dealt the same tiles")
+	}
+	if _, otherHands := deal(SeedFromInt(43)); otherHands[0] == hands[0] {
+		t.Error("Different seeds dealt the same tiles")
+	}
+}
+
+func TestInjectedWall(t *testing.T) {
+	wall := GetTileList()
+	slices.Reverse(wall)
+
+	match := NewSeededMatch(DefaultMatchRules(), Seed{})
+	match.Round.Wall = wall
+	if _, err := match.StartNextHand(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(match.Game.Tiles[:], wall) {
+		t.Error("The injected wall was not dealt as it is")
+	}
+
+	match.Round.Wall = wall[:100]
+	if _, err := match.StartNextHand(); err == nil {
+		t.Error("A wall without 136 tiles was accepted")
+	}
+}
@@ -1,23 +1,75 @@
 package core
 
-type AltMessageHandler struct{}
+import (
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+)
 
-// HandleGameEndEventType implements BoardEventHandler.
-func (a AltMessageHandler) HandleGameEndEventType(GameEndEventData) error {
-	panic("unimplemented")
+// Builds the version of a PARTIAL board event that everyone except the
+// player it is meant for receives, with the tiles only that player may
+// know replaced by Hidden
+type AltMessageHandler struct {
+	Event BoardEvent
+	// Whether the other players receive the event at all
+	Send bool
 }
 
-// HandleGameSetupEventType implements BoardEventHandler.
-func (a AltMessageHandler) HandleGameSetupEventType(GameSetupEventData) error {
-	panic("unimplemented")
+// ==================== PRIVATE FUNCTIONS ====================
+
+func (a *AltMessageHandler) set(eventType BoardEventType, data any) {
+	a.Event = BoardEvent{EventType: eventType, Data: data}
+	a.Send = true
+}
+
+func hideTiles(tiles []Tile) []Tile {
+	hidden := make([]Tile, len(tiles))
+	for idx := range hidden {
+		hidden[idx] = Hidden
+	}
+	return hidden
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// HandleGameEndEventType implements BoardEventHandler. The results of a
+// hand are shown to everyone.
+func (a *AltMessageHandler) HandleGameEndEventType(data GameEndEventData) error {
+	a.set(GameEndEventType, data)
+	return nil
+}
+
+// HandleMatchEndEventType implements BoardEventHandler.
+func (a *AltMessageHandler) HandleMatchEndEventType(data MatchEndEventData) error {
+	a.set(MatchEndEventType, data)
+	return nil
+}
+
+// HandleGameSetupEventType implements BoardEventHandler. The other
+// players learn how many tiles were dealt, but not which.
+func (a *AltMessageHandler) HandleGameSetupEventType(data GameSetupEventData) error {
+	setups := make([]Setup, 0, len(data.Setup))
+	for _, setup := range data.Setup {
+		if tiles, ok := setup.Data.([]Tile); ok && setup.Type == INITIAL_TILES {
+			setup.Data = hideTiles(tiles)
+		}
+		setups = append(setups, setup)
+	}
+	a.set(GameSetupEventType, GameSetupEventData{Setup: setups})
+	return nil
 }
 
-// HandlePlayerActionEventType implements BoardEventHandler.
-func (a AltMessageHandler) HandlePlayerActionEventType(PlayerActionEventData) error {
-	panic("unimplemented")
+// HandlePlayerActionEventType implements BoardEventHandler. The other
+// players see that a tile was drawn, but not which.
+func (a *AltMessageHandler) HandlePlayerActionEventType(data PlayerActionEventData) error {
+	if data.ActionType == DRAW {
+		data.Data = DrawData{DrawnTile: Hidden}
+	}
+	a.set(PlayerActionEventType, data)
+	return nil
 }
 
-// HandlePotentialActionEventType implements BoardEventHandler.
-func (a AltMessageHandler) HandlePotentialActionEventType(PotentialActionEventData) error {
-	panic("unimplemented")
+// HandlePotentialActionEventType implements BoardEventHandler. Only the
+// player who can take an action is told about it.
+func (a *AltMessageHandler) HandlePotentialActionEventType(PotentialActionEventData) error {
+	a.Send = false
+	return nil
 }
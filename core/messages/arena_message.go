@@ -22,6 +22,15 @@ const (
 	StartGameActionType
 	PlayerActionType
 	PlayerQuitActionType
+
+	// Sent from game (server) to player (client) when the player has to act
+	TimerEventType
+
+	// Sent from game (server) to player (client) with everything the player
+	// can currently see of the game, in response to SnapshotActionType
+	SnapshotEventType
+	// Sent from player (client) to game (server) to ask for a snapshot
+	SnapshotActionType
 )
 
 // ArenaMessage are messages that are sent between clients and server
@@ -44,6 +53,17 @@ type PlayerQuitEventData struct {
 
 type GameStartedEventData struct{}
 
+// How long the player has to act before the arena acts for them, in
+// milliseconds. Once ActionTime runs out, the time bank is used up.
+type TimerEventData struct {
+	ActionTime int64 `json:"action_time"`
+	TimeBank   int64 `json:"time_bank"`
+}
+
+type SnapshotEventData struct {
+	View PlayerView `json:"view"`
+}
+
 type ArenaBoardEventData struct {
 	BoardEvent // For handling generic games, this should be replaced
 }
@@ -54,10 +74,13 @@ type ArenaActionHandler[Input any] interface {
 	HandleStartGameAction(StartGameActionData, Input) error
 	HandlePlayerAction(PlayerActionData, Input) error
 	HandlePlayerQuitAction(PlayerQuitActionData, Input) error
+	HandleSnapshotAction(SnapshotActionData, Input) error
 }
 
 type StartGameActionData struct{}
 
+type SnapshotActionData struct{}
+
 type PlayerQuitActionData struct{}
 
 type PlayerActionData struct {
@@ -86,6 +109,27 @@ func (msg *ArenaMessage) UnmarshalJSON(rawData []byte) error {
 			return err
 		}
 		msg.Data = data
+	case TimerEventType:
+		data := TimerEventData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case SnapshotEventType:
+		data := SnapshotEventData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case SnapshotActionType:
+		data := SnapshotActionData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
 	case GameStartedEventType:
 		data := GameStartedEventData{}
 		err := json.Unmarshal(raw.Data, &data)
@@ -129,7 +173,7 @@ func (msg *ArenaMessage) UnmarshalJSON(rawData []byte) error {
 		}
 		msg.Data = data
 	default:
-		return fmt.Errorf("unexpected core.ArenaMessageType: %#v", raw.MessageType)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.ArenaMessageType: %#v", raw.MessageType)}
 	}
 
 	return nil
@@ -140,22 +184,28 @@ func ArenaActionDispatch[E any](handler ArenaActionHandler[E], msg ArenaMessage,
 	case PlayerActionType:
 		message, ok := msg.Data.(PlayerActionData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandlePlayerAction(message, input)
 	case PlayerQuitActionType:
 		message, ok := msg.Data.(PlayerQuitActionData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandlePlayerQuitAction(message, input)
 	case StartGameActionType:
 		message, ok := msg.Data.(StartGameActionData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleStartGameAction(message, input)
+	case SnapshotActionType:
+		message, ok := msg.Data.(SnapshotActionData)
+		if !ok {
+			return CodedError{Code: BAD_MESSAGE}
+		}
+		return handler.HandleSnapshotAction(message, input)
 	default:
-		return fmt.Errorf("unexpected core.ArenaMessageType: %#v", msg.MessageType)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.ArenaMessageType: %#v", msg.MessageType)}
 	}
 }
@@ -19,6 +19,8 @@ const (
 	GameSetupEventType
 	// A game end event
 	GameEndEventType
+	// The end of the last hand of a match
+	MatchEndEventType
 )
 
 type BoardEvent struct {
@@ -41,6 +43,13 @@ type GameSetupEventData struct {
 
 type GameEndEventData struct {
 	GameResult GameResult `json:"result"`
+	// The wall of the hand, to check against the WALL_HASH setup
+	Wall WallReveal `json:"wall"`
+}
+
+type MatchEndEventData struct {
+	Points     []int32 `json:"points"`     // Final points, indexed by player
+	Placements []uint8 `json:"placements"` // Player indices from first to last
 }
 
 type BoardEventHandler interface {
@@ -48,6 +57,7 @@ type BoardEventHandler interface {
 	HandlePotentialActionEventType(PotentialActionEventData) error
 	HandleGameSetupEventType(GameSetupEventData) error
 	HandleGameEndEventType(GameEndEventData) error
+	HandleMatchEndEventType(MatchEndEventData) error
 }
 
 func (msg *BoardEvent) UnmarshalJSON(rawData []byte) error {
@@ -68,6 +78,12 @@ func (msg *BoardEvent) UnmarshalJSON(rawData []byte) error {
 			return err
 		}
 		msg.Data = data
+	case MatchEndEventType:
+		data := MatchEndEventData{}
+		if err := json.Unmarshal(raw.Data, &data); err != nil {
+			return err
+		}
+		msg.Data = data
 	case GameSetupEventType:
 		data := GameSetupEventData{}
 		if err := json.Unmarshal(raw.Data, &data); err != nil {
@@ -87,8 +103,24 @@ func (msg *BoardEvent) UnmarshalJSON(rawData []byte) error {
 		}
 		msg.Data = data
 	default:
-		return fmt.Errorf("unexpected core.BoardEventType: %#v", raw.MessageType)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.BoardEventType: %#v", raw.MessageType)}
+	}
+	return nil
+}
+
+// Decodes FromPlayer along with the embedded ActionData, whose own
+// UnmarshalJSON would otherwise decode the whole event and leave it out
+func (msg *PlayerActionEventData) UnmarshalJSON(rawData []byte) error {
+	var raw struct {
+		FromPlayer uint8 `json:"from_player"`
+	}
+	if err := json.Unmarshal(rawData, &raw); err != nil {
+		return err
 	}
+	if err := json.Unmarshal(rawData, &msg.ActionData); err != nil {
+		return err
+	}
+	msg.FromPlayer = raw.FromPlayer
 	return nil
 }
 
@@ -97,28 +129,34 @@ func BoardEventDispatch(handler BoardEventHandler, event BoardEvent) (err error)
 	case GameEndEventType:
 		message, ok := event.Data.(GameEndEventData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleGameEndEventType(message)
+	case MatchEndEventType:
+		message, ok := event.Data.(MatchEndEventData)
+		if !ok {
+			return CodedError{Code: BAD_MESSAGE}
+		}
+		return handler.HandleMatchEndEventType(message)
 	case GameSetupEventType:
 		message, ok := event.Data.(GameSetupEventData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleGameSetupEventType(message)
 	case PlayerActionEventType:
 		message, ok := event.Data.(PlayerActionEventData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandlePlayerActionEventType(message)
 	case PotentialActionEventType:
 		message, ok := event.Data.(PotentialActionEventData)
 		if !ok {
-			return BadMessage{}
+			return CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandlePotentialActionEventType(message)
 	default:
-		return fmt.Errorf("unexpected core.BoardEventType: %#v", event.EventType)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected core.BoardEventType: %#v", event.EventType)}
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	"github.com/google/uuid"
 )
 
 type MessageType uint8
@@ -26,6 +27,17 @@ const (
 	ListArenasActionType
 	CreateArenaActionType
 	ArenaInfoActionType
+
+	// Sent in response to InitialMessageActionType
+	InitialMessageResponseType
+
+	// Steps through the replay of a finished match
+	ReplayActionType
+	ReplayResponseType
+
+	// Sent in response to ServerArenaActionType, once the arena took or
+	// turned down the action
+	ArenaActionResponseType
 )
 
 type Message struct {
@@ -35,7 +47,8 @@ type Message struct {
 }
 
 type AgentInfo struct {
-	Name string `json:"name"`
+	Name string    `json:"name"`
+	ID   uuid.UUID `json:"id"`
 }
 
 // ==================== EVENTS ====================
@@ -47,8 +60,23 @@ type ServerArenaMessageEventData struct {
 // ==================== RESPONSES ====================
 
 type GenericResponseData struct {
-	Success    bool   `json:"success"`
-	FailReason string `json:"fail_reason"`
+	Success    bool      `json:"success"`
+	Code       ErrorCode `json:"code"` // Why the request failed
+	FailReason string    `json:"fail_reason"`
+}
+
+type ArenaActionResponseData struct {
+	Success    bool      `json:"success"`
+	Code       ErrorCode `json:"code"` // Why the action failed
+	FailReason string    `json:"fail_reason"`
+}
+
+type InitialMessageResponseData struct {
+	Success bool `json:"success"`
+	// Identifies the client when it connects again
+	SessionToken string `json:"session_token"`
+	// Whether the client got back the arena seat held for its session
+	Resumed bool `json:"resumed"`
 }
 
 type ListArenasResponseData struct {
@@ -62,6 +90,18 @@ type ArenaInfoResponseData struct {
 	Agents      []AgentInfo `json:"agents"`
 	GameStarted bool        `json:"game_started"`
 	DateCreated time.Time   `json:"date_created"`
+	// The log of the current or last match, which can be replayed once
+	// the match has finished
+	GameID uuid.UUID `json:"game_id"`
+}
+
+type ReplayResponseData struct {
+	Success  bool `json:"success"`
+	Position int  `json:"position"`
+	Length   int  `json:"length"` // The number of steps in the replay
+	// The events of the step when moving one step forward, otherwise
+	// snapshots of the game
+	Messages []ArenaMessage `json:"messages"`
 }
 
 // ==================== ACTIONS ====================
@@ -73,14 +113,21 @@ type ServerActionHandler[Return any] interface {
 	HandleListArenas(ListArenasActionData) (Return, error)
 	HandleCreateArena(CreateArenaActionData) (Return, error)
 	HandleGetArenaInfo(ArenaInfoActionData) (Return, error)
+	HandleReplay(ReplayActionData) (Return, error)
 }
 
 type InitialMessageActionData struct {
 	Name string `json:"name"`
+	// The session of an earlier connection to resume, if any
+	SessionToken string `json:"session_token"`
 }
 
 type JoinArenaActionData struct {
 	ArenaName string `json:"arena_name"`
+	// Whether to watch the game instead of playing in it
+	Spectate bool          `json:"spectate"`
+	View     SpectatorView `json:"view"`
+	Player   uint8         `json:"player"` // The player watched with PLAYER_VIEW
 }
 
 type ServerArenaActionData struct {
@@ -95,6 +142,14 @@ type CreateArenaActionData struct {
 
 type ArenaInfoActionData struct{}
 
+type ReplayActionData struct {
+	GameID   uuid.UUID `json:"game_id"`
+	Position int       `json:"position"` // The step to move the replay to
+	// What to see of the game, like a spectator
+	View   SpectatorView `json:"view"`
+	Player uint8         `json:"player"`
+}
+
 // ==================== DECODE AND DISPATCH ====================
 
 func (msg *Message) UnmarshalJSON(rawData []byte) error {
@@ -154,8 +209,66 @@ func (msg *Message) UnmarshalJSON(rawData []byte) error {
 			return err
 		}
 		msg.Data = data
+	case ReplayActionType:
+		data := ReplayActionData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+
+	// Sent from server to client
+	case ServerArenaEventType:
+		data := ServerArenaMessageEventData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case GenericResponseType:
+		data := GenericResponseData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case ListArenasResponseType:
+		data := ListArenasResponseData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case ArenaInfoResponseType:
+		data := ArenaInfoResponseData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case InitialMessageResponseType:
+		data := InitialMessageResponseData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case ReplayResponseType:
+		data := ReplayResponseData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+	case ArenaActionResponseType:
+		data := ArenaActionResponseData{}
+		err := json.Unmarshal(raw.Data, &data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
 	default:
-		return fmt.Errorf("unexpected web.MessageType: %#v during unmarshalling", raw.MessageType)
+		return CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected web.MessageType: %#v during unmarshalling", raw.MessageType)}
 	}
 
 	return nil
@@ -166,41 +279,47 @@ func ServerActionDispatch[Return any](handler ServerActionHandler[Return], messa
 	case InitialMessageActionType:
 		initialMessageReturn, ok := message.Data.(InitialMessageActionData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleInitialMessage(initialMessageReturn)
 	case ServerArenaActionType:
 		serverArenaAction, ok := message.Data.(ServerArenaActionData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleServerArena(serverArenaAction)
 	case ListArenasActionType:
 		data, ok := message.Data.(ListArenasActionData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleListArenas(data)
 	case CreateArenaActionType:
 		data, ok := message.Data.(CreateArenaActionData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleCreateArena(data)
 	case JoinArenaActionType:
 		data, ok := message.Data.(JoinArenaActionData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleJoinArena(data)
 	case ArenaInfoActionType:
 		data, ok := message.Data.(ArenaInfoActionData)
 		if !ok {
-			return ret, BadMessage{}
+			return ret, CodedError{Code: BAD_MESSAGE}
 		}
 		return handler.HandleGetArenaInfo(data)
+	case ReplayActionType:
+		data, ok := message.Data.(ReplayActionData)
+		if !ok {
+			return ret, CodedError{Code: BAD_MESSAGE}
+		}
+		return handler.HandleReplay(data)
 	default:
 	}
 
-	return ret, fmt.Errorf("unexpected web.MessageType: %#v during dispatch", message.MessageType)
+	return ret, CodedError{Code: BAD_MESSAGE, Reason: fmt.Sprintf("unexpected web.MessageType: %#v during dispatch", message.MessageType)}
 }
@@ -8,3 +8,12 @@ const (
 	EXCLUDE
 	GLOBAL
 )
+
+// What a spectator gets to see of a game
+type SpectatorView uint8
+
+const (
+	PUBLIC_VIEW     SpectatorView = iota // Only what every player can see
+	PLAYER_VIEW                          // Everything one player can see
+	OMNISCIENT_VIEW                      // Everything, after a delay
+)
@@ -0,0 +1,631 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/google/uuid"
+)
+
+// A seat played by a bot process that speaks the mjai protocol over its
+// stdin and stdout: every event is written as a line of JSON, and the
+// bot answers each one with a line of its own, {"type":"none"} when it
+// has nothing to do. Its answers to the events it can act on are taken
+// as its actions.
+type MjaiAgent struct {
+	Name string
+	ID   uuid.UUID
+
+	arena  *Arena
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	encode *json.Encoder
+	decode *json.Decoder
+	queue  mjaiQueue
+
+	// Only used with the arena locked
+	translator mjaiTranslator
+	last       *mjaiItem // The last event queued
+	decision   *mjaiItem // The last event the seat could act on
+}
+
+// An event in the mjai protocol
+type mjaiEvent map[string]any
+
+// What a bot answers an event with
+type mjaiResponse struct {
+	Type     string   `json:"type"`
+	Pai      string   `json:"pai"`
+	Consumed []string `json:"consumed"`
+}
+
+type mjaiItem struct {
+	event mjaiEvent
+	// What the seat can do once the event was sent, set with the arena
+	// locked
+	actions []ActionData
+}
+
+// The events waiting to be written to the bot, so that sending them
+// never blocks the arena
+type mjaiQueue struct {
+	items  []*mjaiItem
+	closed bool
+	cond   *sync.Cond
+}
+
+// Turns the board events a seat receives into mjai events. The bot is
+// told about the seats by their mjai id, which is their order in the
+// first hand of the match.
+type mjaiTranslator struct {
+	player  uint8   // The player index of the seat
+	ids     []uint8 // The mjai id of every player index
+	started bool    // Whether start_game was sent for the match
+	drawn   Tile    // The tile the seat drew last
+	dora    int     // How many dora indicators the bot was told about
+	// The id of the player whose discard can be claimed, -1 if none
+	discarder int
+	// The id of the player whose riichi stands once nobody rons the
+	// discard, -1 if none
+	reaching int
+	// Whether the seat declared the call that is being made. The seat is
+	// told about its declaration before the call is made.
+	declared bool
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The mjai name of a tile, e.g. 5m, 5mr for a red five, E or P
+func mjaiTile(tile Tile) string {
+	if tile == Hidden {
+		return "?"
+	}
+	kind := tile.ClearRedOrDora()
+	switch {
+	case kind.IsSuited():
+		name := fmt.Sprintf("%d%c", kind.GetTileNumber()+1, "mps"[kind/16])
+		if tile&RedTile != 0 {
+			name += "r"
+		}
+		return name
+	case kind == White:
+		return "P"
+	case kind == Green:
+		return "F"
+	case kind == Red:
+		return "C"
+	default:
+		return string("ESWN"[kind-EastTile])
+	}
+}
+
+func mjaiTiles(tiles []Tile) []string {
+	names := make([]string, 0, len(tiles))
+	for _, tile := range tiles {
+		names = append(names, mjaiTile(tile))
+	}
+	return names
+}
+
+func parseMjaiTile(name string) (Tile, error) {
+	for _, kind := range GetTileKinds() {
+		if mjaiTile(kind) == name {
+			return kind, nil
+		}
+		if kind.IsSuited() && mjaiTile(kind.SetRedTile()) == name {
+			return kind.SetRedTile(), nil
+		}
+	}
+	return Invalid, fmt.Errorf("Unknown mjai tile %q", name)
+}
+
+func newMjaiQueue() mjaiQueue {
+	return mjaiQueue{cond: sync.NewCond(&sync.Mutex{})}
+}
+
+func (queue *mjaiQueue) push(item *mjaiItem) {
+	queue.cond.L.Lock()
+	defer queue.cond.L.Unlock()
+	queue.items = append(queue.items, item)
+	queue.cond.Signal()
+}
+
+// Waits for the next event, and returns false once the queue is closed
+func (queue *mjaiQueue) pop() (*mjaiItem, bool) {
+	queue.cond.L.Lock()
+	defer queue.cond.L.Unlock()
+	for len(queue.items) == 0 && !queue.closed {
+		queue.cond.Wait()
+	}
+	if queue.closed {
+		return nil, false
+	}
+	item := queue.items[0]
+	queue.items = queue.items[1:]
+	return item, true
+}
+
+func (queue *mjaiQueue) close() {
+	queue.cond.L.Lock()
+	defer queue.cond.L.Unlock()
+	queue.closed = true
+	queue.cond.Broadcast()
+}
+
+// The riichi declared with the last discard stands, since the game
+// moved on without anyone ronning it
+func (t *mjaiTranslator) acceptReach() []mjaiEvent {
+	if t.reaching < 0 {
+		return nil
+	}
+	event := mjaiEvent{"type": "reach_accepted", "actor": t.reaching}
+	t.reaching = -1
+	return []mjaiEvent{event}
+}
+
+func (t *mjaiTranslator) startHand(data GameSetupEventData) []mjaiEvent {
+	var hand []Tile
+	var order []uint8
+	var points [4]int32
+	var wind Wind
+	var kyoku, honba, sticks uint8
+	var dora Tile
+	for _, setup := range data.Setup {
+		switch setup.Type {
+		case INITIAL_TILES:
+			hand = setup.Data.([]Tile)
+		case DORA:
+			dora = setup.Data.(Tile)
+		case STARTING_POINTS:
+			points = setup.Data.([4]int32)
+		case PLAYER_NUMBER:
+			t.player = setup.Data.(uint8)
+		case PLAYER_ORDER:
+			order = setup.Data.([]uint8)
+		case ROUND_WIND:
+			wind = setup.Data.(Wind)
+		case ROUND_NUMBER:
+			kyoku = setup.Data.(uint8)
+		case HONBA:
+			honba = setup.Data.(uint8)
+		case RIICHI_STICKS:
+			sticks = setup.Data.(uint8)
+		}
+	}
+
+	t.ids = make([]uint8, len(order))
+	scores := make([]int32, len(order))
+	tehais := make([][]string, len(order))
+	for player := range order {
+		id := (order[player] + kyoku) % 4
+		t.ids[player] = id
+		scores[id] = points[player]
+		tehais[id] = slices.Repeat([]string{"?"}, 13)
+	}
+	tehais[t.ids[t.player]] = mjaiTiles(hand)
+
+	t.drawn = Invalid
+	t.dora = 1
+	t.discarder = -1
+	t.reaching = -1
+	t.declared = false
+
+	events := make([]mjaiEvent, 0, 2)
+	if !t.started {
+		t.started = true
+		events = append(events, mjaiEvent{"type": "start_game", "id": t.ids[t.player]})
+	}
+	return append(events, mjaiEvent{
+		"type":        "start_kyoku",
+		"bakaze":      mjaiTile(Tile(wind)),
+		"kyoku":       kyoku + 1,
+		"honba":       honba,
+		"kyotaku":     sticks,
+		"oya":         kyoku,
+		"dora_marker": mjaiTile(dora),
+		"scores":      scores,
+		"tehais":      tehais,
+	})
+}
+
+// Whether an event of the seat is only its declaration of a call, which
+// the bot is not told about
+func (t *mjaiTranslator) declaration(player uint8) bool {
+	if player != t.player {
+		t.declared = false
+		return false
+	}
+	t.declared = !t.declared
+	return t.declared
+}
+
+func (t *mjaiTranslator) playerAction(data PlayerActionEventData) []mjaiEvent {
+	player := data.FromPlayer
+	actor := t.ids[player]
+
+	switch data.ActionType {
+	case DRAW:
+		tile := data.Data.(DrawData).DrawnTile
+		if player == t.player {
+			t.drawn = tile
+		}
+		t.discarder = -1
+		return append(t.acceptReach(), mjaiEvent{"type": "tsumo", "actor": actor, "pai": mjaiTile(tile)})
+
+	case TOSS, RIICHI:
+		events := make([]mjaiEvent, 0, 2)
+		var tile Tile
+		if data.ActionType == RIICHI {
+			tile = data.Data.(RiichiData).TileToRiichi
+			t.reaching = int(actor)
+			// The bot of the seat already declared its riichi
+			if player != t.player {
+				events = append(events, mjaiEvent{"type": "reach", "actor": actor})
+			}
+		} else {
+			tile = data.Data.(TossData).TileToToss
+		}
+		t.discarder = int(actor)
+		// Only the seat knows which tile it drew
+		tsumogiri := player == t.player && tile == t.drawn
+		return append(events, mjaiEvent{"type": "dahai", "actor": actor, "pai": mjaiTile(tile), "tsumogiri": tsumogiri})
+
+	case CHII:
+		if t.declaration(player) {
+			return nil
+		}
+		chii := data.Data.(ChiiData)
+		return append(t.acceptReach(), mjaiEvent{
+			"type":     "chi",
+			"actor":    actor,
+			"target":   t.discarder,
+			"pai":      mjaiTile(chii.TileToChii),
+			"consumed": mjaiTiles(chii.TilesInHand[:]),
+		})
+
+	case PON:
+		if t.declaration(player) {
+			return nil
+		}
+		pon := data.Data.(PonData)
+		return append(t.acceptReach(), mjaiEvent{
+			"type":     "pon",
+			"actor":    actor,
+			"target":   t.discarder,
+			"pai":      mjaiTile(pon.TileToPon),
+			"consumed": mjaiTiles(pon.TilesInHand[:]),
+		})
+
+	case KAN:
+		kan := data.Data.(KanData)
+		if kan.Added {
+			return []mjaiEvent{{
+				"type":     "kakan",
+				"actor":    actor,
+				"pai":      mjaiTile(kan.TileToKan),
+				"consumed": mjaiTiles(kan.TilesInHand[:]),
+			}}
+		}
+		if t.discarder < 0 {
+			consumed := mjaiTiles(append(kan.TilesInHand[:], kan.TileToKan))
+			return []mjaiEvent{{"type": "ankan", "actor": actor, "consumed": consumed}}
+		}
+		if t.declaration(player) {
+			return nil
+		}
+		return append(t.acceptReach(), mjaiEvent{
+			"type":     "daiminkan",
+			"actor":    actor,
+			"target":   t.discarder,
+			"pai":      mjaiTile(kan.TileToKan),
+			"consumed": mjaiTiles(kan.TilesInHand[:]),
+		})
+
+	default:
+		// Wins are told along with the result, and skips are not told
+		return nil
+	}
+}
+
+func (t *mjaiTranslator) endHand(data GameEndEventData) []mjaiEvent {
+	result := data.GameResult
+	if result.Type != WIN_RESULT {
+		return append(t.acceptReach(), mjaiEvent{"type": "ryukyoku"}, mjaiEvent{"type": "end_kyoku"})
+	}
+
+	events := make([]mjaiEvent, 0, 2)
+	for _, win := range append([]GameResult{result}, result.OtherWins...) {
+		actor := t.ids[win.WonBy]
+		target := actor
+		if win.Result.WonByRon {
+			target = t.ids[win.PointsTransfers[0].From]
+		}
+		events = append(events, mjaiEvent{
+			"type":   "hora",
+			"actor":  actor,
+			"target": target,
+			"pai":    mjaiTile(win.Result.WinningTile),
+		})
+	}
+	return append(events, mjaiEvent{"type": "end_kyoku"})
+}
+
+// The mjai events for a board event the seat received
+func (t *mjaiTranslator) translate(event BoardEvent) []mjaiEvent {
+	switch data := event.Data.(type) {
+	case GameSetupEventData:
+		return t.startHand(data)
+	case PlayerActionEventData:
+		return t.playerAction(data)
+	case GameEndEventData:
+		return t.endHand(data)
+	case MatchEndEventData:
+		t.started = false
+		return []mjaiEvent{{"type": "end_game"}}
+	default:
+		return nil
+	}
+}
+
+// Tells the bot about the dora indicators revealed by kans. The game
+// does not send them out, so they are taken from the game itself.
+func (t *mjaiTranslator) revealDora(indicators []Tile) []mjaiEvent {
+	events := make([]mjaiEvent, 0)
+	for ; t.dora < len(indicators); t.dora++ {
+		events = append(events, mjaiEvent{"type": "dora", "dora_marker": mjaiTile(indicators[t.dora])})
+	}
+	return events
+}
+
+// The pending action of the given type that the check accepts
+func findPending(pending []ActionData, actionType ActionType, check func(ActionData) bool) (ActionData, bool) {
+	idx := slices.IndexFunc(pending, func(action ActionData) bool {
+		return action.ActionType == actionType && (check == nil || check(action))
+	})
+	if idx < 0 {
+		return ActionData{}, false
+	}
+	return pending[idx], true
+}
+
+// The actions the bot takes with its response, out of the ones the
+// seat has. A riichi is declared with a reach response, and the discard
+// that follows it.
+func mjaiActions(response mjaiResponse, pending []ActionData, riichi bool) ([]ActionData, error) {
+	if response.Type == "none" {
+		if _, ok := findPending(pending, TOSS, nil); ok {
+			return nil, errors.New("Bot did not discard")
+		}
+		skips := make([]ActionData, 0, len(pending))
+		for _, action := range pending {
+			skips = append(skips, ActionData{ActionType: SKIP, Data: SkipData{ActionToSkip: action}})
+		}
+		return skips, nil
+	}
+
+	pai := Invalid
+	if response.Pai != "" {
+		tile, err := parseMjaiTile(response.Pai)
+		if err != nil {
+			return nil, err
+		}
+		pai = tile
+	}
+	consumed := make([]Tile, 0, len(response.Consumed))
+	for _, name := range response.Consumed {
+		tile, err := parseMjaiTile(name)
+		if err != nil {
+			return nil, err
+		}
+		consumed = append(consumed, tile.ClearRedOrDora())
+	}
+
+	var action ActionData
+	found := false
+	switch response.Type {
+	case "dahai":
+		if riichi {
+			action, found = findPending(pending, RIICHI, func(action ActionData) bool {
+				return action.Data.(RiichiData).TileToRiichi.ClearRedOrDora() == pai.ClearRedOrDora()
+			})
+		} else if _, found = findPending(pending, TOSS, nil); found {
+			action = ActionData{ActionType: TOSS, Data: TossData{TileToToss: pai}}
+		}
+	case "hora":
+		if action, found = findPending(pending, TSUMO, nil); !found {
+			action, found = findPending(pending, RON, nil)
+		}
+	case "pon":
+		action, found = findPending(pending, PON, nil)
+	case "daiminkan":
+		action, found = findPending(pending, KAN, nil)
+	case "ankan":
+		action, found = findPending(pending, KAN, func(action ActionData) bool {
+			kan := action.Data.(KanData)
+			return !kan.Added && slices.Contains(consumed, kan.TileToKan)
+		})
+	case "kakan":
+		action, found = findPending(pending, KAN, func(action ActionData) bool {
+			kan := action.Data.(KanData)
+			return kan.Added && kan.TileToKan.SameKind(pai)
+		})
+	case "chi":
+		action, found = findPending(pending, CHII, func(action ActionData) bool {
+			hand := action.Data.(ChiiData).TilesInHand
+			return len(consumed) == 2 &&
+				slices.Contains(consumed, hand[0].ClearRedOrDora()) &&
+				slices.Contains(consumed, hand[1].ClearRedOrDora())
+		})
+	case "ryukyoku":
+		action, found = findPending(pending, KYUUSHU_KYUUHAI, nil)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("Bot cannot %s now", response.Type)
+	}
+	return []ActionData{action}, nil
+}
+
+// Writes an event to the bot, and reads its response
+func (agent *MjaiAgent) exchange(event mjaiEvent) (mjaiResponse, error) {
+	if err := agent.encode.Encode(event); err != nil {
+		return mjaiResponse{}, err
+	}
+	var response mjaiResponse
+	err := agent.decode.Decode(&response)
+	return response, err
+}
+
+// Takes the actions the bot responded with, if the seat can still act
+// on the event
+func (agent *MjaiAgent) act(item *mjaiItem, response mjaiResponse) error {
+	agent.arena.Lock()
+	current := item == agent.decision
+	pending := item.actions
+	player := agent.translator.player
+	id := agent.translator.ids[player]
+	agent.arena.Unlock()
+	if !current {
+		return nil
+	}
+
+	riichi := response.Type == "reach"
+	if riichi {
+		var err error
+		response, err = agent.exchange(mjaiEvent{"type": "reach", "actor": id})
+		if err != nil {
+			return err
+		}
+	}
+
+	actions, err := mjaiActions(response, pending, riichi)
+	for _, action := range actions {
+		if err = agent.arena.SubmitAction(agent, action); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	// Fall back on what the arena would do when the seat runs out of
+	// time, unless the game moved on already
+	fmt.Printf("mjai bot %s: %v\n", agent.Name, err)
+	agent.arena.Lock()
+	if item == agent.decision {
+		actions = agent.arena.match.Game.DefaultActions(player)
+	} else {
+		actions = nil
+	}
+	agent.arena.Unlock()
+	for _, action := range actions {
+		if err := agent.arena.SubmitAction(agent, action); err != nil {
+			fmt.Printf("mjai bot %s: %v\n", agent.Name, err)
+		}
+	}
+	return nil
+}
+
+// Feeds the queued events to the bot until the agent is closed
+func (agent *MjaiAgent) run() {
+	for {
+		item, ok := agent.queue.pop()
+		if !ok {
+			return
+		}
+		response, err := agent.exchange(item.event)
+		if err == nil {
+			err = agent.act(item, response)
+		}
+		if err != nil {
+			fmt.Printf("mjai bot %s stopped: %v\n", agent.Name, err)
+			return
+		}
+	}
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Starts the bot process and seats it in the arena as a player
+func SpawnMjaiAgent(arena *Arena, name string, command string, args ...string) (*MjaiAgent, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	agent := &MjaiAgent{
+		Name:       name,
+		ID:         uuid.New(),
+		arena:      arena,
+		cmd:        cmd,
+		in:         in,
+		encode:     json.NewEncoder(in),
+		decode:     json.NewDecoder(out),
+		queue:      newMjaiQueue(),
+		translator: mjaiTranslator{discarder: -1, reaching: -1},
+	}
+	go agent.run()
+
+	if err := arena.JoinArena(agent, true); err != nil {
+		agent.Close()
+		return nil, err
+	}
+	return agent, nil
+}
+
+// Info implements Agent.
+func (agent *MjaiAgent) Info() AgentInfo {
+	return AgentInfo{Name: agent.Name, ID: agent.ID}
+}
+
+// Send implements Agent. The board events are queued for the bot as
+// mjai events, and the actions the seat can take are kept with the
+// event they follow.
+func (agent *MjaiAgent) Send(message ArenaMessage) {
+	data, ok := message.Data.(ArenaBoardEventData)
+	if !ok {
+		return
+	}
+	if potential, ok := data.Data.(PotentialActionEventData); ok {
+		if agent.last != nil {
+			agent.last.actions = append(agent.last.actions, potential.ActionData)
+			agent.decision = agent.last
+		}
+		return
+	}
+
+	events := agent.translator.translate(data.BoardEvent)
+	if agent.arena.gameStarted {
+		game := &agent.arena.match.Game
+		events = append(events, agent.translator.revealDora(game.Dora[:game.DoraRevealed])...)
+	}
+	for _, event := range events {
+		agent.last = &mjaiItem{event: event}
+		agent.queue.push(agent.last)
+	}
+}
+
+// Stops feeding events to the bot, and waits for it to exit once its
+// stdin is closed
+func (agent *MjaiAgent) Close() error {
+	agent.queue.close()
+	agent.in.Close()
+	return agent.cmd.Wait()
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+// Plays as an mjai bot that discards whatever it draws when the test
+// binary is started by TestMjaiAgent
+func TestMjaiHelperProcess(t *testing.T) {
+	if os.Getenv("LIBRERIICHI_MJAI_HELPER") != "1" {
+		t.Skip("Only run as a bot process")
+	}
+
+	decoder := json.NewDecoder(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	id := -1
+	for decoder.More() {
+		var event struct {
+			Type  string `json:"type"`
+			ID    int    `json:"id"`
+			Actor int    `json:"actor"`
+			Pai   string `json:"pai"`
+		}
+		if err := decoder.Decode(&event); err != nil {
+			os.Exit(1)
+		}
+
+		response := map[string]any{"type": "none"}
+		switch {
+		case event.Type == "start_game":
+			id = event.ID
+		case event.Type == "tsumo" && event.Actor == id:
+			response = map[string]any{"type": "dahai", "actor": id, "pai": event.Pai, "tsumogiri": true}
+		}
+		encoder.Encode(response)
+	}
+	os.Exit(0)
+}
+
+func TestMjaiTile(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		tile Tile
+		want string
+	}{
+		{"1m", Manzu, "1m"},
+		{"9p", Pinzu + 8, "9p"},
+		{"red 5s", (Souzu + 4).SetRedTile(), "5sr"},
+		{"south", SouthTile, "S"},
+		{"white", White, "P"},
+		{"green", Green, "F"},
+		{"red", Red, "C"},
+		{"hidden", Hidden, "?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mjaiTile(tt.tile)
+			if got != tt.want {
+				t.Errorf("mjaiTile() = %v, want %v", got, tt.want)
+			}
+			if tt.tile != Hidden {
+				if parsed, err := parseMjaiTile(got); err != nil || parsed != tt.tile {
+					t.Errorf("parseMjaiTile(%q) = %v, %v, want %v", got, parsed, err, tt.tile)
+				}
+			}
+		})
+	}
+}
+
+func TestMjaiActions(t *testing.T) {
+	toss := ActionData{ActionType: TOSS, Data: TossData{TileToToss: Invalid}}
+	riichi := ActionData{ActionType: RIICHI, Data: RiichiData{TileToRiichi: Pinzu + 2}}
+	ankan := ActionData{ActionType: KAN, Data: KanData{TileToKan: EastTile}}
+	kakan := ActionData{ActionType: KAN, Data: KanData{TileToKan: White, Added: true}}
+	pon := ActionData{ActionType: PON, Data: PonData{TileToPon: Manzu + 4}}
+	chii := ActionData{ActionType: CHII, Data: ChiiData{TileToChii: Manzu + 4, TilesInHand: [2]Tile{Manzu + 5, Manzu + 6}}}
+	otherChii := ActionData{ActionType: CHII, Data: ChiiData{TileToChii: Manzu + 4, TilesInHand: [2]Tile{Manzu + 2, Manzu + 3}}}
+
+	tests := []struct {
+		name     string // description of this test case
+		response mjaiResponse
+		pending  []ActionData
+		riichi   bool
+		want     []ActionData // nil when the response is not allowed
+	}{
+		{"discard", mjaiResponse{Type: "dahai", Pai: "1s"}, []ActionData{toss, riichi}, false,
+			[]ActionData{{ActionType: TOSS, Data: TossData{TileToToss: Souzu}}}},
+		{"riichi", mjaiResponse{Type: "dahai", Pai: "3p"}, []ActionData{toss, riichi}, true, []ActionData{riichi}},
+		{"riichi on another tile", mjaiResponse{Type: "dahai", Pai: "4p"}, []ActionData{toss, riichi}, true, nil},
+		{"ankan", mjaiResponse{Type: "ankan", Consumed: []string{"E", "E", "E", "E"}}, []ActionData{toss, ankan}, false, []ActionData{ankan}},
+		{"kakan", mjaiResponse{Type: "kakan", Pai: "P", Consumed: []string{"P", "P", "P"}}, []ActionData{toss, ankan, kakan}, false, []ActionData{kakan}},
+		{"chii", mjaiResponse{Type: "chi", Pai: "5m", Consumed: []string{"6m", "7m"}}, []ActionData{otherChii, chii, pon}, false, []ActionData{chii}},
+		{"pass", mjaiResponse{Type: "none"}, []ActionData{chii, pon}, false, []ActionData{
+			{ActionType: SKIP, Data: SkipData{ActionToSkip: chii}},
+			{ActionType: SKIP, Data: SkipData{ActionToSkip: pon}},
+		}},
+		{"pass on own turn", mjaiResponse{Type: "none"}, []ActionData{toss}, false, nil},
+		{"win without one", mjaiResponse{Type: "hora"}, []ActionData{pon}, false, nil},
+		{"unknown tile", mjaiResponse{Type: "dahai", Pai: "0m"}, []ActionData{toss}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mjaiActions(tt.response, tt.pending, tt.riichi)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("mjaiActions() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("mjaiActions() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMjaiTranslator(t *testing.T) {
+	translator := mjaiTranslator{discarder: -1, reaching: -1}
+	setup := GameSetupEventData{Setup: []Setup{
+		{Type: INITIAL_TILES, Data: make([]Tile, 13)},
+		{Type: DORA, Data: White},
+		{Type: PLAYER_NUMBER, Data: uint8(2)},
+		{Type: PLAYER_ORDER, Data: []uint8{3, 0, 1, 2}},
+		{Type: ROUND_NUMBER, Data: uint8(1)},
+		{Type: ROUND_WIND, Data: East},
+		{Type: STARTING_POINTS, Data: [4]int32{25000, 25000, 25000, 25000}},
+		{Type: HONBA, Data: uint8(0)},
+		{Type: RIICHI_STICKS, Data: uint8(0)},
+	}}
+	action := func(actionType ActionType, data any, player uint8) BoardEvent {
+		return BoardEvent{
+			EventType: PlayerActionEventType,
+			Data:      PlayerActionEventData{ActionData: ActionData{ActionType: actionType, Data: data}, FromPlayer: player},
+		}
+	}
+
+	events := []BoardEvent{
+		{EventType: GameSetupEventType, Data: setup},
+		action(DRAW, DrawData{DrawnTile: Hidden}, 1),
+		action(RIICHI, RiichiData{TileToRiichi: Manzu}, 1),
+		action(PON, PonData{TileToPon: Manzu}, 2), // The seat declares a pon
+		action(DRAW, DrawData{DrawnTile: Souzu}, 2),
+	}
+	got := make([]mjaiEvent, 0)
+	for _, event := range events {
+		got = append(got, translator.translate(event)...)
+	}
+	got = append(got, translator.revealDora([]Tile{White, Manzu})...)
+
+	want := []mjaiEvent{
+		{"type": "start_game", "id": uint8(2)},
+		{"type": "start_kyoku"},
+		{"type": "tsumo", "actor": uint8(1), "pai": "?"},
+		{"type": "reach", "actor": uint8(1)},
+		{"type": "dahai", "actor": uint8(1), "pai": "1m", "tsumogiri": false},
+		{"type": "reach_accepted", "actor": 1},
+		{"type": "tsumo", "actor": uint8(2), "pai": "1s"},
+		{"type": "dora", "dora_marker": "1m"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Got events %v, want %v", got, want)
+	}
+	for idx := range want {
+		for key, value := range want[idx] {
+			if got[idx][key] != value {
+				t.Errorf("Event %d is %v, want %v", idx, got[idx], want[idx])
+				break
+			}
+		}
+	}
+}
+
+func TestMjaiCallTiles(t *testing.T) {
+	five, red := Pinzu+4, (Pinzu + 4).SetRedTile()
+	tests := []struct {
+		name         string // description of this test case
+		action       ActionData
+		discarder    int
+		wantPai      any
+		wantConsumed []string
+	}{
+		{"pon with a red five from the hand", ActionData{ActionType: PON, Data: PonData{TileToPon: five, TilesInHand: [2]Tile{red, five}}},
+			0, "5p", []string{"5pr", "5p"}},
+		{"daiminkan on a red five", ActionData{ActionType: KAN, Data: KanData{TileToKan: red, TilesInHand: [3]Tile{five, five, five}}},
+			0, "5pr", []string{"5p", "5p", "5p"}},
+		{"ankan with a red five", ActionData{ActionType: KAN, Data: KanData{TileToKan: five, TilesInHand: [3]Tile{red, five, five}}},
+			-1, nil, []string{"5pr", "5p", "5p", "5p"}},
+		{"kakan onto a pon with a red five", ActionData{ActionType: KAN, Data: KanData{TileToKan: five, TilesInHand: [3]Tile{red, five, five}, Added: true}},
+			-1, "5p", []string{"5pr", "5p", "5p"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translator := mjaiTranslator{ids: []uint8{0, 1, 2, 3}, discarder: tt.discarder, reaching: -1}
+			got := translator.playerAction(PlayerActionEventData{ActionData: tt.action, FromPlayer: 1})
+			if len(got) != 1 {
+				t.Fatalf("Got events %v, want one", got)
+			}
+			if got[0]["pai"] != tt.wantPai || !slices.Equal(got[0]["consumed"].([]string), tt.wantConsumed) {
+				t.Errorf("Got %v, want pai %v and consumed %v", got[0], tt.wantPai, tt.wantConsumed)
+			}
+		})
+	}
+}
+
+func TestMjaiAgent(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	for _, name := range []string{"east", "south", "west"} {
+		if err := arena.JoinArena(NewBot(name), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("LIBRERIICHI_MJAI_HELPER", "1")
+	agent, err := SpawnMjaiAgent(&arena, "mjai", os.Args[0], "-test.run=^TestMjaiHelperProcess$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Minute)
+	for arena.GetArenaInfo().GameStarted {
+		if time.Now().After(deadline) {
+			t.Fatal("Match did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	arena.Lock()
+	defer arena.Unlock()
+	seat, _ := arena.playerIdx(agent)
+	if !slices.ContainsFunc(arena.log.Entries, func(entry LogEntry) bool {
+		return entry.Type == ACTION_ENTRY && entry.Player == seat
+	}) {
+		t.Error("The bot took no actions")
+	}
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+)
+
+// How long the seat of a player who left during a match is held for
+// them to come back
+const DefaultGracePeriod = 2 * time.Minute
+
+// How many of the messages a held seat missed are kept for its player.
+// The backlog has to fit in the queue of the client along with the
+// snapshot, since the arena hands both over without waiting on it.
+const missedLimit = clientBuffer / 2
+
+// A seat whose player left during a match. A bot plays the seat until
+// the player comes back.
+type awaySeat struct {
+	bot *Bot
+	// The session that can reclaim the seat, empty once it can no
+	// longer be reclaimed
+	token  string
+	missed []ArenaMessage // What was sent to the seat while it was held
+	expiry *time.Timer
+	// Whether the other players were told that the player quit
+	quitAnnounced bool
+	// Set once the player reconnected, until the seat is handed back
+	reclaimed bool
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// Sends a message to a player, or to the bot playing for them while
+// they are away
+func (arena *Arena) deliver(playerIdx uint8, message ArenaMessage) {
+	seat, ok := arena.away[playerIdx]
+	if !ok {
+		arena.agents[playerIdx].Send(message)
+		return
+	}
+
+	if seat.token != "" {
+		// The snapshot shows the hand as it is, so older messages can go
+		seat.missed = append(seat.missed, message)
+		if len(seat.missed) > missedLimit {
+			seat.missed = seat.missed[1:]
+		}
+	}
+	seat.bot.Send(message)
+}
+
+// Hands the seat over to a bot, holding it for the session to reclaim
+// until the grace period runs out
+func (arena *Arena) leaveSeat(playerIdx uint8, token string) *awaySeat {
+	seat := &awaySeat{
+		bot:   NewBot(arena.agents[playerIdx].Info().Name),
+		token: token,
+	}
+	if token != "" {
+		seat.expiry = time.AfterFunc(arena.gracePeriod, func() {
+			arena.expireSeat(playerIdx, seat)
+		})
+	}
+	arena.away[playerIdx] = seat
+	return seat
+}
+
+// Stops holding the seat for its player, who can no longer reclaim it.
+// The bot plays the seat for the rest of the match.
+func (arena *Arena) abandonSeat(seat *awaySeat) {
+	if seat.expiry != nil {
+		seat.expiry.Stop()
+	}
+	if seat.token != "" {
+		RemoveSession(seat.token)
+	}
+	seat.token = ""
+	seat.missed = nil
+}
+
+// Called once the grace period of a held seat runs out
+func (arena *Arena) expireSeat(playerIdx uint8, seat *awaySeat) {
+	arena.Lock()
+	defer arena.Unlock()
+
+	if arena.away[playerIdx] != seat || seat.token == "" || seat.reclaimed {
+		return
+	}
+	arena.abandonSeat(seat)
+
+	if !seat.quitAnnounced {
+		seat.quitAnnounced = true
+		arena.Send(ArenaMessage{
+			MessageType: PlayerQuitEventType,
+			Data: PlayerQuitEventData{
+				Name: arena.agents[playerIdx].Info().Name,
+			},
+		}, EXCLUDE, playerIdx)
+	}
+
+	if !arena.gameStarted {
+		arena.removeAbandoned()
+	}
+}
+
+// Removes a player from the arena, keeping the seats that are held in
+// line with the players
+func (arena *Arena) removeAgent(playerIdx uint8) {
+	last := uint8(len(arena.agents) - 1)
+	Remove(&arena.agents, uint(playerIdx))
+
+	delete(arena.away, playerIdx)
+	if seat, ok := arena.away[last]; ok && last != playerIdx {
+		delete(arena.away, last)
+		arena.away[playerIdx] = seat
+	}
+}
+
+// Removes the players who left during the match once it is over
+func (arena *Arena) removeAbandoned() {
+	for idx := len(arena.agents) - 1; idx >= 0; idx-- {
+		if seat, ok := arena.away[uint8(idx)]; ok && seat.token == "" {
+			arena.removeAgent(uint8(idx))
+		}
+	}
+	if len(arena.agents) == 0 {
+		RemoveArena(arena.Name)
+	}
+}
+
+// Hands a held seat back to its player, along with what they missed and
+// a snapshot of the hand as it is now. Sending to the client never
+// blocks, so the backlog is handed over with the arena locked, and no
+// message can come in between.
+func (arena *Arena) resume(playerIdx uint8, seat *awaySeat) {
+	arena.Lock()
+	defer arena.Unlock()
+
+	if arena.away[playerIdx] != seat {
+		return
+	}
+
+	delete(arena.away, playerIdx)
+	for _, message := range seat.missed {
+		arena.deliver(playerIdx, message)
+	}
+	if arena.gameStarted {
+		if err := arena.sendSnapshot(playerIdx); err != nil {
+			panic(err)
+		}
+	}
+	arena.updateTimers()
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Holds the seat of a player whose connection dropped during a match,
+// until they reconnect or the grace period runs out
+func (arena *Arena) Disconnect(client *Client) error {
+	arena.Lock()
+	defer arena.Unlock()
+
+	if !arena.gameStarted {
+		return CodedError{Code: GAME_NOT_STARTED}
+	}
+	if client.SessionToken == "" {
+		return CodedError{Code: SESSION_NOT_FOUND, Reason: "No session to reconnect with"}
+	}
+	playerIdx, err := arena.playerIdx(client)
+	if err != nil {
+		return err
+	}
+	if seat, ok := arena.away[playerIdx]; ok {
+		// The player quit, and their seat is already held for them
+		if seat.token == client.SessionToken {
+			return nil
+		}
+		return CodedError{Code: PLAYER_AWAY, Reason: "Player already left"}
+	}
+
+	arena.leaveSeat(playerIdx, client.SessionToken)
+	// The bot acts straight away if the game waits on the seat
+	return arena.driveGame()
+}
+
+// Gives the client back the seat held for its session. What the seat
+// missed and a snapshot of the hand are sent to the client right after.
+func (arena *Arena) Reconnect(client *Client) error {
+	arena.Lock()
+	defer arena.Unlock()
+
+	for playerIdx, seat := range arena.away {
+		if seat.token == "" || seat.token != client.SessionToken || seat.reclaimed {
+			continue
+		}
+
+		seat.expiry.Stop()
+		seat.reclaimed = true
+		arena.agents[playerIdx] = client
+		client.Arena = arena
+
+		// The client is still waiting for the reply to its request, so
+		// the seat is handed back once the arena is free again. Until
+		// then, messages to the seat are still kept for it.
+		go arena.resume(playerIdx, seat)
+		return nil
+	}
+	return CodedError{Code: NO_SEAT_HELD}
+}
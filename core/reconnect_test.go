@@ -0,0 +1,180 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+// Starts a match in an arena whose players all have a session, without
+// time limits
+func newSessionArena(t *testing.T, gracePeriod time.Duration) (*Arena, []*Client) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	arena.gracePeriod = gracePeriod
+
+	clients := make([]*Client, 4)
+	for idx := range clients {
+		clients[idx] = newTestClient()
+		if _, err := clients[idx].HandleInitialMessage(InitialMessageActionData{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := arena.JoinArena(clients[idx], true); err != nil {
+			t.Fatal(err)
+		}
+		SetSessionArena(clients[idx].SessionToken, &arena)
+	}
+	t.Cleanup(func() {
+		for _, client := range clients {
+			RemoveSession(client.SessionToken)
+		}
+	})
+
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	return &arena, clients
+}
+
+// Waits until the condition holds in the arena
+func waitFor(t *testing.T, arena *Arena, what string, condition func() bool) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		arena.Lock()
+		done := condition()
+		arena.Unlock()
+		if done {
+			return
+		}
+	}
+	t.Fatalf("Timed out waiting until %s", what)
+}
+
+func TestReconnect(t *testing.T) {
+	arena, clients := newSessionArena(t, time.Minute)
+	dealer := arena.match.Game.OrderToPlayer[0]
+	token := clients[dealer].SessionToken
+
+	if err := arena.Disconnect(clients[dealer]); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, arena, "the dealer's turn is played for them", func() bool {
+		return len(arena.match.Game.Players[dealer].Discards) == 1
+	})
+
+	client := newTestClient()
+	result, err := client.HandleInitialMessage(InitialMessageActionData{SessionToken: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response := result.Message.Data.(InitialMessageResponseData); !response.Resumed {
+		t.Fatal("Seat was not resumed")
+	}
+	waitFor(t, arena, "the seat is handed back", func() bool {
+		return len(arena.away) == 0
+	})
+
+	if arena.agents[dealer] != client || client.Arena != arena {
+		t.Error("Client did not get the seat back")
+	}
+
+	messages := received(client)
+	if len(messages) < 2 {
+		t.Fatalf("Got %d messages, want the missed events and a snapshot", len(messages))
+	}
+	if messages[0].MessageType != ArenaBoardEventType {
+		t.Errorf("First message is %v, want a missed board event", messages[0].MessageType)
+	}
+	snapshot, ok := messages[len(messages)-1].Data.(SnapshotEventData)
+	if !ok {
+		t.Fatalf("Last message is %v, want a snapshot", messages[len(messages)-1].MessageType)
+	}
+	if snapshot.View.Player != dealer || len(snapshot.View.Seats[dealer].Discards) != 1 {
+		t.Errorf("Snapshot %+v does not show the dealer's discard", snapshot.View)
+	}
+}
+
+func TestGracePeriodExpires(t *testing.T) {
+	arena, clients := newSessionArena(t, 10*time.Millisecond)
+	token := clients[1].SessionToken
+
+	if err := arena.Disconnect(clients[1]); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, arena, "the grace period runs out", func() bool {
+		return arena.away[1].token == ""
+	})
+
+	if _, err := GetSession(token); err == nil {
+		t.Error("Session outlived the grace period")
+	}
+	client := newTestClient()
+	result, err := client.HandleInitialMessage(InitialMessageActionData{SessionToken: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveSession(client.SessionToken)
+	if response := result.Message.Data.(InitialMessageResponseData); response.Resumed || response.SessionToken == token {
+		t.Errorf("Resumed the session after the grace period: %+v", response)
+	}
+	if _, ok := arena.away[1]; !ok {
+		t.Error("Abandoned seat is no longer played for")
+	}
+}
+
+func TestReconnectBacklogFits(t *testing.T) {
+	arena, clients := newSessionArena(t, time.Minute)
+	token := clients[1].SessionToken
+	if err := arena.Disconnect(clients[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// More than the client can be sent at once
+	arena.Lock()
+	for range clientBuffer {
+		arena.deliver(1, ArenaMessage{MessageType: SnapshotEventType, Data: SnapshotEventData{}})
+	}
+	arena.Unlock()
+
+	client := newTestClient()
+	if _, err := client.HandleInitialMessage(InitialMessageActionData{SessionToken: token}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, arena, "the seat is handed back", func() bool {
+		return len(arena.away) == 0
+	})
+
+	if messages := received(client); len(messages) > missedLimit+1 {
+		t.Errorf("Got %d messages, want at most %d missed ones and a snapshot", len(messages), missedLimit)
+	}
+	select {
+	case <-client.lagging:
+		t.Error("Client was dropped while getting what it missed")
+	default:
+	}
+}
+
+func TestResumeConnectedSession(t *testing.T) {
+	arena, clients := newSessionArena(t, time.Minute)
+	token := clients[1].SessionToken
+
+	client := newTestClient()
+	result, err := client.HandleInitialMessage(InitialMessageActionData{SessionToken: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveSession(client.SessionToken)
+	if response := result.Message.Data.(InitialMessageResponseData); response.Resumed || response.SessionToken == token {
+		t.Errorf("Took over the session of a connected client: %+v", response)
+	}
+
+	// Dropping the second connection leaves the seated client alone
+	client.HandleClientDestruction()
+	if _, err := GetSession(token); err != nil {
+		t.Errorf("Session of the seated client was removed: %v", err)
+	}
+	if arena.agents[1] != clients[1] || len(arena.away) != 0 {
+		t.Error("Seated client lost its seat")
+	}
+}
@@ -0,0 +1,387 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/google/uuid"
+)
+
+// A recorded match played again from its log. The match is played
+// through when it is loaded, and then stepped through one input at a
+// time: the deal of a hand, an action, or the arena moving the game on.
+type Replay struct {
+	Seed     Seed
+	Rules    MatchRules
+	Players  []AgentInfo
+	Finished bool // Whether the log goes up to the end of the match
+
+	steps    []replayStep
+	position int // How many steps were played
+}
+
+// Returned when replaying a log does not give the events it recorded
+type ReplayDivergenceError struct {
+	Entry  int // The index of the log entry the replay diverged at
+	Reason string
+}
+
+type loggedEvent struct {
+	event      ArenaBoardEventData
+	visibility Visibility
+	sendTo     uint8
+}
+
+type replayStep struct {
+	events []loggedEvent
+	views  []PlayerView // Of every player, once the step was played
+}
+
+// Plays a log through, checking each event against the log
+type replayer struct {
+	entries  []LogEntry
+	next     int // The next entry to check
+	match    Match
+	replay   *Replay
+	observer replayObserver
+}
+
+// Follows the game while a log is played through
+type replayObserver interface {
+	handStarted(match *Match)
+	// Called around every call that moves the game on
+	beforeCall(game *MahjongGame)
+	afterCall(game *MahjongGame, sendInfos []MessageSendInfo)
+	// Called once a hand has ended, before the match moves on from it
+	handEnded(match *Match) error
+}
+
+func (e ReplayDivergenceError) Error() string {
+	return fmt.Sprintf("Replay diverges from its log at entry %d: %s", e.Entry, e.Reason)
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+func (r *replayer) diverged(reason string, args ...any) error {
+	return ReplayDivergenceError{Entry: r.next, Reason: fmt.Sprintf(reason, args...)}
+}
+
+// Checks an event the replay sent against the next one in the log
+func (r *replayer) send(event ArenaBoardEventData, visibility Visibility, sendTo uint8) error {
+	if r.next >= len(r.entries) {
+		// The log was cut short, e.g. because the match is still going
+		return nil
+	}
+
+	entry := r.entries[r.next]
+	if entry.Type != EVENT_ENTRY {
+		return r.diverged("sent event %v that was not logged", event.EventType)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, entry.Event) || visibility != entry.Visibility || sendTo != entry.SendTo {
+		return r.diverged("sent %s, logged %s", data, entry.Event)
+	}
+
+	step := &r.replay.steps[len(r.replay.steps)-1]
+	step.events = append(step.events, loggedEvent{event, visibility, sendTo})
+	r.next += 1
+	return nil
+}
+
+func (r *replayer) sendAll(sendInfos []MessageSendInfo) error {
+	for _, sendInfo := range sendInfos {
+		for _, event := range sendInfo.Events {
+			if err := r.send(event, sendInfo.Visibility, sendInfo.SendTo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Moves the game on like Arena.driveGame does, including the end of a
+// hand and of the match
+func (r *replayer) drive() error {
+	game := &r.match.Game
+	if r.observer != nil {
+		r.observer.beforeCall(game)
+	}
+	sendInfos, shouldEnd := game.GetNextEvent()
+	if r.observer != nil {
+		r.observer.afterCall(game, sendInfos)
+	}
+	if err := r.sendAll(sendInfos); err != nil {
+		return err
+	}
+	if !shouldEnd {
+		return nil
+	}
+
+	result, err := game.GetGameResults()
+	if err != nil {
+		return err
+	}
+	err = r.send(encodeBoardEvent(GameEndEventType, GameEndEventData{
+		GameResult: result,
+		Wall:       game.RevealWall(),
+	}), GLOBAL, 0)
+	if err != nil {
+		return err
+	}
+
+	if r.observer != nil {
+		if err := r.observer.handEnded(&r.match); err != nil {
+			return err
+		}
+	}
+	if err := r.match.FinishHand(); err != nil {
+		return err
+	}
+	if !r.match.Finished {
+		return nil
+	}
+	r.replay.Finished = true
+	return r.send(encodeBoardEvent(MatchEndEventType, MatchEndEventData{
+		Points:     r.match.Round.Points,
+		Placements: r.match.Placements(),
+	}), GLOBAL, 0)
+}
+
+// Replays the input at the next entry of the log as a new step
+func (r *replayer) step() error {
+	entry := r.entries[r.next]
+	r.replay.steps = append(r.replay.steps, replayStep{})
+	game := &r.match.Game
+
+	switch entry.Type {
+	case HAND_START_ENTRY:
+		if entry.Round == nil || entry.Round.Seed != r.match.Round.Seed {
+			return r.diverged("hand was not dealt from seed %v", r.match.Round.Seed)
+		}
+		r.match.Round.WallSalt = entry.Round.WallSalt
+		r.next += 1
+
+		setups, err := r.match.StartNextHand()
+		if err != nil {
+			return r.diverged("%v", err)
+		}
+		if r.observer != nil {
+			r.observer.handStarted(&r.match)
+		}
+		for idx, setup := range setups {
+			event := encodeBoardEvent(GameSetupEventType, GameSetupEventData{Setup: setup})
+			if err := r.send(event, PLAYER, uint8(idx)); err != nil {
+				return err
+			}
+		}
+		if err := r.drive(); err != nil {
+			return err
+		}
+
+	case ACTION_ENTRY:
+		if entry.Action == nil {
+			return r.diverged("action entry without an action")
+		}
+		if r.observer != nil {
+			r.observer.beforeCall(game)
+		}
+		sendInfos, err := ActionDecode(game, *entry.Action, entry.Player)
+		if err != nil {
+			return r.diverged("%v for action %v of player %d", err, entry.Action.ActionType, entry.Player)
+		}
+		if r.observer != nil {
+			r.observer.afterCall(game, sendInfos)
+		}
+		r.next += 1
+		if err := r.sendAll(sendInfos); err != nil {
+			return err
+		}
+		if err := r.drive(); err != nil {
+			return err
+		}
+
+	case EVENT_ENTRY:
+		// The arena moved the game on without an action, which happens
+		// when a player leaves or comes back
+		before := r.next
+		if err := r.drive(); err != nil {
+			return err
+		}
+		if r.next == before {
+			return r.diverged("logged an event the replay did not send")
+		}
+
+	default:
+		return r.diverged("unexpected entry type %d", entry.Type)
+	}
+
+	views := make([]PlayerView, len(game.Players))
+	for idx := range views {
+		views[idx] = game.PlayerView(uint8(idx))
+	}
+	r.replay.steps[len(r.replay.steps)-1].views = views
+	return nil
+}
+
+// A player view without what only that player knows
+func publicView(view PlayerView) PlayerView {
+	hand := make([]Tile, len(view.ClosedHand))
+	for idx := range hand {
+		hand[idx] = Hidden
+	}
+	view.ClosedHand = hand
+	if view.DrawnTile != Invalid {
+		view.DrawnTile = Hidden
+	}
+	view.PendingActions = nil
+	return view
+}
+
+// The snapshots that show the viewer where the replay is at
+func (replay *Replay) snapshots(viewer Spectator) []ArenaMessage {
+	if replay.position == 0 {
+		return nil
+	}
+
+	views := replay.steps[replay.position-1].views
+	switch viewer.View {
+	case PLAYER_VIEW:
+		views = views[viewer.Player : viewer.Player+1]
+	case PUBLIC_VIEW:
+		views = []PlayerView{publicView(views[0])}
+	}
+
+	messages := make([]ArenaMessage, 0, len(views))
+	for _, view := range views {
+		messages = append(messages, ArenaMessage{
+			MessageType: SnapshotEventType,
+			Data:        SnapshotEventData{View: view},
+		})
+	}
+	return messages
+}
+
+// The events of a step the viewer gets to see
+func (replay *Replay) events(step replayStep, viewer Spectator) ([]ArenaMessage, error) {
+	messages := make([]ArenaMessage, 0, len(step.events))
+	for _, logged := range step.events {
+		sees, full := viewer.sees(logged.visibility, logged.sendTo)
+		if !sees {
+			continue
+		}
+
+		message := ArenaMessage{MessageType: ArenaBoardEventType, Data: logged.event}
+		if !full {
+			alt, send, err := GetAltMessage(message)
+			if err != nil {
+				return nil, err
+			}
+			if !send {
+				continue
+			}
+			message = alt
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Plays a logged match through from its seed and actions, telling the
+// observer, if any, about it
+func loadReplay(entries []LogEntry, observer replayObserver) (*Replay, error) {
+	if len(entries) == 0 || entries[0].Type != MATCH_START_ENTRY || entries[0].Rules == nil {
+		return nil, CodedError{Code: REPLAY_UNAVAILABLE, Reason: "Log does not start with a match"}
+	}
+
+	start := entries[0]
+	replay := &Replay{Seed: start.Seed, Rules: *start.Rules, Players: start.Players}
+	r := replayer{
+		entries:  entries,
+		next:     1,
+		match:    NewSeededMatch(*start.Rules, start.Seed),
+		replay:   replay,
+		observer: observer,
+	}
+	for r.next < len(entries) {
+		if err := r.step(); err != nil {
+			return nil, err
+		}
+	}
+	return replay, nil
+}
+
+// Loads the replay of a finished match from the logs of GlobalLogSink.
+// Matches that are still going cannot be replayed, since the replay
+// shows every hand.
+func OpenReplay(gameID uuid.UUID) (*Replay, error) {
+	source, ok := GlobalLogSink.(LogSource)
+	if !ok {
+		return nil, CodedError{Code: REPLAY_UNAVAILABLE, Reason: "Game logs cannot be read"}
+	}
+	entries, err := source.Read(gameID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", CodedError{Code: REPLAY_UNAVAILABLE}, err)
+	}
+
+	replay, err := LoadReplay(entries)
+	if err != nil {
+		return nil, err
+	}
+	if !replay.Finished {
+		return nil, CodedError{Code: REPLAY_UNAVAILABLE, Reason: "Match has not finished"}
+	}
+	return replay, nil
+}
+
+// Plays a logged match through from its seed and actions. A log that
+// stops before the end of the match is replayed as far as it goes.
+func LoadReplay(entries []LogEntry) (*Replay, error) {
+	return loadReplay(entries, nil)
+}
+
+// The number of steps in the replay
+func (replay *Replay) Len() int {
+	return len(replay.steps)
+}
+
+// How many steps were played so far
+func (replay *Replay) Position() int {
+	return replay.position
+}
+
+// Moves the replay to the given step, and returns the messages that take
+// the viewer there. Moving one step forward gives the events of that
+// step as the viewer saw them when the match was played, and any other
+// move gives snapshots of the game.
+func (replay *Replay) Seek(position int, viewer Spectator) ([]ArenaMessage, error) {
+	if position < 0 || position > len(replay.steps) {
+		return nil, CodedError{Code: OUT_OF_RANGE}
+	}
+	if viewer.View > OMNISCIENT_VIEW || (viewer.View == PLAYER_VIEW && int(viewer.Player) >= len(replay.Players)) {
+		return nil, CodedError{Code: UNKNOWN_VIEW}
+	}
+
+	forward := position == replay.position+1
+	replay.position = position
+	if forward {
+		return replay.events(replay.steps[position-1], viewer)
+	}
+	return replay.snapshots(viewer), nil
+}
+
+func (replay *Replay) Forward(viewer Spectator) ([]ArenaMessage, error) {
+	return replay.Seek(replay.position+1, viewer)
+}
+
+func (replay *Replay) Backward(viewer Spectator) ([]ArenaMessage, error) {
+	return replay.Seek(replay.position-1, viewer)
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	"github.com/google/uuid"
+)
+
+// A bot that keeps the board events it is sent
+type recordingBot struct {
+	*Bot
+	events []ArenaMessage
+}
+
+func (bot *recordingBot) Send(message ArenaMessage) {
+	if message.MessageType == ArenaBoardEventType {
+		bot.events = append(bot.events, message)
+	}
+}
+
+func playLoggedMatch(t *testing.T) ([]LogEntry, []*recordingBot) {
+	t.Helper()
+	sink := FileLogSink{Dir: t.TempDir()}
+	arena := CreateArena("test", [16]byte{})
+	arena.logSink = sink
+	bots := make([]*recordingBot, 4)
+	for idx := range bots {
+		bots[idx] = &recordingBot{Bot: NewBot("bot")}
+		if err := arena.JoinArena(bots[idx], true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadLogFile(sink.Path(arena.log.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries, bots
+}
+
+func TestReplay(t *testing.T) {
+	entries, bots := playLoggedMatch(t)
+	replay, err := LoadReplay(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replay.Finished || replay.Len() == 0 {
+		t.Fatalf("Replay of %d steps did not finish", replay.Len())
+	}
+
+	// Every seat sees again what it saw during the match
+	for player, bot := range bots {
+		viewer := Spectator{View: PLAYER_VIEW, Player: uint8(player)}
+		if _, err := replay.Seek(0, viewer); err != nil {
+			t.Fatal(err)
+		}
+
+		seen := make([]ArenaMessage, 0)
+		for replay.Position() < replay.Len() {
+			messages, err := replay.Forward(viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen = append(seen, messages...)
+		}
+
+		got, _ := json.Marshal(seen)
+		want, _ := json.Marshal(bot.events)
+		if string(got) != string(want) {
+			t.Errorf("Player %d saw %d events in the replay and %d in the match", player, len(seen), len(bot.events))
+		}
+	}
+
+	// Stepping back shows where the game was
+	messages, err := replay.Backward(Spectator{View: OMNISCIENT_VIEW})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 4 || messages[0].MessageType != SnapshotEventType {
+		t.Errorf("Stepping back gave %+v, want a snapshot of every player", messages)
+	}
+	if _, err := replay.Seek(replay.Len()+1, Spectator{}); err == nil {
+		t.Error("Seeked past the end of the replay")
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	entries, _ := playLoggedMatch(t)
+	event := slices.IndexFunc(entries, func(entry LogEntry) bool { return entry.Type == EVENT_ENTRY })
+
+	tests := []struct {
+		name   string // description of this test case
+		change func(entries []LogEntry)
+	}{
+		{"other seed", func(entries []LogEntry) { entries[0].Seed[0] ^= 1 }},
+		{"changed event", func(entries []LogEntry) { entries[event].SendTo = (entries[event].SendTo + 1) % 4 }},
+		{"missing action", func(entries []LogEntry) {
+			action := slices.IndexFunc(entries, func(entry LogEntry) bool { return entry.Type == ACTION_ENTRY })
+			entries[action].Type = EVENT_ENTRY
+		}},
+	}
+	if _, err := LoadReplay(entries[1:]); CodeOf(err) != REPLAY_UNAVAILABLE {
+		t.Errorf("LoadReplay() without the match start = %v, want code %v", err, REPLAY_UNAVAILABLE)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := slices.Clone(entries)
+			tt.change(changed)
+
+			_, err := LoadReplay(changed)
+			var divergence ReplayDivergenceError
+			if !errors.As(err, &divergence) {
+				t.Fatalf("LoadReplay() = %v, want a divergence", err)
+			}
+		})
+	}
+}
+
+func TestHandleReplay(t *testing.T) {
+	sink := FileLogSink{Dir: t.TempDir()}
+	GlobalLogSink = sink
+	defer func() { GlobalLogSink = nil }()
+
+	entries, _ := playLoggedMatch(t)
+	gameID := uuid.New()
+	if err := sink.Append(gameID, entries[:len(entries)/2]); err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient()
+	result, _ := client.HandleReplay(ReplayActionData{GameID: gameID, Position: 1})
+	if data, ok := result.Message.Data.(GenericResponseData); !ok || data.Success {
+		t.Errorf("Replayed a match that has not finished: %+v", result.Message)
+	}
+
+	if err := sink.Append(gameID, entries[len(entries)/2:]); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = client.HandleReplay(ReplayActionData{GameID: gameID, Position: 1, View: OMNISCIENT_VIEW})
+	data, ok := result.Message.Data.(ReplayResponseData)
+	if !ok || data.Position != 1 || len(data.Messages) == 0 {
+		t.Errorf("Got %+v, want the first step of the replay", result.Message)
+	}
+}
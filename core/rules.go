@@ -0,0 +1,31 @@
+package core
+
+// Optional rules for a single hand. The zero value turns every
+// optional rule off.
+type GameRules struct {
+	// Whether every player who rons the same discard wins. Otherwise
+	// only the player closest to the discarder in turn order wins
+	// (atamahane).
+	DoubleRon bool
+	// Whether one five of each suit is red, and counts as a dora
+	RedFives bool
+
+	// Abortive draws
+
+	KyuushuKyuuhai bool // Nine different terminals and honours on the first draw
+	SuufonRenda    bool // The same wind discarded by everyone on the first go-around
+	SuuchaRiichi   bool // Every player declared riichi
+	Suukaikan      bool // Four kans declared by more than one player
+	Sanchahou      bool // Three players ronned the same discard
+}
+
+func DefaultGameRules() GameRules {
+	return GameRules{
+		DoubleRon:      true,
+		KyuushuKyuuhai: true,
+		SuufonRenda:    true,
+		SuuchaRiichi:   true,
+		Suukaikan:      true,
+		Sanchahou:      true,
+	}
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+)
+
+// Seeds the random choices of a match or a hand. Seeds are as large as
+// the state of the generator, so that the walls cannot be searched for
+// the seed that dealt them.
+type Seed [32]byte
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// Derives the seed for one part of a match, e.g. the wall of its third
+// hand. Knowing derived seeds gives away nothing about the seed they
+// were derived from, or about the other derived seeds.
+func (seed Seed) derive(label string, idx uint32) Seed {
+	mac := hmac.New(sha256.New, seed[:])
+	mac.Write([]byte(label))
+	binary.Write(mac, binary.BigEndian, idx)
+	return Seed(mac.Sum(nil))
+}
+
+// A generator that always gives the same numbers for the seed
+func (seed Seed) rng() *rand.Rand {
+	return rand.New(rand.NewChaCha8(seed))
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// A seed from crypto/rand, which nobody can guess
+func NewSeed() Seed {
+	var seed Seed
+	crand.Read(seed[:])
+	return seed
+}
+
+// A seed made from a number, for simulations and tests that have to be
+// repeated. The seed is as easy to guess as the number.
+func SeedFromInt(number int64) Seed {
+	var seed Seed
+	binary.BigEndian.PutUint64(seed[:], uint64(number))
+	return seed
+}
+
+func (seed Seed) String() string {
+	return hex.EncodeToString(seed[:])
+}
+
+// Seeds are written to logs as hex
+func (seed Seed) MarshalText() ([]byte, error) {
+	return []byte(seed.String()), nil
+}
+
+func (seed *Seed) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(seed) {
+		return errors.New("Seed needs 64 hex digits")
+	}
+	_, err := hex.Decode(seed[:], text)
+	return err
+}
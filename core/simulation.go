@@ -0,0 +1,171 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+)
+
+// How many times a hand may ask for its next event before the
+// simulation gives up on it. A hand takes a few hundred at most.
+const maxHandSteps = 10000
+
+// What happened over a series of simulated matches, indexed by player
+type SimulationStats struct {
+	Matches int
+	Hands   int
+	Draws   int // Exhaustive and abortive draws
+
+	Wins       []int
+	DealIns    []int
+	Placements [][]int // How many times each player finished in each place
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// Plays a hand until it ends, asking the actors for their actions
+// whenever the game waits on them
+func simulateHand(game *MahjongGame, actors []Actor) error {
+	for step := 0; step < maxHandSteps; step++ {
+		_, shouldEnd := game.GetNextEvent()
+		if shouldEnd {
+			return nil
+		}
+
+		awaited := game.AwaitedPlayers()
+		if len(awaited) == 0 {
+			return errors.New("Game waits on nobody")
+		}
+
+		player := awaited[0]
+		actions := actors[player].Act(game.PlayerView(player))
+		if len(actions) == 0 {
+			actions = game.DefaultActions(player)
+		}
+		for _, action := range actions {
+			if _, err := ActionDecode(game, action, player); err != nil {
+				return fmt.Errorf("Player %d cannot %v: %w", player, action.ActionType, err)
+			}
+		}
+	}
+	return errors.New("Hand did not end")
+}
+
+func (stats *SimulationStats) recordHand(result GameResult) {
+	stats.Hands += 1
+	if result.Type != WIN_RESULT {
+		stats.Draws += 1
+		return
+	}
+
+	stats.Wins[result.WonBy] += 1
+	for _, other := range result.OtherWins {
+		stats.Wins[other.WonBy] += 1
+	}
+	if result.Result.WonByRon {
+		stats.DealIns[result.PointsTransfers[0].From] += 1
+	}
+}
+
+// Checks that no points were made or lost over the hand
+func checkPoints(match Match) error {
+	total := 1000 * int32(match.Round.RiichiSticks)
+	for _, points := range match.Round.Points {
+		total += points
+	}
+	if want := int32(len(match.Round.Points)) * match.Rules.StartingPoints; total != want {
+		return fmt.Errorf("Points add up to %d instead of %d", total, want)
+	}
+	return nil
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+func NewSimulationStats(numPlayers int) SimulationStats {
+	stats := SimulationStats{
+		Wins:       make([]int, numPlayers),
+		DealIns:    make([]int, numPlayers),
+		Placements: make([][]int, numPlayers),
+	}
+	for idx := range stats.Placements {
+		stats.Placements[idx] = make([]int, numPlayers)
+	}
+	return stats
+}
+
+// Plays a whole match between the actors from the given seed, driving
+// the game through GetNextEvent and ActionDecode like an arena does, but
+// without sending any messages. Any action the game rejects, or a hand
+// that does not end, is returned as an error, since the actors only pick
+// from the actions they are offered.
+func SimulateMatch(rules MatchRules, seed int64, actors []Actor, stats *SimulationStats) error {
+	if len(actors) != 4 {
+		return errors.New("Not enough actors")
+	}
+
+	match := NewSeededMatch(rules, SeedFromInt(seed))
+	for !match.Finished {
+		if _, err := match.StartNextHand(); err != nil {
+			return err
+		}
+		if err := simulateHand(&match.Game, actors); err != nil {
+			return fmt.Errorf("Hand with seed %v: %w", match.Round.Seed, err)
+		}
+
+		result, err := match.Game.GetGameResults()
+		if err != nil {
+			return err
+		}
+		stats.recordHand(result)
+
+		if err := match.FinishHand(); err != nil {
+			return err
+		}
+		if err := checkPoints(match); err != nil {
+			return err
+		}
+	}
+
+	stats.Matches += 1
+	for place, player := range match.Placements() {
+		stats.Placements[player][place] += 1
+	}
+	return nil
+}
+
+// The share of hands the player won
+func (stats SimulationStats) WinRate(player uint8) float64 {
+	if stats.Hands == 0 {
+		return 0
+	}
+	return float64(stats.Wins[player]) / float64(stats.Hands)
+}
+
+// The share of hands the player lost by dealing into a ron
+func (stats SimulationStats) DealInRate(player uint8) float64 {
+	if stats.Hands == 0 {
+		return 0
+	}
+	return float64(stats.DealIns[player]) / float64(stats.Hands)
+}
+
+// The average place the player finished in, from 1 to 4
+func (stats SimulationStats) AveragePlacement(player uint8) float64 {
+	if stats.Matches == 0 {
+		return 0
+	}
+	total := 0
+	for place, count := range stats.Placements[player] {
+		total += (place + 1) * count
+	}
+	return float64(total) / float64(stats.Matches)
+}
+
+// The share of hands that ended without a winner
+func (stats SimulationStats) DrawRate() float64 {
+	if stats.Hands == 0 {
+		return 0
+	}
+	return float64(stats.Draws) / float64(stats.Hands)
+}
@@ -0,0 +1,31 @@
+package core
+
+import "testing"
+
+func TestSimulateMatch(t *testing.T) {
+	rules := DefaultMatchRules()
+	rules.Length = TONPUUSEN
+	actors := []Actor{NewBot("east"), NewBot("south"), NewBot("west"), NewBot("north")}
+
+	stats := NewSimulationStats(len(actors))
+	for seed := range int64(3) {
+		if err := SimulateMatch(rules, seed, actors, &stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stats.Matches != 3 || stats.Hands < 3*4 {
+		t.Errorf("Played %d matches and %d hands", stats.Matches, stats.Hands)
+	}
+	wins, placements := 0, 0.0
+	for player := range uint8(len(actors)) {
+		wins += stats.Wins[player]
+		placements += stats.AveragePlacement(player)
+	}
+	if wins+stats.Draws < stats.Hands {
+		t.Errorf("%d wins and %d draws over %d hands", wins, stats.Draws, stats.Hands)
+	}
+	if placements != 1+2+3+4 {
+		t.Errorf("Average placements add up to %v", placements)
+	}
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+// How long omniscient spectators see events after the players do, so
+// that they cannot pass hidden information on to a player
+const DefaultSpectatorDelay = time.Minute
+
+type Spectator struct {
+	View   SpectatorView
+	Player uint8 // The player watched with PLAYER_VIEW
+}
+
+// A client watching an arena
+type spectator struct {
+	Spectator
+	client  *Client
+	delayed *delayedQueue // Only used by OMNISCIENT_VIEW
+}
+
+type delayedMessage struct {
+	sendAt  time.Time
+	message Message
+}
+
+// The messages waiting out the delay of a spectator, so that queueing
+// them never blocks the arena however many are sent during the delay
+type delayedQueue struct {
+	messages []delayedMessage
+	closed   bool
+	cond     *sync.Cond
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+func newDelayedQueue() *delayedQueue {
+	return &delayedQueue{cond: sync.NewCond(&sync.Mutex{})}
+}
+
+func (queue *delayedQueue) push(message delayedMessage) {
+	queue.cond.L.Lock()
+	defer queue.cond.L.Unlock()
+	queue.messages = append(queue.messages, message)
+	queue.cond.Signal()
+}
+
+// Waits for the next message, and returns false once the queue is closed
+func (queue *delayedQueue) pop() (delayedMessage, bool) {
+	queue.cond.L.Lock()
+	defer queue.cond.L.Unlock()
+	for len(queue.messages) == 0 && !queue.closed {
+		queue.cond.Wait()
+	}
+	if queue.closed {
+		return delayedMessage{}, false
+	}
+	message := queue.messages[0]
+	queue.messages = queue.messages[1:]
+	return message, true
+}
+
+func (queue *delayedQueue) close() {
+	queue.cond.L.Lock()
+	defer queue.cond.L.Unlock()
+	queue.closed = true
+	queue.cond.Broadcast()
+}
+
+// Whether the spectator sees a message sent with the given visibility,
+// and if so whether they see the full message or its redacted version
+func (spectator Spectator) sees(visibility Visibility, sendTo uint8) (sees bool, full bool) {
+	switch spectator.View {
+	case OMNISCIENT_VIEW:
+		return true, true
+
+	case PLAYER_VIEW:
+		switch visibility {
+		case GLOBAL:
+			return true, true
+		case PARTIAL:
+			return true, sendTo == spectator.Player
+		case PLAYER:
+			return sendTo == spectator.Player, true
+		case EXCLUDE:
+			return sendTo != spectator.Player, true
+		}
+
+	case PUBLIC_VIEW:
+		switch visibility {
+		case GLOBAL, EXCLUDE:
+			return true, true
+		case PARTIAL:
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// Forwards delayed messages to the spectator once their time comes
+func (spectator spectator) sendDelayed() {
+	for {
+		delayed, ok := spectator.delayed.pop()
+		if !ok {
+			return
+		}
+		time.Sleep(time.Until(delayed.sendAt))
+		spectator.client.push(delayed.message)
+	}
+}
+
+func (arena *Arena) findSpectator(client *Client) int {
+	return slices.IndexFunc(arena.spectators, func(spectator spectator) bool {
+		return spectator.client == client
+	})
+}
+
+// Sends a message to every spectator who gets to see it
+func (arena *Arena) sendToSpectators(data ArenaMessage, visibility Visibility, sendTo uint8) error {
+	var altMessage *ArenaMessage
+	sendAlt := true
+	for _, spectator := range arena.spectators {
+		sees, full := spectator.sees(visibility, sendTo)
+		if !sees {
+			continue
+		}
+
+		arenaMessage := data
+		if !full {
+			if altMessage == nil {
+				alt, send, err := GetAltMessage(data)
+				if err != nil {
+					return err
+				}
+				altMessage, sendAlt = &alt, send
+			}
+			if !sendAlt {
+				continue
+			}
+			arenaMessage = *altMessage
+		}
+
+		message := Message{
+			MessageType: ServerArenaEventType,
+			Data:        ServerArenaMessageEventData{ArenaMessage: arenaMessage},
+		}
+		if spectator.delayed != nil {
+			spectator.delayed.push(delayedMessage{
+				sendAt:  time.Now().Add(arena.spectatorDelay),
+				message: message,
+			})
+		} else {
+			spectator.client.push(message)
+		}
+	}
+	return nil
+}
+
+// Tells the spectators with PUBLIC_VIEW about a new hand. Every player
+// is sent a setup of their own, so those spectators get the setup of
+// the first player with the dealt tiles hidden, and without the player
+// number since they do not sit at the table.
+func (arena *Arena) sendPublicSetup(setup ArenaMessage) error {
+	altMessage, _, err := GetAltMessage(setup)
+	if err != nil {
+		return err
+	}
+	event := altMessage.Data.(ArenaBoardEventData)
+	data := event.Data.(GameSetupEventData)
+	data.Setup = slices.DeleteFunc(data.Setup, func(setup Setup) bool {
+		return setup.Type == PLAYER_NUMBER
+	})
+	event.Data = data
+	altMessage.Data = event
+
+	for _, spectator := range arena.spectators {
+		if spectator.View == PUBLIC_VIEW {
+			spectator.client.push(Message{
+				MessageType: ServerArenaEventType,
+				Data:        ServerArenaMessageEventData{ArenaMessage: altMessage},
+			})
+		}
+	}
+	return nil
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Adds a client that watches the game without taking part in it
+func (arena *Arena) Spectate(client *Client, settings Spectator) error {
+	arena.Lock()
+	defer arena.Unlock()
+
+	if settings.View > OMNISCIENT_VIEW {
+		return CodedError{Code: UNKNOWN_VIEW, Reason: "Unknown spectator view"}
+	}
+	if settings.View == PLAYER_VIEW && int(settings.Player) >= arena.match.Game.GetMaxPlayers() {
+		return CodedError{Code: UNKNOWN_VIEW, Reason: "No such player to watch"}
+	}
+	if arena.findSpectator(client) != -1 {
+		return CodedError{Code: ALREADY_SPECTATING}
+	}
+
+	spectator := spectator{Spectator: settings, client: client}
+	if settings.View == OMNISCIENT_VIEW {
+		spectator.delayed = newDelayedQueue()
+		go spectator.sendDelayed()
+	}
+	arena.spectators = append(arena.spectators, spectator)
+
+	client.Arena = arena
+	return nil
+}
+
+// Removes a spectator from the arena
+func (arena *Arena) StopSpectating(client *Client) error {
+	arena.Lock()
+	defer arena.Unlock()
+
+	idx := arena.findSpectator(client)
+	if idx == -1 {
+		return CodedError{Code: NOT_SPECTATING}
+	}
+
+	if delayed := arena.spectators[idx].delayed; delayed != nil {
+		delayed.close()
+	}
+	arena.spectators = slices.Delete(arena.spectators, idx, idx+1)
+	return nil
+}
+
+// Whether the client is watching the arena rather than playing in it
+func (arena *Arena) IsSpectator(client *Client) bool {
+	arena.Lock()
+	defer arena.Unlock()
+	return arena.findSpectator(client) != -1
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/errors"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+)
+
+func newTestClient() *Client {
+	return &Client{Name: "test", Recv: make(chan Message, clientBuffer), lagging: make(chan UnitType, 1)}
+}
+
+// Drains the messages a client has been sent so far
+func received(client *Client) []ArenaMessage {
+	messages := make([]ArenaMessage, 0)
+	for {
+		select {
+		case message := <-client.Recv:
+			messages = append(messages, message.Data.(ServerArenaMessageEventData).ArenaMessage)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestSpectatorViews(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	for range 4 {
+		if err := arena.JoinArena(newTestClient(), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	public, player, omniscient := newTestClient(), newTestClient(), newTestClient()
+	if err := arena.JoinArena(public, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := arena.Spectate(player, Spectator{View: PLAYER_VIEW, Player: 2}); err != nil {
+		t.Fatal(err)
+	}
+	arena.spectatorDelay = 20 * time.Millisecond
+	if err := arena.Spectate(omniscient, Spectator{View: OMNISCIENT_VIEW}); err != nil {
+		t.Fatal(err)
+	}
+	if err := arena.Spectate(public, Spectator{}); err == nil {
+		t.Error("Spectated the same arena twice")
+	}
+
+	message := ArenaMessage{MessageType: GameStartedEventType, Data: GameStartedEventData{}}
+	tests := []struct {
+		name           string // description of this test case
+		visibility     Visibility
+		sendTo         uint8
+		wantPublic     int
+		wantPlayer     int
+		wantOmniscient int
+	}{
+		{"global messages reach everyone", GLOBAL, 0, 1, 1, 1},
+		{"a message to the watched player", PLAYER, 2, 0, 1, 1},
+		{"a message to another player", PLAYER, 1, 0, 0, 1},
+		{"a message excluding the watched player", EXCLUDE, 2, 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := arena.Send(message, tt.visibility, tt.sendTo); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := len(received(public)); got != tt.wantPublic {
+				t.Errorf("public spectator got %d messages, want %d", got, tt.wantPublic)
+			}
+			if got := len(received(player)); got != tt.wantPlayer {
+				t.Errorf("player spectator got %d messages, want %d", got, tt.wantPlayer)
+			}
+			if got := len(received(omniscient)); got != 0 {
+				t.Errorf("omniscient spectator got %d messages before the delay", got)
+			}
+			got := 0
+			for start := time.Now(); got < tt.wantOmniscient && time.Since(start) < time.Second; {
+				time.Sleep(time.Millisecond)
+				got += len(received(omniscient))
+			}
+			if got != tt.wantOmniscient {
+				t.Errorf("omniscient spectator got %d messages, want %d", got, tt.wantOmniscient)
+			}
+		})
+	}
+}
+
+func TestSpectatorCannotAct(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	if err := arena.JoinArena(NewBot("bot"), false); CodeOf(err) != CANNOT_SPECTATE {
+		t.Errorf("Bot spectating returned %v, want code %v", err, CANNOT_SPECTATE)
+	}
+	spectator := newTestClient()
+	if err := arena.JoinArena(spectator, false); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := spectator.HandleServerArena(ServerArenaActionData{
+		ArenaMessage: ArenaMessage{MessageType: PlayerActionType, Data: PlayerActionData{}},
+	})
+	if err == nil {
+		t.Error("Spectator sent a player action")
+	}
+
+	_, err = spectator.HandleServerArena(ServerArenaActionData{
+		ArenaMessage: ArenaMessage{MessageType: PlayerQuitActionType, Data: PlayerQuitActionData{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arena.IsSpectator(spectator) || spectator.Arena != nil {
+		t.Error("Spectator did not leave the arena")
+	}
+}
+
+func TestPublicSpectatorSetup(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.timeLimits = TimeLimits{}
+	for range 4 {
+		if err := arena.JoinArena(newTestClient(), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	public := newTestClient()
+	if err := arena.JoinArena(public, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var setups []Setup
+	for _, message := range received(public) {
+		if event, ok := message.Data.(ArenaBoardEventData); ok && event.EventType == GameSetupEventType {
+			setups = event.Data.(GameSetupEventData).Setup
+		}
+	}
+	types := make([]SetupType, 0, len(setups))
+	for _, setup := range setups {
+		types = append(types, setup.Type)
+		if tiles, ok := setup.Data.([]Tile); ok && setup.Type == INITIAL_TILES && slices.ContainsFunc(tiles, func(tile Tile) bool { return tile != Hidden }) {
+			t.Errorf("Public spectator saw the dealt tiles %v", tiles)
+		}
+	}
+	if !slices.Contains(types, DORA) || !slices.Contains(types, PLAYER_ORDER) || slices.Contains(types, PLAYER_NUMBER) {
+		t.Errorf("Public spectator got the setup %+v, want the table without a player number", setups)
+	}
+}
+
+func TestDelayedSpectatorDoesNotBlock(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	arena.spectatorDelay = time.Hour
+	if err := arena.Spectate(newTestClient(), Spectator{View: OMNISCIENT_VIEW}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for range 4 * clientBuffer {
+			arena.Send(ArenaMessage{MessageType: GameStartedEventType, Data: GameStartedEventData{}}, GLOBAL, 0)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sending blocked on the delayed spectator")
+	}
+}
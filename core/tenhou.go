@@ -0,0 +1,515 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/util"
+)
+
+// A match in the tenhou.net/6 JSON log format, which analysis tools
+// such as mortal and NAGA read
+type TenhouLog struct {
+	Title []string   `json:"title"`
+	Name  []string   `json:"name"` // By seat in the first hand
+	Rule  TenhouRule `json:"rule"`
+	// One entry per hand: the hand number, the points and dora, then
+	// the starting hand, draws and discards of every seat, and the
+	// result
+	Log [][]any `json:"log"`
+}
+
+type TenhouRule struct {
+	Disp string `json:"disp"`
+	Aka  int    `json:"aka"` // Whether red fives are played
+}
+
+// Tenhou marks a discard of the tile that was just drawn with 60
+const tenhouTsumogiri = 60
+
+var tenhouYakuNames = map[YakuType]string{
+	MENZEN_TSUMO_YAKU:    "門前清自摸和",
+	RIICHI_YAKU:          "立直",
+	IPPATSU_YAKU:         "一発",
+	PINFU_YAKU:           "平和",
+	IIPEIKOU_YAKU:        "一盃口",
+	HAITEI_YAOYUE_YAKU:   "海底摸月",
+	HOUTEI_RAOYUI_YAKU:   "河底撈魚",
+	RINSHAN_KAIHOU_YAKU:  "嶺上開花",
+	CHANKAN_YAKU:         "槍槓",
+	TANYAO_YAKU:          "断幺九",
+	DOUBLE_RIICHI_YAKU:   "両立直",
+	CHANTAIYAO_YAKU:      "混全帯幺九",
+	SANSHOKU_DOUJUN_YAKU: "三色同順",
+	ITTSU_YAKU:           "一気通貫",
+	TOITOI_YAKU:          "対々和",
+	SANANKOU_YAKU:        "三暗刻",
+	SANSHOKU_DOUKOU_YAKU: "三色同刻",
+	SANKANTSU_YAKU:       "三槓子",
+	CHIITOITSU_YAKU:      "七対子",
+	HONROUTOU_YAKU:       "混老頭",
+	SHOUSANGEN_YAKU:      "小三元",
+	HONITSU_YAKU:         "混一色",
+	JUNCHAN_YAKU:         "純全帯幺九",
+	RYANPEIKOU_YAKU:      "二盃口",
+	CHINITSU_YAKU:        "清一色",
+
+	KOKUSHI_MUSOU_YAKU:                "国士無双",
+	KOKUSHI_MUSOU_THIRTEEN_WAITS_YAKU: "国士無双１３面",
+	SUUANKOU_YAKU:                     "四暗刻",
+	DAISANGEN_YAKU:                    "大三元",
+	SHOUSUUSHII_YAKU:                  "小四喜",
+	DAISUUSHII_YAKU:                   "大四喜",
+	TSUUIISOU_YAKU:                    "字一色",
+	CHINROUTOU_YAKU:                   "清老頭",
+	RYUUIISOU_YAKU:                    "緑一色",
+	CHUUREN_POUTOU_YAKU:               "九蓮宝燈",
+	SUUKANTSU_YAKU:                    "四槓子",
+	TENHOU_YAKU:                       "天和",
+	CHIIHOU_YAKU:                      "地和",
+}
+
+var tenhouLimitNames = map[ScoreLimit]string{
+	MANGAN:        "満貫",
+	HANEMAN:       "跳満",
+	BAIMAN:        "倍満",
+	SANBAIMAN:     "三倍満",
+	KAZOE_YAKUMAN: "役満",
+	YAKUMAN:       "役満",
+}
+
+var tenhouDrawNames = map[ResultType]string{
+	KYUUSHU_KYUUHAI_RESULT: "九種九牌",
+	SUUFON_RENDA_RESULT:    "四風連打",
+	SUUCHA_RIICHI_RESULT:   "四家立直",
+	SUUKAIKAN_RESULT:       "四槓散了",
+	SANCHAHOU_RESULT:       "三家和了",
+}
+
+var tenhouHonourNames = map[Tile]string{
+	EastTile:  "東",
+	SouthTile: "南",
+	WestTile:  "西",
+	NorthTile: "北",
+	White:     "白",
+	Green:     "發",
+	Red:       "中",
+}
+
+// What the game looked like before a call to it, to tell what the
+// events it returned did
+type tenhouBefore struct {
+	hands   [][]Tile
+	state   MahjongState
+	current uint8
+	drawn   Tile
+}
+
+// The hand being exported
+type tenhouHand struct {
+	header   []int
+	points   []int32
+	seats    []uint8 // The seat of each player in the first hand
+	haipai   [][]int // By seat
+	takes    [][]any
+	discards [][]any
+}
+
+// Writes down every hand while a log is played through
+type tenhouExporter struct {
+	before tenhouBefore
+	hand   *tenhouHand
+	log    *TenhouLog
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The tenhou code of a tile: 11-19, 21-29 and 31-39 for the suits,
+// 41-47 for the winds and dragons, and 51-53 for the red fives
+func tenhouTile(tile Tile) int {
+	kind := tile.ClearRedOrDora()
+	switch {
+	case tile&RedTile != 0:
+		return 51 + int(kind)/16
+	case kind.IsSuited():
+		return 10*(int(kind)/16+1) + int(kind.GetTileNumber()) + 1
+	case kind == White:
+		return 45
+	case kind == Green:
+		return 46
+	case kind == Red:
+		return 47
+	default:
+		return 41 + int(kind-EastTile)
+	}
+}
+
+func tenhouTiles(tiles []Tile) []int {
+	codes := make([]int, 0, len(tiles))
+	for _, tile := range tiles {
+		codes = append(codes, tenhouTile(tile))
+	}
+	return codes
+}
+
+// Sorts tiles in tenhou order, with red fives among the other fives
+func sortTenhou(tiles []Tile) []Tile {
+	tiles = slices.Clone(tiles)
+	slices.SortStableFunc(tiles, func(a, b Tile) int {
+		return cmp.Compare(a.ClearRedOrDora(), b.ClearRedOrDora())
+	})
+	return tiles
+}
+
+// Writes the tiles as one call, with the marker before the called tile
+func tenhouCall(marker string, called Tile, fromHand []Tile, calledAt int) string {
+	call := ""
+	for idx, tile := range sortTenhou(fromHand) {
+		if idx == calledAt {
+			call += marker + strconv.Itoa(tenhouTile(called))
+		}
+		call += strconv.Itoa(tenhouTile(tile))
+	}
+	if calledAt >= len(fromHand) {
+		call += marker + strconv.Itoa(tenhouTile(called))
+	}
+	return call
+}
+
+// The pon of the tile among the calls, turned into the kan that adds
+// the tile to it
+func tenhouKakan(takes []any, added Tile) string {
+	for _, take := range takes {
+		call, ok := take.(string)
+		if !ok || !strings.Contains(call, "p") {
+			continue
+		}
+		// At most one tile of the pon is a red five
+		digits := strings.Replace(call, "p", "", 1)
+		kind := strconv.Itoa(tenhouTile(added.ClearRedOrDora()))
+		if digits[:2] == kind || digits[2:4] == kind {
+			return strings.Replace(call, "p", "k"+strconv.Itoa(tenhouTile(added)), 1)
+		}
+	}
+	panic("No pon to add the tile to")
+}
+
+// The tiles that are in before but not in after
+func removedTiles(before []Tile, after []Tile) []Tile {
+	left := slices.Clone(after)
+	removed := make([]Tile, 0)
+	for _, tile := range before {
+		if idx := slices.Index(left, tile); idx >= 0 {
+			left = slices.Delete(left, idx, idx+1)
+		} else {
+			removed = append(removed, tile)
+		}
+	}
+	return removed
+}
+
+// handStarted implements replayObserver.
+func (e *tenhouExporter) handStarted(match *Match) {
+	game := &match.Game
+	round := match.Round
+	hand := &tenhouHand{
+		header:   []int{4*int(round.RoundWind-East) + int(round.Kyoku), int(round.Honba), int(round.RiichiSticks)},
+		points:   make([]int32, 4),
+		seats:    round.Seats,
+		haipai:   make([][]int, 4),
+		takes:    make([][]any, 4),
+		discards: make([][]any, 4),
+	}
+	for idx, player := range game.Players {
+		seat := round.Seats[idx]
+		hand.points[seat] = player.Points
+		hand.haipai[seat] = tenhouTiles(sortTenhou(player.ClosedHand))
+		hand.takes[seat] = make([]any, 0)
+		hand.discards[seat] = make([]any, 0)
+	}
+	e.hand = hand
+}
+
+// beforeCall implements replayObserver.
+func (e *tenhouExporter) beforeCall(game *MahjongGame) {
+	hands := make([][]Tile, len(game.Players))
+	for idx, player := range game.Players {
+		hands[idx] = slices.Clone(player.ClosedHand)
+	}
+	e.before = tenhouBefore{
+		hands:   hands,
+		state:   game.GameState,
+		current: game.currentPlayerIdx(),
+		drawn:   game.DrawnTile,
+	}
+}
+
+// afterCall implements replayObserver. It writes down the draws,
+// discards and calls among the events.
+func (e *tenhouExporter) afterCall(game *MahjongGame, sendInfos []MessageSendInfo) {
+	before := e.before
+	hand := e.hand
+	for _, sendInfo := range sendInfos {
+		for _, event := range sendInfo.Events {
+			data, ok := event.Data.(PlayerActionEventData)
+			if !ok {
+				continue
+			}
+
+			player := data.FromPlayer
+			seat := hand.seats[player]
+			used := removedTiles(before.hands[player], game.Players[player].ClosedHand)
+			// Where the discarder sits from the caller: 1 to the left,
+			// 2 across and 3 to the right
+			direction := int(game.PlayerToOrder[player]+4-game.PlayerToOrder[before.current]) % 4
+
+			switch data.ActionType {
+			case DRAW:
+				hand.takes[seat] = append(hand.takes[seat], tenhouTile(data.Data.(DrawData).DrawnTile))
+			case TOSS, RIICHI:
+				var tile Tile
+				if tossData, ok := data.Data.(TossData); ok {
+					tile = tossData.TileToToss
+				} else {
+					tile = data.Data.(RiichiData).TileToRiichi
+				}
+
+				discard := tenhouTile(tile)
+				if before.drawn != Invalid && tile == before.drawn {
+					discard = tenhouTsumogiri
+				}
+				if data.ActionType == RIICHI {
+					hand.discards[seat] = append(hand.discards[seat], fmt.Sprintf("r%d", discard))
+				} else {
+					hand.discards[seat] = append(hand.discards[seat], discard)
+				}
+			case CHII:
+				call := tenhouCall("c", data.Data.(ChiiData).TileToChii, used, 0)
+				hand.takes[seat] = append(hand.takes[seat], call)
+			case PON:
+				call := tenhouCall("p", data.Data.(PonData).TileToPon, used, direction-1)
+				hand.takes[seat] = append(hand.takes[seat], call)
+			case KAN:
+				kan := data.Data.(KanData)
+				tile := kan.TileToKan
+				if kan.Added {
+					// Written like the pon it is added to, with the
+					// added tile after the marker
+					hand.discards[seat] = append(hand.discards[seat], tenhouKakan(hand.takes[seat], tile))
+				} else if before.state == CURRENT_TURN_PLAYED {
+					// A called kan takes the place of a discard. The tile
+					// from the right goes last, after all three of the hand.
+					calledAt := direction - 1
+					if direction == 3 {
+						calledAt = len(used)
+					}
+					hand.takes[seat] = append(hand.takes[seat], tenhouCall("m", tile, used, calledAt))
+					hand.discards[seat] = append(hand.discards[seat], 0)
+				} else {
+					used = sortTenhou(used)
+					call := tenhouCall("a", Last(used), used[:len(used)-1], len(used)-1)
+					hand.discards[seat] = append(hand.discards[seat], call)
+				}
+			}
+		}
+	}
+}
+
+// The points each seat gains or loses from a result, not counting the
+// riichi deposits they made during the hand
+func (e *tenhouExporter) deltas(result GameResult, riichiSticks uint8) []int {
+	deltas := make([]int, 4)
+	for _, transfer := range result.PointsTransfers {
+		deltas[e.hand.seats[transfer.From]] -= int(transfer.Amount)
+		deltas[e.hand.seats[transfer.To]] += int(transfer.Amount)
+	}
+	if result.Type == WIN_RESULT {
+		deltas[e.hand.seats[result.WonBy]] += 1000 * int(riichiSticks)
+	}
+	return deltas
+}
+
+// How much the win was worth, e.g. 30符2飜2000点 or 満貫2000-4000点
+func tenhouPoints(score Score, tsumo bool, dealer bool) string {
+	value := fmt.Sprintf("%d符%d飜", score.Fu, score.Han)
+	if name, ok := tenhouLimitNames[score.Limit]; ok {
+		value = name
+	}
+
+	switch {
+	case !tsumo:
+		return fmt.Sprintf("%s%d点", value, score.RonPayment)
+	case dealer:
+		return fmt.Sprintf("%s%d点∀", value, score.NonDealerPayment)
+	default:
+		return fmt.Sprintf("%s%d-%d点", value, score.NonDealerPayment, score.DealerPayment)
+	}
+}
+
+// The yaku of a win with their han, e.g. 立直(1飜), and the dora
+func tenhouYaku(win WinResult) []string {
+	yaku := make([]string, 0)
+	yakuman := win.Yakus.IsYakuman()
+	for bit := YakuType(1); bit != 0; bit <<= 1 {
+		name, ok := tenhouYakuNames[bit]
+		if !win.Yakus.Has(bit) || !ok {
+			continue
+		}
+		han := bit.Han()
+		if win.WinningHand.HandOpen {
+			han -= bit.HanLossOnOpen()
+		}
+		if yakuman {
+			yaku = append(yaku, name+"(役満)")
+		} else {
+			yaku = append(yaku, fmt.Sprintf("%s(%d飜)", name, han))
+		}
+	}
+	if yakuman {
+		return yaku
+	}
+
+	// Every triplet of a dragon or a valued wind is its own yaku
+	context := win.Context
+	for _, meld := range win.Decomposition.Melds {
+		if meld.Type == SEQUENCE || !meld.Tile.IsHonour() {
+			continue
+		}
+		name := tenhouHonourNames[meld.Tile]
+		if meld.Tile == Tile(context.SeatWind) {
+			yaku = append(yaku, "自風 "+name+"(1飜)")
+		}
+		if meld.Tile == Tile(context.RoundWind) {
+			yaku = append(yaku, "場風 "+name+"(1飜)")
+		}
+		if !meld.Tile.IsWind() {
+			yaku = append(yaku, "役牌 "+name+"(1飜)")
+		}
+	}
+
+	// Dora, ura dora and red fives are counted apart
+	tile := win.WinningTile
+	withoutUra := context
+	withoutUra.Riichi, withoutUra.DoubleRiichi = false, false
+	withoutDora := withoutUra
+	withoutDora.DoraIndicators = nil
+
+	aka := CountDora(win.WinningHand, tile, withoutDora)
+	dora := CountDora(win.WinningHand, tile, withoutUra) - aka
+	ura := CountDora(win.WinningHand, tile, context) - dora - aka
+	for _, count := range []struct {
+		name  string
+		count int
+	}{{"ドラ", dora}, {"赤ドラ", aka}, {"裏ドラ", ura}} {
+		if count.count > 0 {
+			yaku = append(yaku, fmt.Sprintf("%s(%d飜)", count.name, count.count))
+		}
+	}
+	return yaku
+}
+
+func (e *tenhouExporter) winDetails(result GameResult) []any {
+	seats := e.hand.seats
+	winner := seats[result.WonBy]
+	from := winner
+	if result.Result.WonByRon {
+		from = seats[result.PointsTransfers[0].From]
+	}
+	dealer := result.Result.Context.SeatWind == East
+	details := []any{winner, from, winner, tenhouPoints(result.Score, !result.Result.WonByRon, dealer)}
+	for _, yaku := range tenhouYaku(result.Result) {
+		details = append(details, yaku)
+	}
+	return details
+}
+
+// The result of the hand, e.g. ["和了", deltas, details] for a win
+func (e *tenhouExporter) result(result GameResult) []any {
+	switch result.Type {
+	case WIN_RESULT:
+		entry := []any{"和了", e.deltas(result, result.RiichiSticks), e.winDetails(result)}
+		for _, other := range result.OtherWins {
+			entry = append(entry, e.deltas(other, 0), e.winDetails(other))
+		}
+		return entry
+
+	case EXHAUSTIVE_DRAW_RESULT:
+		tenpai := Count(result.Tenpai, true)
+		switch {
+		case len(result.NagashiMangan) != 0:
+			return []any{"流し満貫", e.deltas(result, 0)}
+		case tenpai == 0:
+			return []any{"全員不聴"}
+		case tenpai == len(result.Tenpai):
+			return []any{"全員聴牌"}
+		default:
+			return []any{"流局", e.deltas(result, 0)}
+		}
+
+	default:
+		return []any{tenhouDrawNames[result.Type]}
+	}
+}
+
+// handEnded implements replayObserver. It adds the hand to the log.
+func (e *tenhouExporter) handEnded(match *Match) error {
+	game := &match.Game
+	result, err := game.GetGameResults()
+	if err != nil {
+		return err
+	}
+
+	dora := tenhouTiles(game.Dora[:game.DoraRevealed])
+	ura := make([]int, 0)
+	wins := append([]GameResult{result}, result.OtherWins...)
+	if result.Type == WIN_RESULT && slices.ContainsFunc(wins, func(win GameResult) bool {
+		return win.Result.Context.Riichi || win.Result.Context.DoubleRiichi
+	}) {
+		ura = tenhouTiles(game.UraDora[:game.DoraRevealed])
+	}
+
+	hand := e.hand
+	entry := []any{hand.header, hand.points, dora, ura}
+	for seat := range hand.haipai {
+		entry = append(entry, hand.haipai[seat], hand.takes[seat], hand.discards[seat])
+	}
+	e.log.Log = append(e.log.Log, append(entry, e.result(result)))
+	return nil
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// Converts the log of a match to the tenhou.net/6 format. The log is
+// replayed to find out what happened, so a log that does not match the
+// game is an error. Only the hands that were played to the end are
+// exported.
+func ExportTenhou(entries []LogEntry) (TenhouLog, error) {
+	log := TenhouLog{Log: make([][]any, 0)}
+	replay, err := loadReplay(entries, &tenhouExporter{log: &log})
+	if err != nil {
+		return TenhouLog{}, err
+	}
+
+	seats := NewSeededMatch(replay.Rules, replay.Seed).Round.Seats
+	log.Name = make([]string, len(replay.Players))
+	for idx, player := range replay.Players {
+		log.Name[seats[idx]] = player.Name
+	}
+
+	log.Title = []string{"LibreRiichi", entries[0].Time.Format("2006/01/02 15:04")}
+	// Open tanyao is allowed
+	log.Rule = TenhouRule{Disp: "般南喰", Aka: 0}
+	if replay.Rules.Length == TONPUUSEN {
+		log.Rule.Disp = "般東喰"
+	}
+	if replay.Rules.RedFives {
+		log.Rule.Disp += "赤"
+		log.Rule.Aka = 1
+	}
+	return log, nil
+}
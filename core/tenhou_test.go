@@ -0,0 +1,103 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+)
+
+func TestTenhouTile(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		tile Tile
+		want int
+	}{
+		{"1m", Manzu, 11},
+		{"9p", Pinzu + 8, 29},
+		{"5s", Souzu + 4, 35},
+		{"red 5m", (Manzu + 4).SetRedTile(), 51},
+		{"red 5s", (Souzu + 4).SetRedTile(), 53},
+		{"dora 3p", Pinzu + 2 | DoraTile, 23},
+		{"east", EastTile, 41},
+		{"north", NorthTile, 44},
+		{"white", White, 45},
+		{"green", Green, 46},
+		{"red", Red, 47},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tenhouTile(tt.tile); got != tt.want {
+				t.Errorf("tenhouTile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenhouCall(t *testing.T) {
+	tests := []struct {
+		name     string // description of this test case
+		marker   string
+		called   Tile
+		fromHand []Tile
+		calledAt int
+		want     string
+	}{
+		{"chii", "c", Manzu + 2, []Tile{Manzu + 3, Manzu + 1}, 0, "c131214"},
+		{"pon across", "p", White, []Tile{White, White}, 1, "45p4545"},
+		{"kan from the right", "m", EastTile, []Tile{EastTile, EastTile, EastTile}, 3, "414141m41"},
+		{"red five", "p", Pinzu + 4, []Tile{(Pinzu + 4).SetRedTile(), Pinzu + 4}, 2, "5225p25"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tenhouCall(tt.marker, tt.called, tt.fromHand, tt.calledAt); got != tt.want {
+				t.Errorf("tenhouCall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenhouKakan(t *testing.T) {
+	takes := []any{11, "c131214", "45p4545", 22}
+	if got := tenhouKakan(takes, White); got != "45k454545" {
+		t.Errorf("tenhouKakan() = %v, want 45k454545", got)
+	}
+}
+
+func TestExportTenhou(t *testing.T) {
+	entries, _ := playLoggedMatch(t)
+	log, err := ExportTenhou(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hands := 0
+	for _, entry := range entries {
+		if entry.Type == HAND_START_ENTRY {
+			hands += 1
+		}
+	}
+	if len(log.Log) != hands || len(log.Name) != 4 {
+		t.Fatalf("Exported %d hands of %d players, want %d hands of 4", len(log.Log), len(log.Name), hands)
+	}
+
+	for idx, hand := range log.Log {
+		// The header, points, dora and ura dora, 3 lists per seat and
+		// the result
+		if len(hand) != 4+3*4+1 {
+			t.Fatalf("Hand %d has %d fields", idx, len(hand))
+		}
+		for seat := range 4 {
+			if haipai := hand[4+3*seat].([]int); len(haipai) != 13 {
+				t.Errorf("Seat %d started hand %d with %d tiles", seat, idx, len(haipai))
+			}
+		}
+		if result := hand[len(hand)-1].([]any); len(result) == 0 {
+			t.Errorf("Hand %d has no result", idx)
+		}
+	}
+
+	if _, err := json.Marshal(log); err != nil {
+		t.Error(err)
+	}
+}
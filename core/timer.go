@@ -0,0 +1,196 @@
+package core
+
+import (
+	"slices"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+// How long players have to act before the arena acts for them. A zero
+// TurnTime or CallTime means there is no time limit for that action.
+type TimeLimits struct {
+	TurnTime time.Duration // To act on their own turn
+	CallTime time.Duration // To decide on a call or ron
+	// Extra time each player has for the whole match, which is used up
+	// once their turn or call time runs out
+	TimeBank time.Duration
+}
+
+// A player the game is waiting on, in a given state. A player who is
+// waited on again in another state, or after another discard, gets a
+// fresh turn or call time.
+type awaitedAction struct {
+	player uint8
+	state  MahjongState
+	hand   uint32 // The hand of the match
+	// The tiles discarded so far in the hand, which tells the calls
+	// offered on different discards apart
+	discards int
+}
+
+// Keeps track of how long the players the arena is waiting on have
+// taken, and when the next one of them runs out of time
+type actionTimer struct {
+	limits TimeLimits
+	banks  []time.Duration // The time bank left for each player
+	since  map[awaitedAction]time.Time
+	timer  *time.Timer
+	// Incremented whenever the timer is rescheduled, so that a timer
+	// that already fired can tell it is out of date
+	generation uint64
+}
+
+func DefaultTimeLimits() TimeLimits {
+	return TimeLimits{
+		TurnTime: 10 * time.Second,
+		CallTime: 5 * time.Second,
+		TimeBank: 20 * time.Second,
+	}
+}
+
+func newActionTimer(limits TimeLimits, numPlayers int) actionTimer {
+	banks := make([]time.Duration, numPlayers)
+	for idx := range banks {
+		banks[idx] = limits.TimeBank
+	}
+	return actionTimer{
+		limits: limits,
+		banks:  banks,
+		since:  make(map[awaitedAction]time.Time),
+	}
+}
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The time a player has before dipping into their time bank, or zero if
+// the action is not timed
+func (timer actionTimer) baseTime(state MahjongState) time.Duration {
+	switch state {
+	case CURRENT_TURN:
+		return timer.limits.TurnTime
+	case CURRENT_TURN_PLAYED:
+		return timer.limits.CallTime
+	default:
+		return 0
+	}
+}
+
+func (timer actionTimer) deadline(action awaitedAction) time.Time {
+	return timer.since[action].Add(timer.baseTime(action.state) + timer.banks[action.player])
+}
+
+// Charges the time a player took over their base time to their bank
+func (timer *actionTimer) finish(action awaitedAction, now time.Time) {
+	overtime := now.Sub(timer.since[action]) - timer.baseTime(action.state)
+	if overtime > 0 {
+		timer.banks[action.player] -= min(overtime, timer.banks[action.player])
+	}
+	delete(timer.since, action)
+}
+
+// Stops the running timer without charging anyone
+func (timer *actionTimer) stop() {
+	timer.generation += 1
+	if timer.timer != nil {
+		timer.timer.Stop()
+		timer.timer = nil
+	}
+}
+
+// Tells a player how long they have to act
+func (arena *Arena) sendTimer(action awaitedAction) {
+	timer := arena.timer
+	arena.Send(ArenaMessage{
+		MessageType: TimerEventType,
+		Data: TimerEventData{
+			ActionTime: timer.baseTime(action.state).Milliseconds(),
+			TimeBank:   timer.banks[action.player].Milliseconds(),
+		},
+	}, PLAYER, action.player)
+}
+
+// Starts timing the players the game now waits on, and charges the
+// players it no longer waits on. Called whenever the game moves on.
+func (arena *Arena) updateTimers() {
+	timer := &arena.timer
+	timer.stop()
+
+	now := time.Now()
+	awaited := make([]awaitedAction, 0, 4)
+	if arena.gameStarted {
+		game := &arena.match.Game
+		discards := 0
+		for _, player := range game.Players {
+			discards += len(player.Discards)
+		}
+		for _, player := range game.AwaitedPlayers() {
+			awaited = append(awaited, awaitedAction{
+				player:   player,
+				state:    game.GameState,
+				hand:     arena.match.Round.Hand,
+				discards: discards,
+			})
+		}
+	}
+
+	for action := range timer.since {
+		if !slices.Contains(awaited, action) {
+			timer.finish(action, now)
+		}
+	}
+
+	var next time.Time
+	for _, action := range awaited {
+		if timer.baseTime(action.state) == 0 {
+			continue
+		}
+		if _, ok := timer.since[action]; !ok {
+			timer.since[action] = now
+			if _, away := arena.away[action.player]; !away {
+				arena.sendTimer(action)
+			}
+		}
+		if deadline := timer.deadline(action); next.IsZero() || deadline.Before(next) {
+			next = deadline
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+
+	generation := timer.generation
+	timer.timer = time.AfterFunc(next.Sub(now), func() {
+		arena.handleTimeout(generation)
+	})
+}
+
+// Acts for every player who ran out of time, then moves the game on
+func (arena *Arena) handleTimeout(generation uint64) {
+	arena.Lock()
+	defer arena.Unlock()
+
+	timer := &arena.timer
+	if generation != timer.generation || !arena.gameStarted {
+		return
+	}
+
+	now := time.Now()
+	game := &arena.match.Game
+	for action := range timer.since {
+		if timer.deadline(action).After(now) {
+			continue
+		}
+		timer.finish(action, now)
+
+		for _, defaultAction := range game.DefaultActions(action.player) {
+			if err := arena.playerAction(defaultAction, action.player); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	if err := arena.driveGame(); err != nil {
+		panic(err)
+	}
+}
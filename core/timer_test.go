@@ -0,0 +1,119 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+func TestTimeBank(t *testing.T) {
+	limits := TimeLimits{TurnTime: 10 * time.Second, CallTime: 5 * time.Second, TimeBank: 20 * time.Second}
+	turn := awaitedAction{player: 1, state: CURRENT_TURN}
+	call := awaitedAction{player: 1, state: CURRENT_TURN_PLAYED}
+
+	tests := []struct {
+		name     string // description of this test case
+		action   awaitedAction
+		took     time.Duration
+		wantBank time.Duration
+	}{
+		{"acting in time keeps the bank", turn, 9 * time.Second, 20 * time.Second},
+		{"a slow turn uses the bank", turn, 15 * time.Second, 15 * time.Second},
+		{"a slow call uses the bank", call, 15 * time.Second, 10 * time.Second},
+		{"the bank does not go below zero", turn, time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timer := newActionTimer(limits, 4)
+			start := time.Now()
+			timer.since[tt.action] = start
+			if got, want := timer.deadline(tt.action), start.Add(timer.baseTime(tt.action.state)+limits.TimeBank); !got.Equal(want) {
+				t.Errorf("deadline = %v, want %v", got, want)
+			}
+
+			timer.finish(tt.action, start.Add(tt.took))
+			if timer.banks[tt.action.player] != tt.wantBank {
+				t.Errorf("bank = %v, want %v", timer.banks[tt.action.player], tt.wantBank)
+			}
+			if _, ok := timer.since[tt.action]; ok {
+				t.Error("finished action is still being timed")
+			}
+		})
+	}
+}
+
+func TestDefaultActions(t *testing.T) {
+	// Player 1 can pon the discarded 1m, and times out instead
+	hands := [4][]Tile{
+		append(tiles(Manzu, 1), filler...),
+		append(tiles(Manzu, 1, 1), filler[1:]...),
+		append(slices.Clone(filler), Manzu+6),
+		append(slices.Clone(filler), Manzu+6),
+	}
+	game := newScenarioGame(t, DefaultGameRules(), hands, Souzu+8)
+	game.GetNextEvent()
+
+	toss := game.DefaultActions(0)
+	if len(toss) != 1 || toss[0].Data != (TossData{TileToToss: Souzu + 8}) {
+		t.Fatalf("default actions on the dealer's turn = %+v, want to discard the drawn tile", toss)
+	}
+	if _, err := game.HandleToss(TossData{TileToToss: Manzu}, 0); err != nil {
+		t.Fatal(err)
+	}
+	game.GetNextEvent()
+
+	if got := game.AwaitedPlayers(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("awaited players = %v, want [1]", got)
+	}
+	for _, action := range game.DefaultActions(1) {
+		if action.ActionType != SKIP {
+			t.Errorf("default action %v, want SKIP", action.ActionType)
+		}
+		if _, err := ActionDecode(game, action, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(game.PendingActions) != 0 {
+		t.Errorf("%d actions still pending", len(game.PendingActions))
+	}
+}
+
+func TestTimerRestartsOnEveryDiscard(t *testing.T) {
+	arena := CreateArena("test", [16]byte{})
+	clients := make([]*Client, 4)
+	for idx := range clients {
+		clients[idx] = newTestClient()
+		if err := arena.JoinArena(clients[idx], true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arena.HandleStartGameAction(StartGameActionData{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(arena.timer.stop)
+
+	// Player 1 is offered a call on two discards in a row, without the
+	// timers being updated in between
+	timers := func() (count int) {
+		for _, message := range received(clients[1]) {
+			if message.MessageType == TimerEventType {
+				count += 1
+			}
+		}
+		return count
+	}
+	game := &arena.match.Game
+	game.GameState = CURRENT_TURN_PLAYED
+	game.PendingActions = []PendingAction{{ActionData: ActionData{ActionType: PON}, fromPlayer: 1}}
+	for discard := range 2 {
+		timers()
+		game.Players[0].Discards = append(game.Players[0].Discards, Manzu)
+		arena.updateTimers()
+		if got := timers(); got != 1 {
+			t.Errorf("Discard %d sent %d timers, want 1", discard, got)
+		}
+	}
+}
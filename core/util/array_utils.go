@@ -1,12 +1,12 @@
 package core
 
-import "math/rand"
+import "math/rand/v2"
 
 // Creates a random permutation of the array
 // Modifies the existing array
 func PermuteArray[T any](array []T) []T {
-	for i := len(array); i > 0; i -= 1 {
-		rand := rand.Intn(i)
+	for i := len(array) - 1; i > 0; i -= 1 {
+		rand := rand.IntN(i + 1)
 		temp := array[i]
 		array[i] = array[rand]
 		array[rand] = temp
@@ -14,6 +14,16 @@ func PermuteArray[T any](array []T) []T {
 	return array
 }
 
+// Creates a permutation of the array drawn from the given source, so
+// that the same source always gives the same permutation
+// Modifies the existing array
+func PermuteArrayWith[T any](rng *rand.Rand, array []T) []T {
+	rng.Shuffle(len(array), func(i, j int) {
+		array[i], array[j] = array[j], array[i]
+	})
+	return array
+}
+
 // Rotates the array to the left, e.g. [1, 2, 3] becomes [2, 3, 1]
 func RotateArrayLeft[T any](array []T, by int) []T {
 	temp := make([]T, by)
@@ -33,8 +43,9 @@ func LastPtr[T any](array []T) *T {
 
 // Always modifies the array
 func Pop[T any](array *[]T) T {
+	last := Last(*array)
 	*array = (*array)[:len(*array)-1]
-	return Last(*array)
+	return last
 }
 
 func Swap[T any](array []T, first, second uint) []T {
@@ -124,10 +124,11 @@ func MakeChannelFromWebsocket(conn *websocket.Conn) ConnChan {
 					return
 				}
 
+				// The client may have gone away, which the incoming side
+				// reports
 				err := conn.WriteMessage(websocket.TextMessage, toWrite)
 				if err != nil {
-					fmt.Println("Couldn't write message")
-					panic(err)
+					fmt.Println("Couldn't write message:", err)
 				}
 			default:
 			}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"slices"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+)
+
+// ==================== PRIVATE FUNCTIONS ====================
+
+// The potential actions of the current player during their turn, with
+// the tile to toss left for them to fill in
+func (game MahjongGame) turnActions() []ActionData {
+	return append([]ActionData{{
+		ActionType: TOSS,
+		Data:       TossData{TileToToss: Invalid},
+	}}, game.getTurnActions()...)
+}
+
+// The actions the player can take right now
+func (game MahjongGame) actionsOf(playerIdx uint8) []ActionData {
+	actions := make([]ActionData, 0)
+	switch game.GameState {
+	case CURRENT_TURN:
+		if playerIdx == game.currentPlayerIdx() {
+			actions = game.turnActions()
+		}
+	case CURRENT_TURN_PLAYED:
+		for _, pending := range game.PendingActions {
+			if pending.fromPlayer == playerIdx {
+				actions = append(actions, pending.ActionData)
+			}
+		}
+	}
+	return actions
+}
+
+// ==================== PUBLIC FUNCTIONS ====================
+
+// The state of the hand as the player sees it
+func (game MahjongGame) PlayerView(playerIdx uint8) PlayerView {
+	player := game.Players[playerIdx]
+	view := PlayerView{
+		Player:         playerIdx,
+		ClosedHand:     slices.Clone(player.ClosedHand),
+		DrawnTile:      Invalid,
+		Seats:          make([]SeatView, 0, len(game.Players)),
+		RoundWind:      game.RoundWind,
+		Kyoku:          game.Kyoku,
+		Honba:          game.Honba,
+		RiichiSticks:   game.RiichiSticks,
+		DoraIndicators: slices.Clone(game.Dora[:game.DoraRevealed]),
+		WallRemaining:  game.wallRemaining(),
+		CurrentPlayer:  game.currentPlayerIdx(),
+		DiscardedTile:  Invalid,
+		PendingActions: game.actionsOf(playerIdx),
+	}
+
+	switch game.GameState {
+	case CURRENT_TURN:
+		if playerIdx == game.currentPlayerIdx() {
+			view.DrawnTile = game.DrawnTile
+		}
+	case CURRENT_TURN_PLAYED:
+		view.DiscardedTile = game.DiscardedTile
+	}
+
+	for _, other := range game.Players {
+		view.Seats = append(view.Seats, SeatView{
+			Discards:     slices.Clone(other.Discards),
+			Kans:         slices.Clone(other.Kans),
+			Ankans:       slices.Clone(other.Ankans),
+			Pons:         slices.Clone(other.Pons),
+			Chiis:        slices.Clone(other.Chiis),
+			ClosedTiles:  len(other.ClosedHand),
+			HandInRiichi: other.HandInRiichi,
+			Points:       other.Points,
+			SeatWind:     other.SeatWind,
+		})
+	}
+	return view
+}
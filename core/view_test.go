@@ -0,0 +1,99 @@
+package core
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/game_data"
+	. "codeberg.org/ijnakashiar/LibreRiichi/core/messages"
+)
+
+func TestPlayerView(t *testing.T) {
+	hands := [4][]Tile{
+		append(tiles(Manzu, 1), filler...),
+		append(tiles(Manzu, 1, 1), filler[1:]...),
+		append(slices.Clone(filler), Manzu+6),
+		append(slices.Clone(filler), Manzu+6),
+	}
+	game := newScenarioGame(t, DefaultGameRules(), hands, Souzu+8)
+	game.GetNextEvent()
+
+	dealer := game.PlayerView(0)
+	if dealer.DrawnTile != Souzu+8 || len(dealer.ClosedHand) != 14 {
+		t.Errorf("Dealer sees drawn tile %v and %d tiles in hand", dealer.DrawnTile, len(dealer.ClosedHand))
+	}
+	if !slices.ContainsFunc(dealer.PendingActions, func(action ActionData) bool { return action.ActionType == TOSS }) {
+		t.Error("Dealer is not shown that they can discard")
+	}
+
+	other := game.PlayerView(1)
+	if other.DrawnTile != Invalid || len(other.PendingActions) != 0 {
+		t.Errorf("Player 1 sees drawn tile %v and actions %v on the dealer's turn", other.DrawnTile, other.PendingActions)
+	}
+	if got := other.Seats[0].ClosedTiles; got != 14 {
+		t.Errorf("Player 1 sees %d tiles in the dealer's hand, want 14", got)
+	}
+
+	// Player 1 can pon the discarded 1m
+	if _, err := game.HandleToss(TossData{TileToToss: Manzu}, 0); err != nil {
+		t.Fatal(err)
+	}
+	game.GetNextEvent()
+
+	tests := []struct {
+		name        string // description of this test case
+		player      uint8
+		wantActions int
+	}{
+		{"the discarder has nothing to do", 0, 0},
+		{"the player who can pon is offered it", 1, 1},
+		{"a player who cannot call has nothing to do", 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := game.PlayerView(tt.player)
+			if !slices.Equal(view.ClosedHand, game.Players[tt.player].ClosedHand) {
+				t.Errorf("View shows hand %v, want %v", view.ClosedHand, game.Players[tt.player].ClosedHand)
+			}
+			if len(view.PendingActions) != tt.wantActions {
+				t.Errorf("View shows actions %v, want %d", view.PendingActions, tt.wantActions)
+			}
+			if view.DiscardedTile != Manzu || !slices.Equal(view.Seats[0].Discards, []Tile{Manzu}) {
+				t.Errorf("View shows discard %v and discards %v", view.DiscardedTile, view.Seats[0].Discards)
+			}
+			if len(view.DoraIndicators) != 1 || view.WallRemaining != game.wallRemaining() {
+				t.Errorf("View shows dora %v and %d tiles left", view.DoraIndicators, view.WallRemaining)
+			}
+		})
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	game := newScenarioGame(t, DefaultGameRules(), [4][]Tile{
+		append(tiles(Manzu, 1), filler...),
+		append(tiles(Manzu, 1, 1), filler[1:]...),
+		append(slices.Clone(filler), Manzu+6),
+		append(slices.Clone(filler), Manzu+6),
+	}, Souzu+8)
+	game.GetNextEvent()
+
+	view := game.PlayerView(0)
+	bytes, err := json.Marshal(ArenaMessage{MessageType: SnapshotEventType, Data: SnapshotEventData{View: view}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := ArenaMessage{}
+	if err := json.Unmarshal(bytes, &message); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := message.Data.(SnapshotEventData)
+	if !ok {
+		t.Fatalf("Decoded %T, want SnapshotEventData", message.Data)
+	}
+	if !slices.Equal(got.View.ClosedHand, view.ClosedHand) || got.View.DrawnTile != view.DrawnTile ||
+		len(got.View.PendingActions) != len(view.PendingActions) || len(got.View.Seats) != 4 {
+		t.Errorf("Decoded view %+v, want %+v", got.View, view)
+	}
+}